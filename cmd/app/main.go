@@ -10,10 +10,28 @@ import (
 	"time"
 
 	"github.com/ThePotatoVerse/internal/app/handler"
+	"github.com/ThePotatoVerse/internal/app/job"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/internal/app/repository/memory"
+	"github.com/ThePotatoVerse/internal/app/repository/postgres"
+	"github.com/ThePotatoVerse/internal/app/service"
 	"github.com/ThePotatoVerse/internal/pkg/config"
+	"github.com/ThePotatoVerse/internal/pkg/validator"
+	"github.com/ThePotatoVerse/pkg/database"
+	"github.com/ThePotatoVerse/pkg/health"
 	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/ThePotatoVerse/pkg/mailer"
+	"github.com/ThePotatoVerse/pkg/ratelimit"
+	"github.com/ThePotatoVerse/pkg/token"
+	"github.com/ThePotatoVerse/pkg/tracing"
+	"github.com/ThePotatoVerse/scripts/migrations"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
+// healthCheckTimeout bounds each readiness check
+const healthCheckTimeout = 2 * time.Second
+
 func main() {
 	// Initialize logger
 	log := logger.New()
@@ -25,8 +43,108 @@ func main() {
 		log.Fatal("Failed to load configuration", "error", err)
 	}
 
+	// Initialize tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), &cfg.Tracing, log)
+	if err != nil {
+		log.Fatal("Failed to initialize tracing", "error", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Error("Failed to flush traces", "error", err)
+		}
+	}()
+
+	// Initialize health checks and metrics
+	healthRegistry := health.NewRegistry(healthCheckTimeout)
+	metricsRegistry := prometheus.NewRegistry()
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	// Initialize storage
+	store, err := newStorage(context.Background(), cfg, log, healthRegistry, metricsRegistry)
+	if err != nil {
+		log.Fatal("Failed to initialize storage", "driver", cfg.Storage.Driver, "error", err)
+	}
+	defer store.close()
+
+	// Initialize services
+	inputValidator := validator.New(&cfg.Validation)
+
+	mail, err := mailer.New(&cfg.Mail, log)
+	if err != nil {
+		log.Fatal("Failed to initialize mailer", "driver", cfg.Mail.Driver, "error", err)
+	}
+	verificationSigner, err := token.NewSigner(cfg.Auth.Verification.Secret, log)
+	if err != nil {
+		log.Fatal("Failed to initialize verification signer", "error", err)
+	}
+
+	verificationService := service.NewVerificationService(log, store.userRepo, store.emailVerificationRepo, store.txManager, mail, verificationSigner, cfg.Server.PublicURL, &cfg.Auth)
+	verificationService = service.NewVerificationServiceTracing(verificationService)
+	verificationService = service.NewVerificationServiceMetrics(verificationService, metricsRegistry)
+
+	userService := service.NewUserService(log, store.userRepo, store.txManager, inputValidator, verificationService)
+	userService = service.NewUserServiceTracing(userService)
+	userService = service.NewUserServiceMetrics(userService, metricsRegistry)
+
+	keys, err := token.LoadKeySet(&cfg.Auth.JWT, log)
+	if err != nil {
+		log.Fatal("Failed to load JWT keys", "error", err)
+	}
+
+	mfaService := service.NewMFAService(log, store.userRepo, store.mfaRepo, store.txManager, &cfg.Auth.MFA)
+	mfaService = service.NewMFAServiceTracing(mfaService)
+	mfaService = service.NewMFAServiceMetrics(mfaService, metricsRegistry)
+
+	authService := service.NewAuthService(log, userService, mfaService, store.userRepo, store.credentialRepo, store.sessionRepo, store.refreshTokenRepo, store.passwordResetRepo, store.mfaChallengeRepo, store.txManager, inputValidator, keys, mail, cfg.Server.PublicURL, &cfg.Auth)
+	authService = service.NewAuthServiceTracing(authService)
+	authService = service.NewAuthServiceMetrics(authService, metricsRegistry)
+
+	apiKeyService := service.NewAPIKeyService(log, store.userRepo, store.apiKeyRepo, inputValidator)
+	apiKeyService = service.NewAPIKeyServiceTracing(apiKeyService)
+	apiKeyService = service.NewAPIKeyServiceMetrics(apiKeyService, metricsRegistry)
+
+	oauthSigner, err := token.NewSigner(cfg.Auth.OAuth.Secret, log)
+	if err != nil {
+		log.Fatal("Failed to initialize OAuth consent signer", "error", err)
+	}
+
+	oauthService := service.NewOAuthService(log, store.oauthClientRepo, store.oauthConsentRepo, store.oauthCodeRepo, store.userRepo, store.txManager, inputValidator, keys, oauthSigner, cfg.Server.PublicURL, &cfg.Auth)
+	oauthService = service.NewOAuthServiceTracing(oauthService)
+	oauthService = service.NewOAuthServiceMetrics(oauthService, metricsRegistry)
+
+	externalLoginSigner, err := token.NewSigner(cfg.Auth.OIDC.Secret, log)
+	if err != nil {
+		log.Fatal("Failed to initialize external login state signer", "error", err)
+	}
+
+	externalLoginService := service.NewExternalLoginService(log, authService, userService, store.userRepo, store.credentialRepo, store.externalIdentityRepo, store.txManager, externalLoginSigner, cfg.Server.PublicURL, &cfg.Auth)
+	externalLoginService = service.NewExternalLoginServiceTracing(externalLoginService)
+	externalLoginService = service.NewExternalLoginServiceMetrics(externalLoginService, metricsRegistry)
+
+	// Start background jobs
+	if cfg.Purge.Enabled {
+		purger := job.NewUserPurger(log, userService, cfg.Purge.Interval, cfg.Purge.Retention)
+		purger.Start(context.Background())
+		defer purger.Stop()
+	}
+
+	sessionCleaner := job.NewSessionCleaner(log, authService, cfg.Auth.SessionCleanupInterval)
+	sessionCleaner.Start(context.Background())
+	defer sessionCleaner.Stop()
+
+	if cfg.RateLimit.Enabled {
+		rateLimitCleaner := job.NewRateLimitCleaner(log, store.rateLimitStore, cfg.RateLimit.CleanupInterval)
+		rateLimitCleaner.Start(context.Background())
+		defer rateLimitCleaner.Stop()
+	}
+
 	// Initialize router
-	router := handler.NewRouter(log)
+	router := handler.NewRouter(log, userService, authService, apiKeyService, verificationService, mfaService, oauthService, externalLoginService, keys, store.rateLimitStore, &cfg.Server, &cfg.Auth, &cfg.RateLimit, healthRegistry, metricsRegistry)
 
 	// Configure HTTP server
 	server := &http.Server{
@@ -51,13 +169,127 @@ func main() {
 	<-quit
 	log.Info("Shutting down server...")
 
+	// Fail readiness first so load balancers stop sending new requests
+	healthRegistry.SetShuttingDown()
+	if cfg.Server.ShutdownDelay > 0 {
+		log.Info("Waiting for load balancers to observe shutdown", "delay", cfg.Server.ShutdownDelay)
+		time.Sleep(cfg.Server.ShutdownDelay)
+	}
+
 	// Create a deadline for server shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown", "error", err)
+		log.Error("Server forced to shutdown", "error", err)
+		return
 	}
 
 	log.Info("Server exited properly")
 }
+
+// storage holds the repositories selected by the storage driver
+type storage struct {
+	userRepo              repository.UserRepository
+	credentialRepo        repository.CredentialRepository
+	sessionRepo           repository.SessionRepository
+	refreshTokenRepo      repository.RefreshTokenRepository
+	apiKeyRepo            repository.APIKeyRepository
+	emailVerificationRepo repository.EmailVerificationRepository
+	passwordResetRepo     repository.PasswordResetRepository
+	mfaRepo               repository.MFARepository
+	mfaChallengeRepo      repository.MFAChallengeRepository
+	oauthClientRepo       repository.OAuthClientRepository
+	oauthConsentRepo      repository.OAuthConsentRepository
+	oauthCodeRepo         repository.OAuthCodeRepository
+	externalIdentityRepo  repository.ExternalIdentityRepository
+	txManager             database.TxManager
+
+	// rateLimitStore keeps the rate limit buckets, which are in memory
+	// unless the rate limit store is postgres
+	rateLimitStore ratelimit.Store
+
+	// close releases any resources held by the storage and must be called
+	// once the server has stopped
+	close func()
+}
+
+// newStorage builds the repositories selected by the storage driver
+func newStorage(ctx context.Context, cfg *config.Config, log logger.Logger, healthRegistry *health.Registry, metricsRegistry prometheus.Registerer) (*storage, error) {
+	switch cfg.Storage.Driver {
+	case config.StorageDriverPostgres:
+		db, err := database.NewPostgres(ctx, &cfg.DB, log)
+		if err != nil {
+			return nil, err
+		}
+
+		if cfg.DB.AutoMigrate {
+			if err := runMigrations(ctx, db, log); err != nil {
+				db.Close()
+				return nil, err
+			}
+		}
+
+		db.RegisterHealthChecks(healthRegistry)
+		db.RegisterMetrics(metricsRegistry)
+
+		return &storage{
+			userRepo:              postgres.NewUserRepository(db, log),
+			credentialRepo:        postgres.NewCredentialRepository(db, log),
+			sessionRepo:           postgres.NewSessionRepository(db, log),
+			refreshTokenRepo:      postgres.NewRefreshTokenRepository(db, log),
+			apiKeyRepo:            postgres.NewAPIKeyRepository(db, log),
+			emailVerificationRepo: postgres.NewEmailVerificationRepository(db, log),
+			passwordResetRepo:     postgres.NewPasswordResetRepository(db, log),
+			mfaRepo:               postgres.NewMFARepository(db, log),
+			mfaChallengeRepo:      postgres.NewMFAChallengeRepository(db, log),
+			oauthClientRepo:       postgres.NewOAuthClientRepository(db, log),
+			oauthConsentRepo:      postgres.NewOAuthConsentRepository(db, log),
+			oauthCodeRepo:         postgres.NewOAuthCodeRepository(db, log),
+			externalIdentityRepo:  postgres.NewExternalIdentityRepository(db, log),
+			txManager:             database.NewTxManager(db),
+			rateLimitStore:        newRateLimitStore(cfg, db),
+			close:                 db.Close,
+		}, nil
+	case config.StorageDriverMemory:
+		txManager := memory.NewTxManager()
+		return &storage{
+			userRepo:              memory.NewUserRepository(txManager),
+			credentialRepo:        memory.NewCredentialRepository(txManager),
+			sessionRepo:           memory.NewSessionRepository(txManager),
+			refreshTokenRepo:      memory.NewRefreshTokenRepository(txManager),
+			apiKeyRepo:            memory.NewAPIKeyRepository(txManager),
+			emailVerificationRepo: memory.NewEmailVerificationRepository(txManager),
+			passwordResetRepo:     memory.NewPasswordResetRepository(txManager),
+			mfaRepo:               memory.NewMFARepository(txManager),
+			mfaChallengeRepo:      memory.NewMFAChallengeRepository(txManager),
+			oauthClientRepo:       memory.NewOAuthClientRepository(txManager),
+			oauthConsentRepo:      memory.NewOAuthConsentRepository(txManager),
+			oauthCodeRepo:         memory.NewOAuthCodeRepository(txManager),
+			externalIdentityRepo:  memory.NewExternalIdentityRepository(txManager),
+			txManager:             txManager,
+			rateLimitStore:        ratelimit.NewMemoryStore(),
+			close:                 func() {},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported storage driver %q", cfg.Storage.Driver)
+	}
+}
+
+// newRateLimitStore builds the rate limit store of a postgres storage
+func newRateLimitStore(cfg *config.Config, db *database.Postgres) ratelimit.Store {
+	if cfg.RateLimit.Store == config.RateLimitStorePostgres {
+		return ratelimit.NewPostgresStore(db)
+	}
+	return ratelimit.NewMemoryStore()
+}
+
+// runMigrations applies the embedded schema migrations
+func runMigrations(ctx context.Context, db *database.Postgres, log logger.Logger) error {
+	migrator, err := database.NewMigrator(db, migrations.FS, log)
+	if err != nil {
+		return err
+	}
+
+	return migrator.Up(ctx)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/ThePotatoVerse/internal/pkg/config"
+	"github.com/ThePotatoVerse/pkg/database"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/ThePotatoVerse/scripts/migrations"
+)
+
+const usage = `Usage: migrate <command>
+
+Commands:
+  up              Apply all pending migrations
+  down [n]        Roll back the last n migrations (default 1)
+  goto <version>  Migrate up or down to the given version
+  status          Show the applied state of every migration`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// Initialize logger
+	log := logger.New()
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load configuration", "error", err)
+	}
+
+	ctx := context.Background()
+
+	// Connect to database
+	db, err := database.NewPostgres(ctx, &cfg.DB, log)
+	if err != nil {
+		log.Fatal("Failed to connect to database", "error", err)
+	}
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db, migrations.FS, log)
+	if err != nil {
+		log.Fatal("Failed to load migrations", "error", err)
+	}
+
+	if err := run(ctx, migrator, os.Args[1:]); err != nil {
+		log.Error("Migration failed", "command", os.Args[1], "error", err)
+		db.Close()
+		os.Exit(1)
+	}
+}
+
+// run executes a single migrate command
+func run(ctx context.Context, migrator *database.Migrator, args []string) error {
+	switch args[0] {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		return migrator.Down(ctx, steps)
+	case "goto":
+		if len(args) < 2 {
+			return fmt.Errorf("goto requires a version")
+		}
+		version, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		return migrator.Goto(ctx, uint(version))
+	case "status":
+		current, status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Current version: %d\n", current)
+		for _, s := range status {
+			state := "pending"
+			if s.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%06d  %-8s %s\n", s.Version, state, s.Name)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", args[0], usage)
+	}
+}
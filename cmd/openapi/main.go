@@ -0,0 +1,27 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ThePotatoVerse/internal/app/handler"
+	"github.com/ThePotatoVerse/internal/pkg/config"
+)
+
+// openapi writes the OpenAPI document of the API to stdout
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load configuration:", err)
+		os.Exit(1)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(handler.NewOpenAPI(&cfg.Auth)); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to encode OpenAPI document:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/service"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandler handles HTTP requests for the API keys of a user
+type APIKeyHandler struct {
+	log           logger.Logger
+	apiKeyService service.APIKeyService
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(log logger.Logger, apiKeyService service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		log:           log,
+		apiKeyService: apiKeyService,
+	}
+}
+
+// apiKeyInput is the request body of API key create requests
+type apiKeyInput struct {
+	Name      string           `json:"name" binding:"required"`
+	Scopes    []service.Action `json:"scopes" doc:"Actions to limit the key to; omit to allow everything the user may do"`
+	ExpiresAt *time.Time       `json:"expires_at" doc:"When the key expires; omit for a key that never expires"`
+}
+
+// createdAPIKeyResponse is a newly created API key, including its value
+type createdAPIKeyResponse struct {
+	model.APIKey
+	Key string `json:"key" doc:"The API key, which is only ever returned here"`
+}
+
+// listAPIKeysResponse lists the API keys of a user
+type listAPIKeysResponse struct {
+	Data []model.APIKey `json:"data"`
+}
+
+// Create issues a new API key
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	userID := c.Param("id")
+	h.log.FromContext(c.Request.Context()).Info("Handling create API key request", "user_id", userID)
+
+	var input apiKeyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(err)
+		return
+	}
+
+	key, err := h.apiKeyService.Create(c.Request.Context(), userID, service.NewAPIKey{
+		Name:      input.Name,
+		Scopes:    input.Scopes,
+		ExpiresAt: input.ExpiresAt,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, createdAPIKeyResponse{APIKey: key.APIKey, Key: key.Key})
+}
+
+// List returns the API keys of a user, without their values
+func (h *APIKeyHandler) List(c *gin.Context) {
+	userID := c.Param("id")
+	h.log.FromContext(c.Request.Context()).Info("Handling list API keys request", "user_id", userID)
+
+	keys, err := h.apiKeyService.List(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, listAPIKeysResponse{Data: keys})
+}
+
+// Revoke deletes an API key
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	userID, keyID := c.Param("id"), c.Param("key_id")
+	h.log.FromContext(c.Request.Context()).Info("Handling revoke API key request", "user_id", userID, "id", keyID)
+
+	if err := h.apiKeyService.Revoke(c.Request.Context(), userID, keyID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,381 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/service"
+	"github.com/ThePotatoVerse/internal/pkg/config"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/ThePotatoVerse/pkg/token"
+	"github.com/gin-gonic/gin"
+)
+
+// AuthHandler handles login, logout and token requests
+type AuthHandler struct {
+	log         logger.Logger
+	authService service.AuthService
+	keys        *token.KeySet
+	cfg         *config.AuthConfig
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(log logger.Logger, authService service.AuthService, keys *token.KeySet, cfg *config.AuthConfig) *AuthHandler {
+	return &AuthHandler{
+		log:         log,
+		authService: authService,
+		keys:        keys,
+		cfg:         cfg,
+	}
+}
+
+// loginInput is the request body of login requests
+type loginInput struct {
+	Email    string `json:"email" binding:"required" format:"email"`
+	Password string `json:"password" binding:"required" format:"password"`
+}
+
+// loginResponse describes the session started by a login
+type loginResponse struct {
+	User      model.User `json:"user"`
+	ExpiresAt time.Time  `json:"expires_at"`
+}
+
+// mfaLoginInput is the request body completing a login with a second factor
+type mfaLoginInput struct {
+	MFAToken string `json:"mfa_token" binding:"required" doc:"The token of the MFA challenge returned by the login"`
+	Code     string `json:"code" binding:"required" doc:"A code from the authenticator app, or a recovery code"`
+}
+
+// mfaChallengeResponse asks for a second factor to complete a login
+type mfaChallengeResponse struct {
+	MFAToken  string    `json:"mfa_token" doc:"Completes the login together with a second factor"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// refreshInput is the request body of refresh and revoke requests
+type refreshInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// passwordResetRequestInput is the request body of password reset requests
+type passwordResetRequestInput struct {
+	Email string `json:"email" binding:"required" format:"email"`
+}
+
+// passwordResetConfirmInput is the request body of password reset confirmations
+type passwordResetConfirmInput struct {
+	Token    string `json:"token" binding:"required" doc:"The token from the password reset email"`
+	Password string `json:"password" binding:"required" format:"password"`
+}
+
+// tokenResponse describes an issued token pair. Lifetimes are in seconds.
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type" enum:"Bearer"`
+	ExpiresIn        int    `json:"expires_in"`
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresIn int    `json:"refresh_expires_in"`
+}
+
+// Login checks an email and password and starts a session held in a cookie.
+// Users with two-factor authentication get an MFA challenge instead.
+func (h *AuthHandler) Login(c *gin.Context) {
+	h.log.FromContext(c.Request.Context()).Info("Handling login request")
+
+	var input loginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(err)
+		return
+	}
+
+	result, err := h.authService.Login(c.Request.Context(), input.Email, input.Password)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if result.MFA != nil {
+		writeMFAChallenge(c, result.MFA)
+		return
+	}
+
+	h.writeSession(c, result)
+}
+
+// LoginWithMFA completes a login with a second factor and starts a session
+// held in a cookie
+func (h *AuthHandler) LoginWithMFA(c *gin.Context) {
+	h.log.FromContext(c.Request.Context()).Info("Handling login with second factor request")
+
+	var input mfaLoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(err)
+		return
+	}
+
+	result, err := h.authService.LoginWithMFA(c.Request.Context(), input.MFAToken, input.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	h.writeSession(c, result)
+}
+
+// writeSession sets the cookie of a started session and describes it
+func (h *AuthHandler) writeSession(c *gin.Context, result service.LoginResult) {
+	h.setSessionCookie(c, result.Token, time.Until(result.ExpiresAt))
+	c.JSON(http.StatusOK, loginResponse{
+		User:      result.User,
+		ExpiresAt: result.ExpiresAt,
+	})
+}
+
+// Logout ends the session of the request, if any, and clears its cookie
+func (h *AuthHandler) Logout(c *gin.Context) {
+	h.log.FromContext(c.Request.Context()).Info("Handling logout request")
+
+	token, _ := c.Cookie(h.cfg.CookieName)
+	if err := h.authService.Logout(c.Request.Context(), token); err != nil {
+		c.Error(err)
+		return
+	}
+
+	h.setSessionCookie(c, "", -1)
+	c.Status(http.StatusNoContent)
+}
+
+// IssueTokens checks an email and password and issues an access token and a
+// refresh token. Users with two-factor authentication get an MFA challenge
+// instead.
+func (h *AuthHandler) IssueTokens(c *gin.Context) {
+	h.log.FromContext(c.Request.Context()).Info("Handling issue tokens request")
+
+	var input loginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(err)
+		return
+	}
+
+	pair, err := h.authService.IssueTokens(c.Request.Context(), input.Email, input.Password)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if pair.MFA != nil {
+		writeMFAChallenge(c, pair.MFA)
+		return
+	}
+
+	writeTokens(c, pair)
+}
+
+// IssueTokensWithMFA completes a login with a second factor and issues an
+// access token and a refresh token
+func (h *AuthHandler) IssueTokensWithMFA(c *gin.Context) {
+	h.log.FromContext(c.Request.Context()).Info("Handling issue tokens with second factor request")
+
+	var input mfaLoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(err)
+		return
+	}
+
+	pair, err := h.authService.IssueTokensWithMFA(c.Request.Context(), input.MFAToken, input.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	writeTokens(c, pair)
+}
+
+// RefreshTokens exchanges a refresh token for a new token pair
+func (h *AuthHandler) RefreshTokens(c *gin.Context) {
+	h.log.FromContext(c.Request.Context()).Info("Handling refresh tokens request")
+
+	var input refreshInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(err)
+		return
+	}
+
+	pair, err := h.authService.RefreshTokens(c.Request.Context(), input.RefreshToken)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	writeTokens(c, pair)
+}
+
+// RevokeTokens revokes a refresh token and every token refreshed from the
+// same login
+func (h *AuthHandler) RevokeTokens(c *gin.Context) {
+	h.log.FromContext(c.Request.Context()).Info("Handling revoke tokens request")
+
+	var input refreshInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.authService.RevokeRefreshToken(c.Request.Context(), input.RefreshToken); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RequestPasswordReset emails a password reset token. The response is the
+// same whether or not the email is registered.
+func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
+	h.log.FromContext(c.Request.Context()).Info("Handling password reset request")
+
+	var input passwordResetRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.authService.RequestPasswordReset(c.Request.Context(), input.Email); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// ConfirmPasswordReset sets a new password with a password reset token
+func (h *AuthHandler) ConfirmPasswordReset(c *gin.Context) {
+	h.log.FromContext(c.Request.Context()).Info("Handling password reset confirmation")
+
+	var input passwordResetConfirmInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.authService.ResetPassword(c.Request.Context(), input.Token, input.Password); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// JWKS publishes the public keys access tokens are verified with
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
+
+// writeTokens writes an issued token pair, which must never be cached
+func writeTokens(c *gin.Context, pair service.TokenPair) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, tokenResponse{
+		AccessToken:      pair.AccessToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int(time.Until(pair.AccessExpiresAt).Round(time.Second).Seconds()),
+		RefreshToken:     pair.RefreshToken,
+		RefreshExpiresIn: int(time.Until(pair.RefreshExpiresAt).Round(time.Second).Seconds()),
+	})
+}
+
+// writeMFAChallenge asks for a second factor to complete a login. The
+// challenge token must never be cached either.
+func writeMFAChallenge(c *gin.Context, challenge *service.MFAChallenge) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusAccepted, mfaChallengeResponse{
+		MFAToken:  challenge.Token,
+		ExpiresAt: challenge.ExpiresAt,
+	})
+}
+
+// setSessionCookie sets the session cookie, or deletes it when maxAge is negative
+func (h *AuthHandler) setSessionCookie(c *gin.Context, token string, maxAge time.Duration) {
+	seconds := int(maxAge.Seconds())
+	if maxAge < 0 {
+		seconds = -1
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(h.cfg.CookieName, token, seconds, "/", "", h.cfg.CookieSecure, true)
+}
+
+// requireAuth creates a gin middleware that rejects requests without a valid
+// bearer access token, API key or session cookie. A bearer token takes
+// precedence over the cookie. The principal is added to the request context
+// and its user ID to the log fields.
+func requireAuth(authService service.AuthService, apiKeyService service.APIKeyService, cfg *config.AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var principal service.Principal
+		var err error
+
+		if bearer, ok := bearerToken(c.Request); ok {
+			if service.IsAPIKey(bearer) {
+				principal, err = apiKeyService.Authenticate(c.Request.Context(), bearer)
+			} else {
+				principal, err = authService.AuthenticateAccessToken(c.Request.Context(), bearer)
+			}
+			if err != nil {
+				c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			}
+		} else {
+			sessionToken, _ := c.Cookie(cfg.CookieName)
+			principal, err = authService.Authenticate(c.Request.Context(), sessionToken)
+			if err != nil {
+				c.Header("WWW-Authenticate", "Bearer")
+			}
+		}
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		ctx := service.ContextWithPrincipal(c.Request.Context(), principal)
+		ctx = logger.ContextWithFields(ctx, "user_id", principal.UserID, "user_role", principal.Role)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// authorize creates a gin middleware that rejects requests whose principal
+// may not perform action on the user named by the id path parameter, if any.
+// It must run after requireAuth.
+func authorize(action service.Action) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := authorizeRequest(c, action); err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// authorizeRequest checks that the principal of the request may perform
+// action on the user named by the id path parameter, if any
+func authorizeRequest(c *gin.Context, action service.Action) error {
+	principal, ok := service.PrincipalFromContext(c.Request.Context())
+	if !ok {
+		return service.ErrUnauthenticated
+	}
+
+	return service.Authorize(principal, action, c.Param("id"))
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, credentials, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(credentials), true
+}
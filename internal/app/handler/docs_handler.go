@@ -0,0 +1,42 @@
+package handler
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ThePotatoVerse/pkg/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+// swaggerUI is the Swagger UI page, which loads the spec from /openapi.json
+//
+//go:embed static/swagger.html
+var swaggerUI []byte
+
+// DocsHandler serves the OpenAPI document and Swagger UI
+type DocsHandler struct {
+	spec []byte
+}
+
+// NewDocsHandler creates a new docs handler serving doc
+func NewDocsHandler(doc *openapi.Document) (*DocsHandler, error) {
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DocsHandler{
+		spec: spec,
+	}, nil
+}
+
+// Spec returns the OpenAPI document
+func (h *DocsHandler) Spec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", h.spec)
+}
+
+// UI returns the Swagger UI page
+func (h *DocsHandler) UI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", swaggerUI)
+}
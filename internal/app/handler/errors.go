@@ -0,0 +1,268 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/internal/app/service"
+	"github.com/ThePotatoVerse/internal/pkg/validator"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/ThePotatoVerse/pkg/problem"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	playground "github.com/go-playground/validator/v10"
+)
+
+// problemTypeBase prefixes the type URI of application specific problems
+const problemTypeBase = "/problems/"
+
+// errorMapping describes the problem an error is reported as
+type errorMapping struct {
+	err    error
+	status int
+	slug   string
+	title  string
+}
+
+// errorMappings translates service and repository errors into problems.
+// Errors matching none of them are reported as internal server errors.
+var errorMappings = []errorMapping{
+	{service.ErrUserNotFound, http.StatusNotFound, "user-not-found", "User not found"},
+	{service.ErrInvalidInput, http.StatusBadRequest, "invalid-input", "Invalid input"},
+	{service.ErrInvalidCursor, http.StatusBadRequest, "invalid-cursor", "Invalid cursor"},
+	{service.ErrEmailTaken, http.StatusConflict, "email-taken", "Email already taken"},
+	{service.ErrVersionMismatch, http.StatusPreconditionFailed, "version-mismatch", "Version mismatch"},
+	{service.ErrInvalidPatch, http.StatusBadRequest, "invalid-patch", "Invalid patch document"},
+	{service.ErrPatchFailed, http.StatusUnprocessableEntity, "patch-failed", "Patch cannot be applied"},
+	{service.ErrReadOnlyField, http.StatusUnprocessableEntity, "read-only-field", "Read-only field"},
+	{service.ErrUnsupportedPatch, http.StatusUnsupportedMediaType, "unsupported-patch", "Unsupported patch media type"},
+	{service.ErrInvalidCredentials, http.StatusUnauthorized, "invalid-credentials", "Invalid credentials"},
+	{service.ErrUnauthenticated, http.StatusUnauthorized, "unauthenticated", "Authentication required"},
+	{service.ErrInvalidToken, http.StatusUnauthorized, "invalid-token", "Invalid token"},
+	{service.ErrForbidden, http.StatusForbidden, "forbidden", "Permission denied"},
+	{service.ErrInvalidRole, http.StatusBadRequest, "invalid-role", "Invalid role"},
+	{service.ErrAPIKeyNotFound, http.StatusNotFound, "api-key-not-found", "API key not found"},
+	{service.ErrInvalidPasswordReset, http.StatusBadRequest, "invalid-password-reset-token", "Invalid password reset token"},
+	{service.ErrInvalidVerification, http.StatusBadRequest, "invalid-verification-token", "Invalid verification token"},
+	{service.ErrInvalidMFACode, http.StatusBadRequest, "invalid-mfa-code", "Invalid two-factor authentication code"},
+	{service.ErrMFAAlreadyEnabled, http.StatusConflict, "mfa-already-enabled", "Two-factor authentication already enabled"},
+	{service.ErrMFANotEnabled, http.StatusConflict, "mfa-not-enabled", "Two-factor authentication not enabled"},
+	{service.ErrMFALocked, http.StatusTooManyRequests, "mfa-locked", "Two-factor authentication locked"},
+	{service.ErrOAuthClientNotFound, http.StatusNotFound, "oauth-client-not-found", "OAuth client not found"},
+	{service.ErrOAuthConsentNotFound, http.StatusNotFound, "oauth-consent-not-found", "OAuth consent not found"},
+	{service.ErrInvalidOAuthClient, http.StatusBadRequest, "invalid-oauth-client", "Unknown OAuth client or redirect URI"},
+	{service.ErrInvalidConsent, http.StatusBadRequest, "invalid-consent", "Invalid or expired consent form"},
+	{service.ErrOAuthRequest, http.StatusBadRequest, "invalid-oauth-request", "Invalid OAuth request"},
+	{service.ErrExternalProviderNotFound, http.StatusNotFound, "identity-provider-not-found", "Identity provider not found"},
+	{service.ErrIdentityNotFound, http.StatusNotFound, "identity-not-found", "Linked identity not found"},
+	{service.ErrInvalidExternalLogin, http.StatusBadRequest, "invalid-external-login", "Invalid or expired external login"},
+	{service.ErrExternalLoginFailed, http.StatusUnauthorized, "external-login-failed", "External login failed"},
+	{service.ErrExternalAccountNotLinked, http.StatusForbidden, "external-account-not-linked", "External account not linked"},
+	{service.ErrIdentityAlreadyLinked, http.StatusConflict, "identity-already-linked", "Identity already linked"},
+	{service.ErrLastLoginMethod, http.StatusConflict, "last-login-method", "Last login method"},
+	// Services translate repository errors about users into the user errors
+	// above, so these are reported generically
+	{repository.ErrNotFound, http.StatusNotFound, "not-found", "Not found"},
+	{repository.ErrConflict, http.StatusConflict, "conflict", "Conflict"},
+	{repository.ErrVersionMismatch, http.StatusPreconditionFailed, "version-mismatch", "Version mismatch"},
+	{repository.ErrInvalidCursor, http.StatusBadRequest, "invalid-cursor", "Invalid cursor"},
+}
+
+// problemFor translates an error into the problem it is reported as
+func problemFor(err error) *problem.Problem {
+	var p *problem.Problem
+	if errors.As(err, &p) {
+		return p
+	}
+
+	var bindingErrors playground.ValidationErrors
+	if errors.As(err, &bindingErrors) {
+		return bindingProblem(bindingErrors)
+	}
+
+	var fieldErrors validator.Errors
+	if errors.As(err, &fieldErrors) {
+		p := validationProblem("the input has invalid fields")
+		for _, fieldError := range fieldErrors {
+			p.Errors = append(p.Errors, problem.FieldError{Field: fieldError.Field, Message: fieldError.Message})
+		}
+		return p
+	}
+
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeError) && typeError.Field != "":
+		// The decoder's message names Go types, so describe the field instead
+		message := "must be a " + jsonTypeName(typeError.Type)
+		p := malformedBody(err)
+		p.Detail = fmt.Sprintf("%s %s", typeError.Field, message)
+		p.Errors = []problem.FieldError{{Field: typeError.Field, Message: message}}
+		return p
+	case errors.As(err, &typeError):
+		return malformedBody(errors.New("request body has the wrong JSON type"))
+	case errors.As(err, &syntaxError), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return malformedBody(err)
+	}
+
+	for _, mapping := range errorMappings {
+		if errors.Is(err, mapping.err) {
+			return &problem.Problem{
+				Type:   problemTypeBase + mapping.slug,
+				Title:  mapping.title,
+				Status: mapping.status,
+				Detail: err.Error(),
+			}
+		}
+	}
+
+	return problem.New(http.StatusInternalServerError)
+}
+
+// invalidParam reports an invalid query parameter
+func invalidParam(name, message string) *problem.Problem {
+	return &problem.Problem{
+		Type:   problemTypeBase + "invalid-parameter",
+		Title:  "Invalid query parameter",
+		Status: http.StatusBadRequest,
+		Detail: fmt.Sprintf("%s %s", name, message),
+		Errors: []problem.FieldError{{Field: name, Message: message}},
+	}
+}
+
+// malformedBody reports a request body that cannot be read or decoded
+func malformedBody(err error) *problem.Problem {
+	p := &problem.Problem{
+		Type:   problemTypeBase + "malformed-body",
+		Title:  "Malformed request body",
+		Status: http.StatusBadRequest,
+		Detail: err.Error(),
+	}
+	if errors.Is(err, io.EOF) {
+		p.Detail = "request body is empty"
+	}
+	return p
+}
+
+// validationProblem reports invalid fields, which the caller adds
+func validationProblem(detail string) *problem.Problem {
+	return &problem.Problem{
+		Type:   problemTypeBase + "validation-failed",
+		Title:  "Validation failed",
+		Status: http.StatusBadRequest,
+		Detail: detail,
+	}
+}
+
+// bindingProblem reports the binding rules a request body failed
+func bindingProblem(bindingErrors playground.ValidationErrors) *problem.Problem {
+	p := validationProblem("the request body has invalid fields")
+	for _, fieldError := range bindingErrors {
+		p.Errors = append(p.Errors, problem.FieldError{
+			Field:   fieldError.Field(),
+			Message: ruleMessage(fieldError),
+		})
+	}
+	return p
+}
+
+// ruleMessage describes a failed binding rule without leaking validator internals
+func ruleMessage(fieldError playground.FieldError) string {
+	switch fieldError.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	default:
+		return fmt.Sprintf("must satisfy %s", fieldError.Tag())
+	}
+}
+
+// jsonTypeName names the JSON type a Go type is decoded from
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// useJSONFieldNames makes binding errors name fields as they appear in JSON
+func useJSONFieldNames() {
+	v, ok := binding.Validator.Engine().(*playground.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	})
+}
+
+// writeProblem writes p as an application/problem+json response and aborts
+// the request
+func writeProblem(c *gin.Context, p *problem.Problem) {
+	if p.Instance == "" {
+		// Copy so that shared problems are never modified
+		instance := *p
+		instance.Instance = c.Request.URL.Path
+		p = &instance
+	}
+
+	c.Header("Content-Type", problem.ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// problemMiddleware creates a gin middleware that writes the last error a
+// handler reported with c.Error as a problem. Internal errors are logged, and
+// their details never reach the client.
+func problemMiddleware(log logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		p := problemFor(err)
+		if p.Status >= http.StatusInternalServerError {
+			log.FromContext(c.Request.Context()).Error("Request failed", "error", err)
+		}
+
+		writeProblem(c, p)
+	}
+}
+
+// noRoute reports requests to unknown routes
+func noRoute(c *gin.Context) {
+	writeProblem(c, problem.New(http.StatusNotFound))
+}
+
+// noMethod reports requests with a method the route does not support
+func noMethod(c *gin.Context) {
+	writeProblem(c, problem.New(http.StatusMethodNotAllowed))
+}
+
+// recovered reports a request whose handler panicked
+func recovered(c *gin.Context, _ interface{}) {
+	writeProblem(c, problem.New(http.StatusInternalServerError))
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+)
+
+// userETag returns the entity tag of a user, derived from its version
+func userETag(user model.User) string {
+	return fmt.Sprintf(`"%d"`, user.Version)
+}
+
+// parseETags parses an If-Match or If-None-Match header into the versions it
+// lists. wildcard is true when the header is "*". Weak tags are only kept when
+// weak is set, as If-Match uses strong comparison and If-None-Match weak.
+func parseETags(header string, weak bool) (versions []int, wildcard bool) {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil, true
+		}
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+
+		version, err := strconv.Atoi(tag[1 : len(tag)-1])
+		if err != nil || version <= 0 {
+			continue
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, false
+}
+
+// containsVersion reports whether versions contains version
+func containsVersion(versions []int, version int) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
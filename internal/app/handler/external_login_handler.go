@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/service"
+	"github.com/ThePotatoVerse/internal/pkg/config"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// externalLoginPath prefixes the external login endpoints
+	externalLoginPath = "/api/v1/auth/oidc"
+
+	// externalLoginCookie holds the state of a login between the redirect
+	// to the provider and its callback
+	externalLoginCookie = "oidc_login"
+)
+
+// ExternalLoginHandler handles logins through external OpenID Connect
+// providers and the identities linked to users
+type ExternalLoginHandler struct {
+	log                  logger.Logger
+	externalLoginService service.ExternalLoginService
+	authHandler          *AuthHandler
+	cfg                  *config.AuthConfig
+}
+
+// NewExternalLoginHandler creates a new external login handler. Sessions are
+// started through authHandler, so that their cookies match password logins.
+func NewExternalLoginHandler(log logger.Logger, externalLoginService service.ExternalLoginService, authHandler *AuthHandler, cfg *config.AuthConfig) *ExternalLoginHandler {
+	return &ExternalLoginHandler{
+		log:                  log,
+		externalLoginService: externalLoginService,
+		authHandler:          authHandler,
+		cfg:                  cfg,
+	}
+}
+
+// listExternalProvidersResponse lists the providers users can log in with
+type listExternalProvidersResponse struct {
+	Data []service.ExternalProvider `json:"data"`
+}
+
+// linkIdentityInput is the request body starting to link a provider
+type linkIdentityInput struct {
+	Provider string `json:"provider" binding:"required"`
+	ReturnTo string `json:"return_to" binding:"omitempty" doc:"Where to send the browser once the account is linked: a path on this server or a URL at an allowed origin"`
+}
+
+// linkIdentityResponse is where to send the browser to link a provider
+type linkIdentityResponse struct {
+	AuthorizationURL string    `json:"authorization_url" format:"uri" doc:"Open in the browser that made this request, which now holds the login state cookie"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+// listIdentitiesResponse lists the identities linked to a user
+type listIdentitiesResponse struct {
+	Data []model.ExternalIdentity `json:"data"`
+}
+
+// Providers lists the configured identity providers
+func (h *ExternalLoginHandler) Providers(c *gin.Context) {
+	h.log.FromContext(c.Request.Context()).Info("Handling list identity providers request")
+
+	providers, err := h.externalLoginService.Providers(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, listExternalProvidersResponse{Data: providers})
+}
+
+// Login starts a login at a provider, redirecting the browser to it
+func (h *ExternalLoginHandler) Login(c *gin.Context) {
+	provider := c.Param("provider")
+	h.log.FromContext(c.Request.Context()).Info("Handling external login request", "provider", provider)
+
+	start, err := h.externalLoginService.Begin(c.Request.Context(), provider, c.Query("return_to"), "")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	h.setStateCookie(c, start.State, time.Until(start.ExpiresAt))
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, start.AuthorizationURL)
+}
+
+// Callback completes a login when the provider redirects back. A login that
+// was started with return_to redirects there; otherwise the session or MFA
+// challenge is described like a password login. Logins needing a second
+// factor pass the MFA token to return_to in the fragment.
+func (h *ExternalLoginHandler) Callback(c *gin.Context) {
+	provider := c.Param("provider")
+	h.log.FromContext(c.Request.Context()).Info("Handling external login callback", "provider", provider)
+
+	state, _ := c.Cookie(externalLoginCookie)
+	h.setStateCookie(c, "", -1)
+	c.Header("Cache-Control", "no-store")
+
+	result, err := h.externalLoginService.Complete(c.Request.Context(), service.ExternalCallback{
+		Provider:         provider,
+		LoginState:       state,
+		State:            c.Query("state"),
+		Code:             c.Query("code"),
+		Error:            c.Query("error"),
+		ErrorDescription: c.Query("error_description"),
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	switch {
+	case result.Linked:
+		if result.ReturnTo != "" {
+			c.Redirect(http.StatusFound, result.ReturnTo)
+			return
+		}
+		c.JSON(http.StatusOK, result.Identity)
+	case result.Login.MFA != nil:
+		if result.ReturnTo != "" {
+			c.Redirect(http.StatusFound, withFragment(result.ReturnTo, url.Values{"mfa_token": {result.Login.MFA.Token}}))
+			return
+		}
+		writeMFAChallenge(c, result.Login.MFA)
+	default:
+		if result.ReturnTo != "" {
+			h.authHandler.setSessionCookie(c, result.Login.Token, time.Until(result.Login.ExpiresAt))
+			c.Redirect(http.StatusFound, result.ReturnTo)
+			return
+		}
+		h.authHandler.writeSession(c, result.Login)
+	}
+}
+
+// Link starts linking a provider to a user. The browser must then be sent to
+// the returned URL.
+func (h *ExternalLoginHandler) Link(c *gin.Context) {
+	userID := c.Param("id")
+	h.log.FromContext(c.Request.Context()).Info("Handling link identity request", "user_id", userID)
+
+	var input linkIdentityInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(err)
+		return
+	}
+
+	start, err := h.externalLoginService.Begin(c.Request.Context(), input.Provider, input.ReturnTo, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	h.setStateCookie(c, start.State, time.Until(start.ExpiresAt))
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, linkIdentityResponse{AuthorizationURL: start.AuthorizationURL, ExpiresAt: start.ExpiresAt})
+}
+
+// ListIdentities lists the identities linked to a user
+func (h *ExternalLoginHandler) ListIdentities(c *gin.Context) {
+	userID := c.Param("id")
+	h.log.FromContext(c.Request.Context()).Info("Handling list identities request", "user_id", userID)
+
+	identities, err := h.externalLoginService.ListIdentities(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, listIdentitiesResponse{Data: identities})
+}
+
+// Unlink removes the identity a user has linked at a provider
+func (h *ExternalLoginHandler) Unlink(c *gin.Context) {
+	userID, provider := c.Param("id"), c.Param("provider")
+	h.log.FromContext(c.Request.Context()).Info("Handling unlink identity request", "user_id", userID, "provider", provider)
+
+	if err := h.externalLoginService.Unlink(c.Request.Context(), userID, provider); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// setStateCookie sets the login state cookie, or deletes it when maxAge is
+// negative. It is only sent to the external login endpoints, and is Lax so
+// that it comes along when the provider redirects back.
+func (h *ExternalLoginHandler) setStateCookie(c *gin.Context, state string, maxAge time.Duration) {
+	seconds := int(maxAge.Seconds())
+	if maxAge < 0 {
+		seconds = -1
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(externalLoginCookie, state, seconds, externalLoginPath, "", h.cfg.CookieSecure, true)
+}
+
+// withFragment replaces the fragment of uri with params, which browsers do
+// not send to servers or in Referer headers
+func withFragment(uri string, params url.Values) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	u.Fragment = ""
+	return u.String() + "#" + params.Encode()
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/service"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// MFAHandler handles HTTP requests for the second factors of a user
+type MFAHandler struct {
+	log        logger.Logger
+	mfaService service.MFAService
+}
+
+// NewMFAHandler creates a new MFA handler
+func NewMFAHandler(log logger.Logger, mfaService service.MFAService) *MFAHandler {
+	return &MFAHandler{
+		log:        log,
+		mfaService: mfaService,
+	}
+}
+
+// mfaCodeInput is the request body of requests that need a current code
+type mfaCodeInput struct {
+	Code string `json:"code" binding:"required" doc:"A code from the authenticator app, or a recovery code"`
+}
+
+// mfaDisableInput is the request body of disable requests
+type mfaDisableInput struct {
+	Code string `json:"code" doc:"A code from the authenticator app, or a recovery code; omitted when cancelling an unconfirmed enrollment, or by admins disabling the factor of another user"`
+}
+
+// mfaStatusResponse describes the second factors of a user
+type mfaStatusResponse struct {
+	TOTPEnabled            bool       `json:"totp_enabled"`
+	EnabledAt              *time.Time `json:"enabled_at"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+// totpEnrollmentResponse is a TOTP secret waiting to be confirmed
+type totpEnrollmentResponse struct {
+	Secret     string `json:"secret" doc:"The base32 secret, for entering into an authenticator app by hand"`
+	OTPAuthURI string `json:"otpauth_uri" doc:"The otpauth:// URI to show as a QR code"`
+}
+
+// recoveryCodesResponse lists new recovery codes
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" doc:"Single-use codes that replace a TOTP code, which are only ever returned here"`
+}
+
+// Status describes the second factors of a user
+func (h *MFAHandler) Status(c *gin.Context) {
+	userID := c.Param("id")
+	h.log.FromContext(c.Request.Context()).Info("Handling MFA status request", "user_id", userID)
+
+	status, err := h.mfaService.Status(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mfaStatusResponse{
+		TOTPEnabled:            status.TOTPEnabled,
+		EnabledAt:              status.EnabledAt,
+		RecoveryCodesRemaining: status.RecoveryCodesRemaining,
+	})
+}
+
+// EnrollTOTP generates a TOTP secret, which must be confirmed before it is used
+func (h *MFAHandler) EnrollTOTP(c *gin.Context) {
+	userID := c.Param("id")
+	h.log.FromContext(c.Request.Context()).Info("Handling enroll TOTP request", "user_id", userID)
+
+	enrollment, err := h.mfaService.EnrollTOTP(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, totpEnrollmentResponse{
+		Secret:     enrollment.Secret,
+		OTPAuthURI: enrollment.URI,
+	})
+}
+
+// ConfirmTOTP enables the enrolled TOTP secret with a first code from it
+func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
+	userID := c.Param("id")
+	h.log.FromContext(c.Request.Context()).Info("Handling confirm TOTP request", "user_id", userID)
+
+	var input mfaCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(err)
+		return
+	}
+
+	codes, err := h.mfaService.ConfirmTOTP(c.Request.Context(), userID, input.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	writeRecoveryCodes(c, codes)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID := c.Param("id")
+	h.log.FromContext(c.Request.Context()).Info("Handling regenerate recovery codes request", "user_id", userID)
+
+	var input mfaCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(err)
+		return
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(c.Request.Context(), userID, input.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	writeRecoveryCodes(c, codes)
+}
+
+// Disable removes the second factors of a user
+func (h *MFAHandler) Disable(c *gin.Context) {
+	userID := c.Param("id")
+	h.log.FromContext(c.Request.Context()).Info("Handling disable MFA request", "user_id", userID)
+
+	// The body is optional, since admins need no code
+	var input mfaDisableInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.Error(err)
+		return
+	}
+
+	if err := h.mfaService.Disable(c.Request.Context(), userID, input.Code); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// writeRecoveryCodes writes new recovery codes, which must never be cached
+func writeRecoveryCodes(c *gin.Context, codes []string) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/service"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// OAuthClientHandler handles HTTP requests for registering OAuth clients,
+// and for the clients users have consented to
+type OAuthClientHandler struct {
+	log          logger.Logger
+	oauthService service.OAuthService
+}
+
+// NewOAuthClientHandler creates a new OAuth client handler
+func NewOAuthClientHandler(log logger.Logger, oauthService service.OAuthService) *OAuthClientHandler {
+	return &OAuthClientHandler{
+		log:          log,
+		oauthService: oauthService,
+	}
+}
+
+// oauthClientInput is the request body of OAuth client create requests
+type oauthClientInput struct {
+	Name         string   `json:"name" binding:"required"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required" doc:"Exact URIs the client may be redirected to: https, http on a loopback address, or a private scheme such as com.example.app"`
+	Confidential bool     `json:"confidential" doc:"Whether the client can keep a secret, such as a server-side web app"`
+}
+
+// oauthClientResponse is a registered OAuth client
+type oauthClientResponse struct {
+	model.OAuthClient
+	Confidential bool `json:"confidential" doc:"Whether the client authenticates with a secret"`
+}
+
+// createdOAuthClientResponse is a newly registered OAuth client, including
+// its secret
+type createdOAuthClientResponse struct {
+	model.OAuthClient
+	Confidential bool   `json:"confidential" doc:"Whether the client authenticates with a secret"`
+	ClientSecret string `json:"client_secret,omitempty" doc:"The secret of a confidential client, which is only ever returned here"`
+}
+
+// listOAuthClientsResponse lists the registered OAuth clients
+type listOAuthClientsResponse struct {
+	Data []oauthClientResponse `json:"data"`
+}
+
+// oauthConsentResponse is a client a user has consented to
+type oauthConsentResponse struct {
+	Client    oauthClientSummary `json:"client"`
+	Scopes    []string           `json:"scopes"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+// listOAuthConsentsResponse lists the clients a user has consented to
+type listOAuthConsentsResponse struct {
+	Data []oauthConsentResponse `json:"data"`
+}
+
+// Create registers a new client
+func (h *OAuthClientHandler) Create(c *gin.Context) {
+	h.log.FromContext(c.Request.Context()).Info("Handling create OAuth client request")
+
+	var input oauthClientInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(err)
+		return
+	}
+
+	client, err := h.oauthService.CreateClient(c.Request.Context(), service.NewOAuthClient{
+		Name:         input.Name,
+		RedirectURIs: input.RedirectURIs,
+		Confidential: input.Confidential,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, createdOAuthClientResponse{
+		OAuthClient:  client.OAuthClient,
+		Confidential: client.Confidential(),
+		ClientSecret: client.Secret,
+	})
+}
+
+// List returns the registered clients
+func (h *OAuthClientHandler) List(c *gin.Context) {
+	h.log.FromContext(c.Request.Context()).Info("Handling list OAuth clients request")
+
+	clients, err := h.oauthService.ListClients(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response := listOAuthClientsResponse{Data: make([]oauthClientResponse, len(clients))}
+	for i, client := range clients {
+		response.Data[i] = newOAuthClientResponse(client)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Get returns a registered client
+func (h *OAuthClientHandler) Get(c *gin.Context) {
+	id := c.Param("client_id")
+	h.log.FromContext(c.Request.Context()).Info("Handling get OAuth client request", "client_id", id)
+
+	client, err := h.oauthService.GetClient(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newOAuthClientResponse(client))
+}
+
+// Delete removes a client, together with the consents given to it
+func (h *OAuthClientHandler) Delete(c *gin.Context) {
+	id := c.Param("client_id")
+	h.log.FromContext(c.Request.Context()).Info("Handling delete OAuth client request", "client_id", id)
+
+	if err := h.oauthService.DeleteClient(c.Request.Context(), id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListConsents returns the clients a user has consented to
+func (h *OAuthClientHandler) ListConsents(c *gin.Context) {
+	userID := c.Param("id")
+	h.log.FromContext(c.Request.Context()).Info("Handling list OAuth consents request", "user_id", userID)
+
+	authorized, err := h.oauthService.ListConsents(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response := listOAuthConsentsResponse{Data: make([]oauthConsentResponse, len(authorized))}
+	for i, consent := range authorized {
+		response.Data[i] = oauthConsentResponse{
+			Client:    oauthClientSummary{ID: consent.Client.ID, Name: consent.Client.Name},
+			Scopes:    consent.Scopes,
+			CreatedAt: consent.CreatedAt,
+			UpdatedAt: consent.UpdatedAt,
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeConsent removes the consent of a user to a client
+func (h *OAuthClientHandler) RevokeConsent(c *gin.Context) {
+	userID, clientID := c.Param("id"), c.Param("client_id")
+	h.log.FromContext(c.Request.Context()).Info("Handling revoke OAuth consent request", "user_id", userID, "client_id", clientID)
+
+	if err := h.oauthService.RevokeConsent(c.Request.Context(), userID, clientID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// newOAuthClientResponse describes client
+func newOAuthClientResponse(client model.OAuthClient) oauthClientResponse {
+	return oauthClientResponse{OAuthClient: client, Confidential: client.Confidential()}
+}
@@ -0,0 +1,367 @@
+package handler
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ThePotatoVerse/internal/app/service"
+	"github.com/ThePotatoVerse/internal/pkg/config"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/ThePotatoVerse/pkg/token"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// OAuth provider endpoints, relative to the issuer
+const (
+	oauthAuthorizePath = "/oauth/authorize"
+	oauthTokenPath     = "/oauth/token"
+	oauthUserInfoPath  = "/oauth/userinfo"
+	jwksPath           = "/.well-known/jwks.json"
+)
+
+// consentHTML is the page asking users to approve a client
+//
+//go:embed static/oauth_consent.html
+var consentHTML string
+
+// consentPage renders consentHTML, escaping the client name and scopes
+var consentPage = template.Must(template.New("consent").Parse(consentHTML))
+
+// scopeDescriptions explains each scope on the consent page
+var scopeDescriptions = map[string]string{
+	service.OAuthScopeOpenID:  "Know who you are on ThePotatoVerse",
+	service.OAuthScopeProfile: "See your name",
+	service.OAuthScopeEmail:   "See your email address and whether it is verified",
+}
+
+// OAuthHandler handles the OAuth 2.0 and OpenID Connect provider endpoints
+type OAuthHandler struct {
+	log          logger.Logger
+	oauthService service.OAuthService
+	authService  service.AuthService
+	keys         *token.KeySet
+	issuer       string
+	cfg          *config.AuthConfig
+}
+
+// NewOAuthHandler creates a new OAuth provider handler. Users authorize
+// clients with their session cookie; issuer is the public URL of the server.
+func NewOAuthHandler(log logger.Logger, oauthService service.OAuthService, authService service.AuthService, keys *token.KeySet, issuer string, cfg *config.AuthConfig) *OAuthHandler {
+	return &OAuthHandler{
+		log:          log,
+		oauthService: oauthService,
+		authService:  authService,
+		keys:         keys,
+		issuer:       strings.TrimSuffix(issuer, "/"),
+		cfg:          cfg,
+	}
+}
+
+// consentInput is the consent form submitted by the user
+type consentInput struct {
+	ConsentToken string `form:"consent_token" json:"consent_token" binding:"required" doc:"The consent token of the consent page"`
+	Decision     string `form:"decision" json:"decision" binding:"required,oneof=approve deny" enum:"approve,deny"`
+}
+
+// consentResponse asks the user to approve a client, for clients that
+// render the consent page themselves
+type consentResponse struct {
+	Client       oauthClientSummary `json:"client"`
+	Scopes       []string           `json:"scopes"`
+	ConsentToken string             `json:"consent_token" doc:"To submit to POST /oauth/authorize with the decision of the user"`
+}
+
+// oauthClientSummary is the public description of a client
+type oauthClientSummary struct {
+	ID   string `json:"id" format:"uuid"`
+	Name string `json:"name"`
+}
+
+// oauthTokenInput is the form-encoded body of token requests
+type oauthTokenInput struct {
+	GrantType    string `form:"grant_type" json:"grant_type" binding:"required" enum:"authorization_code"`
+	Code         string `form:"code" json:"code" binding:"required"`
+	RedirectURI  string `form:"redirect_uri" json:"redirect_uri" binding:"required" doc:"The redirect URI of the authorization request"`
+	CodeVerifier string `form:"code_verifier" json:"code_verifier" binding:"required" doc:"The PKCE verifier of the code challenge"`
+	ClientID     string `form:"client_id" json:"client_id" binding:"omitempty" doc:"Required unless the client authenticates with HTTP Basic"`
+	ClientSecret string `form:"client_secret" json:"client_secret" binding:"omitempty" doc:"The secret of a confidential client, unless it authenticates with HTTP Basic"`
+}
+
+// oauthTokenResponse is a successful token response (RFC 6749 section 5.1)
+type oauthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type" enum:"Bearer"`
+	ExpiresIn   int    `json:"expires_in" doc:"Lifetime of the access token in seconds"`
+	IDToken     string `json:"id_token,omitempty" doc:"OpenID Connect ID token"`
+	Scope       string `json:"scope"`
+}
+
+// oauthErrorResponse is an error response of the token endpoint (RFC 6749
+// section 5.2)
+type oauthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// providerMetadata is the OpenID Connect discovery document
+type providerMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+	AuthorizationResponseISSSupported bool     `json:"authorization_response_iss_parameter_supported"`
+}
+
+// Authorize handles authorization requests. Users without a session are
+// sent to log in first, if a login page is configured. Users who have not
+// consented to the client yet get a consent page, as HTML or as JSON
+// depending on what they accept; everyone else is redirected back to the
+// client.
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	clientID := c.Query("client_id")
+	h.log.FromContext(c.Request.Context()).Info("Handling OAuth authorize request", "client_id", clientID)
+
+	principal, err := h.sessionPrincipal(c)
+	if err != nil {
+		if h.cfg.OAuth.LoginURL != "" && errors.Is(err, service.ErrUnauthenticated) {
+			returnTo := h.issuer + c.Request.URL.RequestURI()
+			c.Redirect(http.StatusFound, service.WithQuery(h.cfg.OAuth.LoginURL, url.Values{"return_to": {returnTo}}))
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	authorization, err := h.oauthService.Authorize(c.Request.Context(), principal.UserID, service.AuthorizationRequest{
+		ResponseType:        c.Query("response_type"),
+		ClientID:            clientID,
+		RedirectURI:         c.Query("redirect_uri"),
+		Scope:               c.Query("scope"),
+		State:               c.Query("state"),
+		CodeChallenge:       c.Query("code_challenge"),
+		CodeChallengeMethod: c.Query("code_challenge_method"),
+		Nonce:               c.Query("nonce"),
+	})
+	if err != nil {
+		h.redirectError(c, err)
+		return
+	}
+	if authorization.RedirectURL != "" {
+		c.Redirect(http.StatusFound, authorization.RedirectURL)
+		return
+	}
+
+	h.writeConsent(c, authorization)
+}
+
+// Consent handles the consent form, and redirects back to the client with
+// a code or an access_denied error
+func (h *OAuthHandler) Consent(c *gin.Context) {
+	h.log.FromContext(c.Request.Context()).Info("Handling OAuth consent request")
+
+	principal, err := h.sessionPrincipal(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var input consentInput
+	if err := c.ShouldBind(&input); err != nil {
+		c.Error(err)
+		return
+	}
+
+	authorization, err := h.oauthService.Consent(c.Request.Context(), principal.UserID, input.ConsentToken, input.Decision == "approve")
+	if err != nil {
+		h.redirectError(c, err)
+		return
+	}
+
+	c.Redirect(http.StatusSeeOther, authorization.RedirectURL)
+}
+
+// Token exchanges an authorization code for tokens. Clients authenticate
+// with HTTP Basic or with client_id and client_secret in the body, and
+// errors are reported as RFC 6749 error responses rather than problems.
+func (h *OAuthHandler) Token(c *gin.Context) {
+	h.log.FromContext(c.Request.Context()).Info("Handling OAuth token request")
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var input oauthTokenInput
+	if err := c.ShouldBindWith(&input, binding.FormPost); err != nil {
+		writeOAuthError(c, http.StatusBadRequest, service.OAuthInvalidRequest, "grant_type, code, redirect_uri and code_verifier are required in a form-encoded body")
+		return
+	}
+
+	// The credentials of HTTP Basic are form-encoded (RFC 6749 section 2.3.1)
+	id, secret, basic := c.Request.BasicAuth()
+	if basic {
+		var idErr, secretErr error
+		id, idErr = url.QueryUnescape(id)
+		secret, secretErr = url.QueryUnescape(secret)
+		if idErr != nil || secretErr != nil || input.ClientSecret != "" || input.ClientID != "" && input.ClientID != id {
+			writeOAuthError(c, http.StatusBadRequest, service.OAuthInvalidRequest, "the client must authenticate with one method only")
+			return
+		}
+		input.ClientID, input.ClientSecret = id, secret
+	}
+
+	tokens, err := h.oauthService.Exchange(c.Request.Context(), service.TokenRequest{
+		GrantType:    input.GrantType,
+		Code:         input.Code,
+		RedirectURI:  input.RedirectURI,
+		CodeVerifier: input.CodeVerifier,
+		ClientID:     input.ClientID,
+		ClientSecret: input.ClientSecret,
+	})
+	if err != nil {
+		var oauthErr *service.OAuthError
+		if !errors.As(err, &oauthErr) {
+			c.Error(err)
+			return
+		}
+
+		status := http.StatusBadRequest
+		if oauthErr.Code == service.OAuthInvalidClient {
+			status = http.StatusUnauthorized
+			if basic {
+				c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+			}
+		}
+		writeOAuthError(c, status, oauthErr.Code, oauthErr.Description)
+		return
+	}
+
+	c.JSON(http.StatusOK, oauthTokenResponse{
+		AccessToken: tokens.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(tokens.ExpiresIn.Seconds()),
+		IDToken:     tokens.IDToken,
+		Scope:       strings.Join(tokens.Scopes, " "),
+	})
+}
+
+// UserInfo returns the claims about the user an access token was issued for
+func (h *OAuthHandler) UserInfo(c *gin.Context) {
+	h.log.FromContext(c.Request.Context()).Info("Handling OAuth userinfo request")
+
+	bearer, ok := bearerToken(c.Request)
+	if !ok {
+		c.Header("WWW-Authenticate", "Bearer")
+		c.Error(service.ErrUnauthenticated)
+		return
+	}
+
+	info, err := h.oauthService.UserInfo(c.Request.Context(), bearer)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidToken):
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		case errors.Is(err, service.ErrForbidden):
+			c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+		}
+		c.Error(err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, info)
+}
+
+// Discovery returns the OpenID Connect discovery document
+func (h *OAuthHandler) Discovery(c *gin.Context) {
+	c.JSON(http.StatusOK, providerMetadata{
+		Issuer:                            h.issuer,
+		AuthorizationEndpoint:             h.issuer + oauthAuthorizePath,
+		TokenEndpoint:                     h.issuer + oauthTokenPath,
+		UserInfoEndpoint:                  h.issuer + oauthUserInfoPath,
+		JWKSURI:                           h.issuer + jwksPath,
+		ScopesSupported:                   service.OAuthScopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  h.keys.Algorithms(),
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "nonce", "name", "updated_at", "email", "email_verified"},
+		AuthorizationResponseISSSupported: true,
+	})
+}
+
+// sessionPrincipal authenticates the session cookie of the request. Only
+// sessions can authorize clients, so that neither clients nor API keys can
+// grant themselves access on behalf of the user.
+func (h *OAuthHandler) sessionPrincipal(c *gin.Context) (service.Principal, error) {
+	sessionToken, _ := c.Cookie(h.cfg.CookieName)
+	return h.authService.Authenticate(c.Request.Context(), sessionToken)
+}
+
+// redirectError sends authorization errors back to the client when the
+// redirect URI has been verified, and reports them as problems otherwise
+func (h *OAuthHandler) redirectError(c *gin.Context, err error) {
+	var oauthErr *service.OAuthError
+	if errors.As(err, &oauthErr) && oauthErr.RedirectURL() != "" {
+		c.Redirect(http.StatusFound, oauthErr.RedirectURL())
+		return
+	}
+	c.Error(err)
+}
+
+// writeConsent asks the user to approve a client
+func (h *OAuthHandler) writeConsent(c *gin.Context, authorization service.Authorization) {
+	c.Header("Cache-Control", "no-store")
+
+	if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+		c.JSON(http.StatusOK, consentResponse{
+			Client:       oauthClientSummary{ID: authorization.Client.ID, Name: authorization.Client.Name},
+			Scopes:       authorization.Scopes,
+			ConsentToken: authorization.ConsentToken,
+		})
+		return
+	}
+
+	scopes := make([]string, len(authorization.Scopes))
+	for i, scope := range authorization.Scopes {
+		scopes[i] = scopeDescriptions[scope]
+	}
+
+	var page bytes.Buffer
+	err := consentPage.Execute(&page, map[string]interface{}{
+		"ClientName":   authorization.Client.Name,
+		"Scopes":       scopes,
+		"ConsentToken": authorization.ConsentToken,
+		"Action":       oauthAuthorizePath,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// The page must not be framed, or other sites could trick users into
+	// approving clients
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
+
+// writeOAuthError writes an RFC 6749 error response
+func writeOAuthError(c *gin.Context, status int, code, description string) {
+	c.JSON(status, oauthErrorResponse{Error: code, ErrorDescription: description})
+}
@@ -0,0 +1,972 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/service"
+	"github.com/ThePotatoVerse/internal/pkg/config"
+	"github.com/ThePotatoVerse/pkg/health"
+	"github.com/ThePotatoVerse/pkg/openapi"
+	"github.com/ThePotatoVerse/pkg/problem"
+	"github.com/ThePotatoVerse/pkg/token"
+)
+
+// APIVersion is the version reported in the OpenAPI document
+const APIVersion = "1.0.0"
+
+// NewOpenAPI describes every documented route served by NewRouter. Tests fail
+// when the two drift apart, so update both together.
+func NewOpenAPI(authCfg *config.AuthConfig) *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "ThePotatoVerse API",
+		Description: "User management API",
+		Version:     APIVersion,
+	})
+
+	userSchema := doc.Schema("User", model.User{})
+	inputSchema := doc.Schema("UserInput", userInput{})
+	createInputSchema := doc.Schema("CreateUserInput", createUserInput{})
+	roleInputSchema := doc.Schema("RoleInput", roleInput{})
+	loginInputSchema := doc.Schema("LoginInput", loginInput{})
+	loginSchema := doc.Schema("Login", loginResponse{})
+	mfaLoginInputSchema := doc.Schema("MFALoginInput", mfaLoginInput{})
+	mfaChallengeSchema := doc.Schema("MFAChallenge", mfaChallengeResponse{})
+	refreshInputSchema := doc.Schema("RefreshInput", refreshInput{})
+	resetRequestSchema := doc.Schema("PasswordResetRequestInput", passwordResetRequestInput{})
+	resetConfirmSchema := doc.Schema("PasswordResetConfirmInput", passwordResetConfirmInput{})
+	tokensSchema := doc.Schema("Tokens", tokenResponse{})
+	jwksSchema := doc.Schema("JWKS", token.JWKS{})
+	listSchema := doc.Schema("UserList", listUsersResponse{})
+	doc.Schema("APIKey", model.APIKey{})
+	apiKeyInputSchema := doc.Schema("APIKeyInput", apiKeyInput{})
+	createdAPIKeySchema := doc.Schema("CreatedAPIKey", createdAPIKeyResponse{})
+	apiKeyListSchema := doc.Schema("APIKeyList", listAPIKeysResponse{})
+	mfaStatusSchema := doc.Schema("MFAStatus", mfaStatusResponse{})
+	mfaCodeInputSchema := doc.Schema("MFACodeInput", mfaCodeInput{})
+	mfaDisableInputSchema := doc.Schema("MFADisableInput", mfaDisableInput{})
+	totpEnrollmentSchema := doc.Schema("TOTPEnrollment", totpEnrollmentResponse{})
+	recoveryCodesSchema := doc.Schema("RecoveryCodes", recoveryCodesResponse{})
+	consentInputSchema := doc.Schema("OAuthConsentInput", consentInput{})
+	consentSchema := doc.Schema("OAuthConsentRequest", consentResponse{})
+	oauthTokenInputSchema := doc.Schema("OAuthTokenInput", oauthTokenInput{})
+	oauthTokensSchema := doc.Schema("OAuthTokens", oauthTokenResponse{})
+	oauthErrorSchema := doc.Schema("OAuthError", oauthErrorResponse{})
+	userInfoSchema := doc.Schema("UserInfo", service.UserInfo{})
+	providerMetadataSchema := doc.Schema("OpenIDProviderMetadata", providerMetadata{})
+	oauthClientInputSchema := doc.Schema("OAuthClientInput", oauthClientInput{})
+	oauthClientSchema := doc.Schema("OAuthClient", oauthClientResponse{})
+	createdOAuthClientSchema := doc.Schema("CreatedOAuthClient", createdOAuthClientResponse{})
+	oauthClientListSchema := doc.Schema("OAuthClientList", listOAuthClientsResponse{})
+	oauthConsentListSchema := doc.Schema("OAuthConsentList", listOAuthConsentsResponse{})
+	providerListSchema := doc.Schema("IdentityProviderList", listExternalProvidersResponse{})
+	identitySchema := doc.Schema("ExternalIdentity", model.ExternalIdentity{})
+	identityListSchema := doc.Schema("ExternalIdentityList", listIdentitiesResponse{})
+	linkIdentityInputSchema := doc.Schema("LinkIdentityInput", linkIdentityInput{})
+	linkIdentitySchema := doc.Schema("LinkIdentity", linkIdentityResponse{})
+	problemSchema := doc.Schema("Problem", problem.Problem{})
+
+	// Scopes are actions, which the Go types cannot enumerate
+	actions := make([]interface{}, len(service.Actions))
+	for i, action := range service.Actions {
+		actions[i] = string(action)
+	}
+	for _, name := range []string{"APIKey", "APIKeyInput", "CreatedAPIKey"} {
+		doc.Components.Schemas[name].Properties["scopes"].Items.Enum = actions
+	}
+	doc.Components.Schemas["HealthReport"] = healthReportSchema()
+	doc.Components.SecuritySchemes["sessionCookie"] = &openapi.SecurityScheme{
+		Type:        "apiKey",
+		In:          "cookie",
+		Name:        authCfg.CookieName,
+		Description: "Session cookie set by POST /api/v1/auth/login",
+	}
+	doc.Components.SecuritySchemes["bearerAuth"] = &openapi.SecurityScheme{
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+		Description:  "Access token issued by POST /api/v1/auth/tokens, or a personal API key",
+	}
+	doc.Components.SecuritySchemes["oauthAccessToken"] = &openapi.SecurityScheme{
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+		Description:  "Access token issued to an OAuth client by POST /oauth/token",
+	}
+	doc.Components.SecuritySchemes["oauthClientBasic"] = &openapi.SecurityScheme{
+		Type:        "http",
+		Scheme:      "basic",
+		Description: "The ID and secret of an OAuth client",
+	}
+
+	failure := func(description string) openapi.Response {
+		return openapi.Response{Description: description, Content: map[string]openapi.MediaType{
+			problem.ContentType: {Schema: problemSchema},
+		}}
+	}
+	// authenticated marks an operation as requiring a session or access token,
+	// and a role permitted to perform it
+	authenticated := func(op *openapi.Operation) *openapi.Operation {
+		op.Security = []openapi.SecurityRequirement{{"bearerAuth": {}}, {"sessionCookie": {}}}
+		op.Responses["401"] = failure("Authentication required")
+		op.Responses["403"] = failure("The role of the caller does not permit this")
+		return op
+	}
+	userResponse := func(description string) openapi.Response {
+		return openapi.Response{
+			Description: description,
+			Headers:     map[string]openapi.Header{"ETag": etagHeader},
+			Content:     jsonContent(userSchema),
+		}
+	}
+
+	// Health checks
+	doc.Add(http.MethodGet, "/livez", &openapi.Operation{
+		OperationID: "livez",
+		Summary:     "Liveness probe",
+		Tags:        []string{"health"},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The process is running", Content: jsonContent(&openapi.Schema{
+				Type:       "object",
+				Properties: map[string]*openapi.Schema{"status": {Type: "string"}},
+				Required:   []string{"status"},
+			})},
+		},
+	})
+	for _, path := range []string{"/readyz", "/health"} {
+		doc.Add(http.MethodGet, path, &openapi.Operation{
+			OperationID: path[1:],
+			Summary:     "Readiness probe",
+			Tags:        []string{"health"},
+			Responses: map[string]openapi.Response{
+				"200": {Description: "Every dependency is healthy", Content: jsonContent(openapi.Ref("HealthReport"))},
+				"503": {Description: "A dependency is unhealthy or the server is shutting down", Content: jsonContent(openapi.Ref("HealthReport"))},
+			},
+		})
+	}
+
+	// Metrics
+	doc.Add(http.MethodGet, "/metrics", &openapi.Operation{
+		OperationID: "metrics",
+		Summary:     "Prometheus metrics",
+		Tags:        []string{"observability"},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Metrics in the Prometheus text format", Content: map[string]openapi.MediaType{
+				"text/plain": {Schema: &openapi.Schema{Type: "string"}},
+			}},
+		},
+	})
+
+	// Documentation
+	doc.Add(http.MethodGet, "/openapi.json", &openapi.Operation{
+		OperationID: "openapi",
+		Summary:     "This OpenAPI document",
+		Tags:        []string{"documentation"},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The OpenAPI document", Content: jsonContent(&openapi.Schema{Type: "object"})},
+		},
+	})
+
+	// Auth
+	loggedInResponse := openapi.Response{
+		Description: "Logged in",
+		Headers: map[string]openapi.Header{
+			"Set-Cookie": {Description: "The session cookie", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Content: jsonContent(loginSchema),
+	}
+	mfaChallengeResponse := openapi.Response{
+		Description: "The password was correct, and the user must complete the login with a second factor",
+		Headers: map[string]openapi.Header{
+			"Cache-Control": {Description: "Always no-store", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Content: jsonContent(mfaChallengeSchema),
+	}
+	doc.Add(http.MethodPost, "/api/v1/auth/login", &openapi.Operation{
+		OperationID: "login",
+		Summary:     "Log in with an email and password",
+		Tags:        []string{"auth"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(loginInputSchema)},
+		Responses: map[string]openapi.Response{
+			"200": loggedInResponse,
+			"202": mfaChallengeResponse,
+			"400": failure("Invalid request body"),
+			"401": failure("Invalid email or password"),
+			"500": failure("Internal error"),
+		},
+	})
+	doc.Add(http.MethodPost, "/api/v1/auth/login/mfa", &openapi.Operation{
+		OperationID: "loginWithMFA",
+		Summary:     "Complete a login with a TOTP or recovery code",
+		Tags:        []string{"auth"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(mfaLoginInputSchema)},
+		Responses: map[string]openapi.Response{
+			"200": loggedInResponse,
+			"400": failure("Invalid request body, or wrong code"),
+			"401": failure("Invalid or expired MFA token, or too many wrong codes"),
+			"500": failure("Internal error"),
+		},
+	})
+	doc.Add(http.MethodPost, "/api/v1/auth/logout", &openapi.Operation{
+		OperationID: "logout",
+		Summary:     "End the current session",
+		Tags:        []string{"auth"},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Logged out, and the session cookie cleared"},
+			"500": failure("Internal error"),
+		},
+	})
+
+	tokensResponse := openapi.Response{
+		Description: "The issued tokens",
+		Headers: map[string]openapi.Header{
+			"Cache-Control": {Description: "Always no-store", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Content: jsonContent(tokensSchema),
+	}
+	doc.Add(http.MethodPost, "/api/v1/auth/tokens", &openapi.Operation{
+		OperationID: "issueTokens",
+		Summary:     "Exchange an email and password for an access token and a refresh token",
+		Tags:        []string{"auth"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(loginInputSchema)},
+		Responses: map[string]openapi.Response{
+			"200": tokensResponse,
+			"202": mfaChallengeResponse,
+			"400": failure("Invalid request body"),
+			"401": failure("Invalid email or password"),
+			"500": failure("Internal error"),
+		},
+	})
+	doc.Add(http.MethodPost, "/api/v1/auth/tokens/mfa", &openapi.Operation{
+		OperationID: "issueTokensWithMFA",
+		Summary:     "Complete a token request with a TOTP or recovery code",
+		Tags:        []string{"auth"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(mfaLoginInputSchema)},
+		Responses: map[string]openapi.Response{
+			"200": tokensResponse,
+			"400": failure("Invalid request body, or wrong code"),
+			"401": failure("Invalid or expired MFA token, or too many wrong codes"),
+			"500": failure("Internal error"),
+		},
+	})
+	doc.Add(http.MethodPost, "/api/v1/auth/tokens/refresh", &openapi.Operation{
+		OperationID: "refreshTokens",
+		Summary:     "Exchange a refresh token for new tokens; each refresh token can be used once",
+		Tags:        []string{"auth"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(refreshInputSchema)},
+		Responses: map[string]openapi.Response{
+			"200": tokensResponse,
+			"400": failure("Invalid request body"),
+			"401": failure("Invalid, expired or reused refresh token"),
+			"500": failure("Internal error"),
+		},
+	})
+	doc.Add(http.MethodPost, "/api/v1/auth/tokens/revoke", &openapi.Operation{
+		OperationID: "revokeTokens",
+		Summary:     "Revoke a refresh token and every token refreshed from the same login",
+		Tags:        []string{"auth"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(refreshInputSchema)},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "The tokens were revoked, or were unknown"},
+			"400": failure("Invalid request body"),
+			"500": failure("Internal error"),
+		},
+	})
+	doc.Add(http.MethodPost, "/api/v1/auth/password-reset/request", &openapi.Operation{
+		OperationID: "requestPasswordReset",
+		Summary:     "Email a password reset token",
+		Tags:        []string{"auth"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(resetRequestSchema)},
+		Responses: map[string]openapi.Response{
+			"202": {Description: "A token was emailed if the email is registered and has not asked too often"},
+			"400": failure("Invalid request body"),
+			"500": failure("Internal error"),
+		},
+	})
+	doc.Add(http.MethodPost, "/api/v1/auth/password-reset/confirm", &openapi.Operation{
+		OperationID: "confirmPasswordReset",
+		Summary:     "Set a new password with a password reset token",
+		Tags:        []string{"auth"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(resetConfirmSchema)},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "The password was changed and every session of the user ended"},
+			"400": failure("Invalid, expired or used token, or invalid password"),
+			"500": failure("Internal error"),
+		},
+	})
+	doc.Add(http.MethodGet, "/.well-known/jwks.json", &openapi.Operation{
+		OperationID: "jwks",
+		Summary:     "Public keys that access tokens are signed with",
+		Tags:        []string{"auth"},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The JSON Web Key Set", Content: jsonContent(jwksSchema)},
+		},
+	})
+	doc.Add(http.MethodGet, "/verify", &openapi.Operation{
+		OperationID: "verifyEmail",
+		Summary:     "Verify an email address with the token sent to it",
+		Tags:        []string{"users"},
+		Parameters: []openapi.Parameter{{
+			Name:     "token",
+			In:       "query",
+			Required: true,
+			Schema:   &openapi.Schema{Type: "string"},
+		}},
+		Responses: map[string]openapi.Response{
+			"200": userResponse("The user, with the email verified"),
+			"400": failure("Invalid, expired or already used token"),
+			"500": failure("Internal error"),
+		},
+	})
+
+	// Users
+	doc.Add(http.MethodGet, "/api/v1/users", authenticated(&openapi.Operation{
+		OperationID: "listUsers",
+		Summary:     "List users",
+		Tags:        []string{"users"},
+		Parameters: []openapi.Parameter{
+			queryParam("limit", "Page size, at most 100", &openapi.Schema{Type: "integer", Minimum: float(1), Maximum: float(service.MaxPageSize)}),
+			queryParam("offset", "Number of users to skip", &openapi.Schema{Type: "integer", Minimum: float(0)}),
+			queryParam("cursor", "Cursor of the next page, from a previous response", &openapi.Schema{Type: "string"}),
+			queryParam("sort", `Sort field, prefixed with "-" for descending order`, &openapi.Schema{
+				Type: "string",
+				Enum: []interface{}{"name", "-name", "email", "-email", "created_at", "-created_at"},
+			}),
+			queryParam("name_prefix", "Only users whose name starts with this prefix", &openapi.Schema{Type: "string"}),
+			queryParam("created_after", "Only users created after this time", &openapi.Schema{Type: "string", Format: "date-time"}),
+			queryParam("include_deleted", "Include soft-deleted users", &openapi.Schema{Type: "boolean"}),
+		},
+		Responses: map[string]openapi.Response{
+			"200": {
+				Description: "A page of users",
+				Headers: map[string]openapi.Header{
+					"Link": {Description: "Link to the next page", Schema: &openapi.Schema{Type: "string"}},
+				},
+				Content: jsonContent(listSchema),
+			},
+			"400": failure("Invalid query parameters or cursor"),
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodPost, "/api/v1/users", &openapi.Operation{
+		OperationID: "createUser",
+		Summary:     "Register a user",
+		Tags:        []string{"users"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(createInputSchema)},
+		Responses: map[string]openapi.Response{
+			"201": userResponse("The created user"),
+			"400": failure("Invalid user"),
+			"409": failure("Email already taken"),
+			"500": failure("Internal error"),
+		},
+	})
+	doc.Add(http.MethodGet, "/api/v1/users/:id", authenticated(&openapi.Operation{
+		OperationID: "getUser",
+		Summary:     "Get a user",
+		Tags:        []string{"users"},
+		Parameters: []openapi.Parameter{
+			idParam,
+			queryParam("include_deleted", "Also return a soft-deleted user", &openapi.Schema{Type: "boolean"}),
+			headerParam("If-None-Match", "Entity tags of cached representations"),
+		},
+		Responses: map[string]openapi.Response{
+			"200": userResponse("The user"),
+			"304": {Description: "The cached representation is current"},
+			"400": failure("Invalid query parameters"),
+			"404": failure("User not found"),
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodPut, "/api/v1/users/:id", authenticated(&openapi.Operation{
+		OperationID: "updateUser",
+		Summary:     "Replace a user",
+		Tags:        []string{"users"},
+		Parameters:  []openapi.Parameter{idParam, ifMatchParam},
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(inputSchema)},
+		Responses: map[string]openapi.Response{
+			"200": userResponse("The updated user"),
+			"400": failure("Invalid user"),
+			"404": failure("User not found"),
+			"409": failure("Email already taken"),
+			"412": failure("The user was modified since the given version"),
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodPatch, "/api/v1/users/:id", authenticated(&openapi.Operation{
+		OperationID: "patchUser",
+		Summary:     "Partially update a user",
+		Tags:        []string{"users"},
+		Parameters:  []openapi.Parameter{idParam, ifMatchParam},
+		RequestBody: &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+			string(service.MergePatch): {Schema: &openapi.Schema{
+				Type:        "object",
+				Description: "JSON Merge Patch (RFC 7396) of the name and email fields",
+				Properties: map[string]*openapi.Schema{
+					"name":  {Type: "string"},
+					"email": {Type: "string", Format: "email"},
+				},
+			}},
+			string(service.JSONPatch): {Schema: &openapi.Schema{
+				Type:        "array",
+				Description: "JSON Patch (RFC 6902) operations",
+				Items: &openapi.Schema{
+					Type: "object",
+					Properties: map[string]*openapi.Schema{
+						"op":    {Type: "string", Enum: []interface{}{"add", "remove", "replace", "move", "copy", "test"}},
+						"path":  {Type: "string"},
+						"from":  {Type: "string"},
+						"value": {},
+					},
+					Required: []string{"op", "path"},
+				},
+			}},
+		}},
+		Responses: map[string]openapi.Response{
+			"200": userResponse("The updated user"),
+			"400": failure("Invalid patch document or resulting user"),
+			"404": failure("User not found"),
+			"409": failure("Email already taken"),
+			"412": failure("The user was modified since the given version"),
+			"415": failure("Unsupported patch media type"),
+			"422": failure("The patch cannot be applied or changes a read-only field"),
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodDelete, "/api/v1/users/:id", authenticated(&openapi.Operation{
+		OperationID: "deleteUser",
+		Summary:     "Delete a user",
+		Tags:        []string{"users"},
+		Parameters: []openapi.Parameter{
+			idParam,
+			ifMatchParam,
+			queryParam("hard", "Delete permanently instead of soft-deleting", &openapi.Schema{Type: "boolean"}),
+		},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "The user was deleted"},
+			"400": failure("Invalid query parameters"),
+			"404": failure("User not found"),
+			"412": failure("The user was modified since the given version"),
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodPost, "/api/v1/users/:id/restore", authenticated(&openapi.Operation{
+		OperationID: "restoreUser",
+		Summary:     "Restore a soft-deleted user",
+		Tags:        []string{"users"},
+		Parameters:  []openapi.Parameter{idParam},
+		Responses: map[string]openapi.Response{
+			"200": userResponse("The restored user"),
+			"404": failure("Deleted user not found"),
+			"409": failure("Email taken by another user"),
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodPut, "/api/v1/users/:id/role", authenticated(&openapi.Operation{
+		OperationID: "setUserRole",
+		Summary:     "Change the role of a user",
+		Tags:        []string{"users"},
+		Parameters:  []openapi.Parameter{idParam, ifMatchParam},
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(roleInputSchema)},
+		Responses: map[string]openapi.Response{
+			"200": userResponse("The updated user"),
+			"400": failure("Invalid role"),
+			"404": failure("User not found"),
+			"412": failure("The user was modified since the given version"),
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodPost, "/api/v1/users/:id/verification", authenticated(&openapi.Operation{
+		OperationID: "resendVerification",
+		Summary:     "Send another email verification link",
+		Tags:        []string{"users"},
+		Parameters:  []openapi.Parameter{idParam},
+		Responses: map[string]openapi.Response{
+			"202": {Description: "The verification email was sent, unless the email is already verified"},
+			"404": failure("User not found"),
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodPost, "/api/v1/users/:id/api-keys", authenticated(&openapi.Operation{
+		OperationID: "createAPIKey",
+		Summary:     "Create a personal API key",
+		Tags:        []string{"api-keys"},
+		Parameters:  []openapi.Parameter{idParam},
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(apiKeyInputSchema)},
+		Responses: map[string]openapi.Response{
+			"201": {Description: "The created API key, including its value", Content: jsonContent(createdAPIKeySchema)},
+			"400": failure("Invalid API key"),
+			"404": failure("User not found"),
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodGet, "/api/v1/users/:id/api-keys", authenticated(&openapi.Operation{
+		OperationID: "listAPIKeys",
+		Summary:     "List the API keys of a user",
+		Tags:        []string{"api-keys"},
+		Parameters:  []openapi.Parameter{idParam},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The API keys, without their values", Content: jsonContent(apiKeyListSchema)},
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodDelete, "/api/v1/users/:id/api-keys/:key_id", authenticated(&openapi.Operation{
+		OperationID: "revokeAPIKey",
+		Summary:     "Revoke an API key",
+		Tags:        []string{"api-keys"},
+		Parameters: []openapi.Parameter{idParam, {
+			Name:     "key_id",
+			In:       "path",
+			Required: true,
+			Schema:   &openapi.Schema{Type: "string", Format: "uuid"},
+		}},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "The API key was revoked"},
+			"404": failure("API key not found"),
+			"500": failure("Internal error"),
+		},
+	}))
+
+	// Two-factor authentication
+	recoveryCodesResponse := openapi.Response{
+		Description: "The new recovery codes; any earlier ones no longer work",
+		Headers: map[string]openapi.Header{
+			"Cache-Control": {Description: "Always no-store", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Content: jsonContent(recoveryCodesSchema),
+	}
+	doc.Add(http.MethodGet, "/api/v1/users/:id/mfa", authenticated(&openapi.Operation{
+		OperationID: "getMFAStatus",
+		Summary:     "Describe the second factors of a user",
+		Tags:        []string{"mfa"},
+		Parameters:  []openapi.Parameter{idParam},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The second factors", Content: jsonContent(mfaStatusSchema)},
+			"404": failure("User not found"),
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodPost, "/api/v1/users/:id/mfa/totp", authenticated(&openapi.Operation{
+		OperationID: "enrollTOTP",
+		Summary:     "Generate a TOTP secret for an authenticator app; it is used once confirmed",
+		Tags:        []string{"mfa"},
+		Parameters:  []openapi.Parameter{idParam},
+		Responses: map[string]openapi.Response{
+			"201": {
+				Description: "The secret, replacing any unconfirmed one",
+				Headers: map[string]openapi.Header{
+					"Cache-Control": {Description: "Always no-store", Schema: &openapi.Schema{Type: "string"}},
+				},
+				Content: jsonContent(totpEnrollmentSchema),
+			},
+			"404": failure("User not found"),
+			"409": failure("Two-factor authentication already enabled"),
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodPost, "/api/v1/users/:id/mfa/totp/confirm", authenticated(&openapi.Operation{
+		OperationID: "confirmTOTP",
+		Summary:     "Enable two-factor authentication with a first code from the enrolled secret",
+		Tags:        []string{"mfa"},
+		Parameters:  []openapi.Parameter{idParam},
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(mfaCodeInputSchema)},
+		Responses: map[string]openapi.Response{
+			"200": recoveryCodesResponse,
+			"400": failure("Invalid request body, or wrong code"),
+			"404": failure("User not found"),
+			"409": failure("No secret enrolled, or two-factor authentication already enabled"),
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodPost, "/api/v1/users/:id/mfa/recovery-codes", authenticated(&openapi.Operation{
+		OperationID: "regenerateRecoveryCodes",
+		Summary:     "Replace the recovery codes of a user",
+		Tags:        []string{"mfa"},
+		Parameters:  []openapi.Parameter{idParam},
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(mfaCodeInputSchema)},
+		Responses: map[string]openapi.Response{
+			"200": recoveryCodesResponse,
+			"400": failure("Invalid request body, or wrong code"),
+			"404": failure("User not found"),
+			"409": failure("Two-factor authentication not enabled"),
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodPost, "/api/v1/users/:id/mfa/disable", authenticated(&openapi.Operation{
+		OperationID: "disableMFA",
+		Summary:     "Disable two-factor authentication, or cancel an unconfirmed enrollment, and delete the recovery codes",
+		Tags:        []string{"mfa"},
+		Parameters:  []openapi.Parameter{idParam},
+		RequestBody: &openapi.RequestBody{Content: jsonContent(mfaDisableInputSchema)},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Two-factor authentication was disabled"},
+			"400": failure("Invalid request body, or wrong code"),
+			"404": failure("User not found"),
+			"409": failure("Two-factor authentication not enabled"),
+			"500": failure("Internal error"),
+		},
+	}))
+
+	// OAuth 2.0 and OpenID Connect provider
+	noStore := map[string]openapi.Header{
+		"Cache-Control": {Description: "Always no-store", Schema: &openapi.Schema{Type: "string"}},
+	}
+	redirect := func(description string) openapi.Response {
+		return openapi.Response{Description: description, Headers: map[string]openapi.Header{
+			"Location": {Description: "The redirect URI, with code, state and iss, or error, error_description, state and iss", Schema: &openapi.Schema{Type: "string"}},
+		}}
+	}
+	oauthFailure := func(description string) openapi.Response {
+		return openapi.Response{Description: description, Headers: noStore, Content: jsonContent(oauthErrorSchema)}
+	}
+	sessionOnly := []openapi.SecurityRequirement{{"sessionCookie": {}}}
+	doc.Add(http.MethodGet, "/.well-known/openid-configuration", &openapi.Operation{
+		OperationID: "openIDConfiguration",
+		Summary:     "OpenID Connect discovery document",
+		Tags:        []string{"oauth"},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The provider metadata", Content: jsonContent(providerMetadataSchema)},
+		},
+	})
+	doc.Add(http.MethodGet, oauthAuthorizePath, &openapi.Operation{
+		OperationID: "oauthAuthorize",
+		Summary:     "Authorize a client with the authorization code flow and PKCE",
+		Tags:        []string{"oauth"},
+		Parameters: []openapi.Parameter{
+			{Name: "response_type", In: "query", Required: true, Schema: &openapi.Schema{Type: "string", Enum: []interface{}{"code"}}},
+			{Name: "client_id", In: "query", Required: true, Schema: &openapi.Schema{Type: "string", Format: "uuid"}},
+			{Name: "redirect_uri", In: "query", Required: true, Description: "One of the registered redirect URIs", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "code_challenge", In: "query", Required: true, Description: "Base64url SHA-256 hash of the code verifier", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "code_challenge_method", In: "query", Required: true, Schema: &openapi.Schema{Type: "string", Enum: []interface{}{"S256"}}},
+			queryParam("scope", "Space-separated scopes; openid is always included", &openapi.Schema{Type: "string"}),
+			queryParam("state", "Returned to the client unchanged", &openapi.Schema{Type: "string"}),
+			queryParam("nonce", "Included in the ID token", &openapi.Schema{Type: "string"}),
+		},
+		Security: sessionOnly,
+		Responses: map[string]openapi.Response{
+			"200": {
+				Description: "The user must approve the client first",
+				Headers:     noStore,
+				Content: map[string]openapi.MediaType{
+					"text/html":        {Schema: &openapi.Schema{Type: "string"}},
+					"application/json": {Schema: consentSchema},
+				},
+			},
+			"302": redirect("Back to the client, or to the login page for users without a session"),
+			"400": failure("Unknown client or redirect URI"),
+			"401": failure("Authentication required, when no login page is configured"),
+			"500": failure("Internal error"),
+		},
+	})
+	doc.Add(http.MethodPost, oauthAuthorizePath, &openapi.Operation{
+		OperationID: "oauthConsent",
+		Summary:     "Approve or deny a client",
+		Tags:        []string{"oauth"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+			"application/x-www-form-urlencoded": {Schema: consentInputSchema},
+			"application/json":                  {Schema: consentInputSchema},
+		}},
+		Security: sessionOnly,
+		Responses: map[string]openapi.Response{
+			"303": redirect("Back to the client"),
+			"400": failure("Invalid or expired consent form"),
+			"401": failure("Authentication required"),
+			"500": failure("Internal error"),
+		},
+	})
+	doc.Add(http.MethodPost, oauthTokenPath, &openapi.Operation{
+		OperationID: "oauthToken",
+		Summary:     "Exchange an authorization code for an access token and ID token",
+		Tags:        []string{"oauth"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+			"application/x-www-form-urlencoded": {Schema: oauthTokenInputSchema},
+		}},
+		Security: []openapi.SecurityRequirement{{"oauthClientBasic": {}}, {}},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The issued tokens", Headers: noStore, Content: jsonContent(oauthTokensSchema)},
+			"400": oauthFailure("Invalid request or code"),
+			"401": oauthFailure("Client authentication failed"),
+			"500": failure("Internal error"),
+		},
+	})
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		doc.Add(method, oauthUserInfoPath, &openapi.Operation{
+			OperationID: "oauthUserInfo" + method[:1] + strings.ToLower(method[1:]),
+			Summary:     "Claims about the user an access token was issued for",
+			Tags:        []string{"oauth"},
+			Security:    []openapi.SecurityRequirement{{"oauthAccessToken": {}}},
+			Responses: map[string]openapi.Response{
+				"200": {Description: "The claims the granted scopes release", Headers: noStore, Content: jsonContent(userInfoSchema)},
+				"401": failure("Missing, invalid or revoked access token"),
+				"403": failure("The access token lacks the openid scope"),
+				"500": failure("Internal error"),
+			},
+		})
+	}
+
+	clientIDParam := openapi.Parameter{
+		Name:     "client_id",
+		In:       "path",
+		Required: true,
+		Schema:   &openapi.Schema{Type: "string", Format: "uuid"},
+	}
+	doc.Add(http.MethodPost, "/api/v1/oauth/clients", authenticated(&openapi.Operation{
+		OperationID: "createOAuthClient",
+		Summary:     "Register an OAuth client",
+		Tags:        []string{"oauth-clients"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(oauthClientInputSchema)},
+		Responses: map[string]openapi.Response{
+			"201": {Description: "The registered client, including its secret", Headers: noStore, Content: jsonContent(createdOAuthClientSchema)},
+			"400": failure("Invalid client"),
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodGet, "/api/v1/oauth/clients", authenticated(&openapi.Operation{
+		OperationID: "listOAuthClients",
+		Summary:     "List the registered OAuth clients",
+		Tags:        []string{"oauth-clients"},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The registered clients", Content: jsonContent(oauthClientListSchema)},
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodGet, "/api/v1/oauth/clients/:client_id", authenticated(&openapi.Operation{
+		OperationID: "getOAuthClient",
+		Summary:     "Get an OAuth client",
+		Tags:        []string{"oauth-clients"},
+		Parameters:  []openapi.Parameter{clientIDParam},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The client", Content: jsonContent(oauthClientSchema)},
+			"404": failure("OAuth client not found"),
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodDelete, "/api/v1/oauth/clients/:client_id", authenticated(&openapi.Operation{
+		OperationID: "deleteOAuthClient",
+		Summary:     "Delete an OAuth client and the consents given to it",
+		Tags:        []string{"oauth-clients"},
+		Parameters:  []openapi.Parameter{clientIDParam},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "The client was deleted"},
+			"404": failure("OAuth client not found"),
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodGet, "/api/v1/users/:id/oauth-consents", authenticated(&openapi.Operation{
+		OperationID: "listOAuthConsents",
+		Summary:     "List the OAuth clients a user has consented to",
+		Tags:        []string{"oauth-clients"},
+		Parameters:  []openapi.Parameter{idParam},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The clients and the scopes allowed to them", Content: jsonContent(oauthConsentListSchema)},
+			"404": failure("User not found"),
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodDelete, "/api/v1/users/:id/oauth-consents/:client_id", authenticated(&openapi.Operation{
+		OperationID: "revokeOAuthConsent",
+		Summary:     "Revoke the consent of a user to an OAuth client",
+		Tags:        []string{"oauth-clients"},
+		Parameters:  []openapi.Parameter{idParam, clientIDParam},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "The consent was revoked; the client has to ask again"},
+			"404": failure("OAuth consent not found"),
+			"500": failure("Internal error"),
+		},
+	}))
+
+	// External identity providers
+	providerParam := openapi.Parameter{
+		Name:     "provider",
+		In:       "path",
+		Required: true,
+		Schema:   &openapi.Schema{Type: "string"},
+	}
+	returnTo := func(description string) openapi.Response {
+		return openapi.Response{Description: description, Headers: map[string]openapi.Header{
+			"Location": {Description: "The return_to of the login", Schema: &openapi.Schema{Type: "string"}},
+		}}
+	}
+	doc.Add(http.MethodGet, "/api/v1/auth/oidc/providers", &openapi.Operation{
+		OperationID: "listIdentityProviders",
+		Summary:     "List the external identity providers users can log in with",
+		Tags:        []string{"auth"},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The providers", Content: jsonContent(providerListSchema)},
+			"500": failure("Internal error"),
+		},
+	})
+	doc.Add(http.MethodGet, "/api/v1/auth/oidc/:provider/login", &openapi.Operation{
+		OperationID: "externalLogin",
+		Summary:     "Log in through an external identity provider",
+		Tags:        []string{"auth"},
+		Parameters: []openapi.Parameter{
+			providerParam,
+			queryParam("return_to", "Where to send the browser after the login: a path on this server or a URL at an allowed origin", &openapi.Schema{Type: "string"}),
+		},
+		Responses: map[string]openapi.Response{
+			"302": {Description: "To the provider, with the login state in a cookie", Headers: map[string]openapi.Header{
+				"Location":   {Description: "The authorization endpoint of the provider", Schema: &openapi.Schema{Type: "string"}},
+				"Set-Cookie": {Description: "The login state cookie", Schema: &openapi.Schema{Type: "string"}},
+			}},
+			"400": failure("return_to is not allowed"),
+			"404": failure("Identity provider not found"),
+			"500": failure("Internal error, or the provider cannot be reached"),
+		},
+	})
+	doc.Add(http.MethodGet, "/api/v1/auth/oidc/:provider/callback", &openapi.Operation{
+		OperationID: "externalLoginCallback",
+		Summary:     "Complete a login through an external identity provider, which redirects here",
+		Tags:        []string{"auth"},
+		Parameters: []openapi.Parameter{
+			providerParam,
+			queryParam("code", "The authorization code", &openapi.Schema{Type: "string"}),
+			queryParam("state", "The state of the login", &openapi.Schema{Type: "string"}),
+			queryParam("error", "The error reported by the provider instead of a code", &openapi.Schema{Type: "string"}),
+			queryParam("error_description", "Describes the error", &openapi.Schema{Type: "string"}),
+		},
+		Responses: map[string]openapi.Response{
+			"200": {
+				Description: "Logged in, or for a link, the linked identity",
+				Headers: map[string]openapi.Header{
+					"Set-Cookie": {Description: "The session cookie", Schema: &openapi.Schema{Type: "string"}},
+				},
+				Content: map[string]openapi.MediaType{
+					"application/json": {Schema: &openapi.Schema{OneOf: []*openapi.Schema{loginSchema, identitySchema}}},
+				},
+			},
+			"202": {
+				Description: "The user must complete the login with a second factor",
+				Headers:     noStore,
+				Content:     jsonContent(mfaChallengeSchema),
+			},
+			"302": returnTo("Logged in or linked, when the login has a return_to; logins needing a second factor carry mfa_token in the fragment"),
+			"400": failure("Missing, invalid or expired login state"),
+			"401": failure("The provider reported an error, or its ID token is invalid"),
+			"403": failure("The account is not linked to a user and cannot sign up"),
+			"404": failure("Identity provider not found"),
+			"409": failure("The email is taken by a user who must link the provider, or the account is linked to another user"),
+			"500": failure("Internal error"),
+		},
+	})
+	doc.Add(http.MethodPost, "/api/v1/users/:id/identities", authenticated(&openapi.Operation{
+		OperationID: "linkIdentity",
+		Summary:     "Start linking an external identity provider to a user; the same browser must then open authorization_url",
+		Tags:        []string{"users"},
+		Parameters:  []openapi.Parameter{idParam},
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(linkIdentityInputSchema)},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Where to send the browser", Headers: noStore, Content: jsonContent(linkIdentitySchema)},
+			"400": failure("Invalid request body, or return_to is not allowed"),
+			"404": failure("Identity provider not found"),
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodGet, "/api/v1/users/:id/identities", authenticated(&openapi.Operation{
+		OperationID: "listIdentities",
+		Summary:     "List the external identities linked to a user",
+		Tags:        []string{"users"},
+		Parameters:  []openapi.Parameter{idParam},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The linked identities", Content: jsonContent(identityListSchema)},
+			"404": failure("User not found"),
+			"500": failure("Internal error"),
+		},
+	}))
+	doc.Add(http.MethodDelete, "/api/v1/users/:id/identities/:provider", authenticated(&openapi.Operation{
+		OperationID: "unlinkIdentity",
+		Summary:     "Unlink an external identity provider from a user",
+		Tags:        []string{"users"},
+		Parameters:  []openapi.Parameter{idParam, providerParam},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "The identity was unlinked"},
+			"404": failure("User or linked identity not found"),
+			"409": failure("The user has no password or other identity to log in with"),
+			"500": failure("Internal error"),
+		},
+	}))
+
+	// Everything but health checks, metrics, documentation and discovery is
+	// rate limited
+	integerHeader := func(description string) openapi.Header {
+		return openapi.Header{Description: description, Schema: &openapi.Schema{Type: "integer"}}
+	}
+	rateLimited := failure("Too many requests from this client; retry once Retry-After seconds have passed")
+	rateLimited.Headers = map[string]openapi.Header{
+		"Retry-After":         integerHeader("Seconds until a request will be allowed"),
+		"RateLimit-Limit":     integerHeader("Requests the client may make at once"),
+		"RateLimit-Remaining": integerHeader("Requests the client may still make at once, also sent on allowed requests"),
+		"RateLimit-Reset":     integerHeader("Seconds until the full limit is available again, also sent on allowed requests"),
+	}
+	for path, item := range doc.Paths {
+		if !strings.HasPrefix(path, "/api/") && !strings.HasPrefix(path, "/oauth/") && path != "/verify" {
+			continue
+		}
+		for _, op := range item {
+			op.Responses["429"] = rateLimited
+		}
+	}
+
+	return doc
+}
+
+// Shared parameters and headers
+var (
+	idParam = openapi.Parameter{
+		Name:     "id",
+		In:       "path",
+		Required: true,
+		Schema:   &openapi.Schema{Type: "string", Format: "uuid"},
+	}
+	ifMatchParam = headerParam("If-Match", "Entity tags the current user must match for the write to proceed")
+	etagHeader   = openapi.Header{
+		Description: "Entity tag of the user version",
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+)
+
+// queryParam describes an optional query parameter
+func queryParam(name, description string, schema *openapi.Schema) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+// headerParam describes an optional request header
+func headerParam(name, description string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "header", Description: description, Schema: &openapi.Schema{Type: "string"}}
+}
+
+// jsonContent describes a JSON body
+func jsonContent(schema *openapi.Schema) map[string]openapi.MediaType {
+	return map[string]openapi.MediaType{"application/json": {Schema: schema}}
+}
+
+// float returns a pointer to v, for schema bounds
+func float(v float64) *float64 {
+	return &v
+}
+
+// healthReportSchema describes health.Report, whose check results render
+// their latency through a custom marshaller
+func healthReportSchema() *openapi.Schema {
+	status := &openapi.Schema{Type: "string", Enum: []interface{}{health.StatusUp, health.StatusDown}}
+	return &openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			"status": status,
+			"checks": {
+				Type: "array",
+				Items: &openapi.Schema{
+					Type: "object",
+					Properties: map[string]*openapi.Schema{
+						"name":       {Type: "string"},
+						"status":     status,
+						"latency_ms": {Type: "number"},
+						"error":      {Type: "string"},
+					},
+					Required: []string{"latency_ms", "name", "status"},
+				},
+			},
+		},
+		Required: []string{"checks", "status"},
+	}
+}
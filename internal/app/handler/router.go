@@ -1,60 +1,195 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
-	"github.com/ThePotatoVerse/internal/app/repository/memory"
 	"github.com/ThePotatoVerse/internal/app/service"
+	"github.com/ThePotatoVerse/internal/pkg/config"
+	"github.com/ThePotatoVerse/internal/pkg/middleware"
+	"github.com/ThePotatoVerse/pkg/health"
 	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/ThePotatoVerse/pkg/openapi"
+	"github.com/ThePotatoVerse/pkg/ratelimit"
+	"github.com/ThePotatoVerse/pkg/token"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-// NewRouter creates and configures a new router
-func NewRouter(log logger.Logger) http.Handler {
+// NewRouter creates and configures a new router. Routes missing from the
+// OpenAPI document returned by NewOpenAPI, or documented but not served, are
+// logged as errors; router_test.go fails on them.
+func NewRouter(log logger.Logger, userService service.UserService, authService service.AuthService, apiKeyService service.APIKeyService, verificationService service.VerificationService, mfaService service.MFAService, oauthService service.OAuthService, externalLoginService service.ExternalLoginService, keys *token.KeySet, rateLimitStore ratelimit.Store, serverCfg *config.ServerConfig, authCfg *config.AuthConfig, rateLimitCfg *config.RateLimitConfig, healthRegistry *health.Registry, metricsRegistry *prometheus.Registry) http.Handler {
 	// Set Gin mode
 	gin.SetMode(gin.ReleaseMode)
 
 	// Create router
 	router := gin.New()
+	router.HandleMethodNotAllowed = true
+	useJSONFieldNames()
+
+	// Only believe the client addresses reported by known proxies, as rate
+	// limits are keyed by them
+	if err := router.SetTrustedProxies(serverCfg.TrustedProxies); err != nil {
+		panic(fmt.Sprintf("invalid trusted proxies: %v", err))
+	}
+	limiter := newRateLimiter(log, rateLimitStore, rateLimitCfg, metricsRegistry)
+	limitAuth := limiter.limit("auth", rateLimitCfg.Auth)
+	limitAPI := limiter.limit("api", rateLimitCfg.API)
+	limitPreAuth := limiter.limit("pre_auth", rateLimitCfg.PreAuth)
+	limitPublic := limiter.limit("public", rateLimitCfg.Public)
 
 	// Add middleware
 	router.Use(
-		gin.Recovery(),
+		middleware.RequestID(),
+		middleware.Tracing(),
+		middleware.Metrics(metricsRegistry),
+		gin.CustomRecovery(recovered),
 		loggerMiddleware(log),
+		problemMiddleware(log),
 	)
 
-	// Health check
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status": "ok",
-		})
+	// Report unknown routes and methods as problems
+	router.NoRoute(noRoute)
+	router.NoMethod(noMethod)
+
+	// Health checks
+	healthHandler := NewHealthHandler(healthRegistry)
+	router.GET("/livez", healthHandler.Livez)
+	router.GET("/readyz", healthHandler.Readyz)
+	router.GET("/health", healthHandler.Readyz)
+
+	// Metrics
+	router.GET("/metrics", gin.WrapH(promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})))
+
+	// API documentation
+	spec := NewOpenAPI(authCfg)
+	docsHandler, err := NewDocsHandler(spec)
+	if err != nil {
+		panic(fmt.Sprintf("failed to encode OpenAPI document: %v", err))
+	}
+	router.GET("/openapi.json", docsHandler.Spec)
+	router.GET("/swagger/index.html", docsHandler.UI)
+	router.GET("/swagger", func(c *gin.Context) {
+		c.Redirect(http.StatusMovedPermanently, "/swagger/index.html")
 	})
 
-	// Initialize repositories
-	userRepo := memory.NewUserRepository()
+	// Public keys for verifying access tokens
+	authHandler := NewAuthHandler(log, authService, keys, authCfg)
+	router.GET(jwksPath, authHandler.JWKS)
+
+	// OAuth 2.0 and OpenID Connect provider
+	oauthHandler := NewOAuthHandler(log, oauthService, authService, keys, serverCfg.PublicURL, authCfg)
+	router.GET("/.well-known/openid-configuration", oauthHandler.Discovery)
+	router.GET(oauthAuthorizePath, limitPublic, oauthHandler.Authorize)
+	router.POST(oauthAuthorizePath, limitPublic, oauthHandler.Consent)
+	router.POST(oauthTokenPath, limitPublic, oauthHandler.Token)
+	router.GET(oauthUserInfoPath, limitPublic, oauthHandler.UserInfo)
+	router.POST(oauthUserInfoPath, limitPublic, oauthHandler.UserInfo)
 
-	// Initialize services
-	userService := service.NewUserService(log, userRepo)
+	// Target of the links in verification emails
+	verificationHandler := NewVerificationHandler(log, verificationService)
+	router.GET("/verify", limitPublic, verificationHandler.Verify)
 
 	// API routes
+	oauthClientHandler := NewOAuthClientHandler(log, oauthService)
+	externalLoginHandler := NewExternalLoginHandler(log, externalLoginService, authHandler, authCfg)
 	api := router.Group("/api/v1")
 	{
-		// User routes
-		userHandler := NewUserHandler(log, userService)
+		// Auth routes
+		auth := api.Group("/auth", limitAuth)
+		{
+			auth.POST("/login", authHandler.Login)
+			auth.POST("/login/mfa", authHandler.LoginWithMFA)
+			auth.POST("/logout", authHandler.Logout)
+			auth.POST("/tokens", authHandler.IssueTokens)
+			auth.POST("/tokens/mfa", authHandler.IssueTokensWithMFA)
+			auth.POST("/tokens/refresh", authHandler.RefreshTokens)
+			auth.POST("/tokens/revoke", authHandler.RevokeTokens)
+			auth.POST("/password-reset/request", authHandler.RequestPasswordReset)
+			auth.POST("/password-reset/confirm", authHandler.ConfirmPasswordReset)
+
+			// Logins through external identity providers
+			auth.GET("/oidc/providers", externalLoginHandler.Providers)
+			auth.GET("/oidc/:provider/login", externalLoginHandler.Login)
+			auth.GET("/oidc/:provider/callback", externalLoginHandler.Callback)
+		}
+
+		// User routes. Registration is open, everything else needs a session
+		// or an access token.
+		userHandler := NewUserHandler(log, userService, authService)
 		users := api.Group("/users")
 		{
-			users.GET("", userHandler.List)
-			users.POST("", userHandler.Create)
-			users.GET("/:id", userHandler.Get)
-			users.PUT("/:id", userHandler.Update)
-			users.DELETE("/:id", userHandler.Delete)
+			users.POST("", limitAuth, userHandler.Create)
+
+			// Every other route checks the role of the principal first
+			authenticated := users.Group("", limitPreAuth, requireAuth(authService, apiKeyService, authCfg), limitAPI)
+			authenticated.GET("", authorize(service.ActionListUsers), userHandler.List)
+			authenticated.GET("/:id", authorize(service.ActionReadUser), userHandler.Get)
+			authenticated.PUT("/:id", authorize(service.ActionUpdateUser), userHandler.Update)
+			authenticated.PATCH("/:id", authorize(service.ActionUpdateUser), userHandler.Patch)
+			authenticated.DELETE("/:id", authorize(service.ActionDeleteUser), userHandler.Delete)
+			authenticated.POST("/:id/restore", authorize(service.ActionRestoreUser), userHandler.Restore)
+			authenticated.PUT("/:id/role", authorize(service.ActionSetUserRole), userHandler.SetRole)
+			authenticated.POST("/:id/verification", authorize(service.ActionResendVerification), verificationHandler.Resend)
+
+			// Personal API keys
+			apiKeyHandler := NewAPIKeyHandler(log, apiKeyService)
+			authenticated.POST("/:id/api-keys", authorize(service.ActionCreateAPIKey), apiKeyHandler.Create)
+			authenticated.GET("/:id/api-keys", authorize(service.ActionListAPIKeys), apiKeyHandler.List)
+			authenticated.DELETE("/:id/api-keys/:key_id", authorize(service.ActionRevokeAPIKey), apiKeyHandler.Revoke)
+
+			// Two-factor authentication
+			mfaHandler := NewMFAHandler(log, mfaService)
+			authenticated.GET("/:id/mfa", authorize(service.ActionManageMFA), mfaHandler.Status)
+			authenticated.POST("/:id/mfa/totp", authorize(service.ActionManageMFA), mfaHandler.EnrollTOTP)
+			authenticated.POST("/:id/mfa/totp/confirm", authorize(service.ActionManageMFA), mfaHandler.ConfirmTOTP)
+			authenticated.POST("/:id/mfa/recovery-codes", authorize(service.ActionManageMFA), mfaHandler.RegenerateRecoveryCodes)
+			authenticated.POST("/:id/mfa/disable", authorize(service.ActionDisableMFA), mfaHandler.Disable)
+
+			// Clients the user has consented to
+			authenticated.GET("/:id/oauth-consents", authorize(service.ActionListOAuthConsents), oauthClientHandler.ListConsents)
+			authenticated.DELETE("/:id/oauth-consents/:client_id", authorize(service.ActionRevokeOAuthConsent), oauthClientHandler.RevokeConsent)
+
+			// Accounts at external identity providers
+			authenticated.POST("/:id/identities", authorize(service.ActionLinkIdentity), externalLoginHandler.Link)
+			authenticated.GET("/:id/identities", authorize(service.ActionListIdentities), externalLoginHandler.ListIdentities)
+			authenticated.DELETE("/:id/identities/:provider", authorize(service.ActionUnlinkIdentity), externalLoginHandler.Unlink)
+		}
+
+		// OAuth client registration
+		clients := api.Group("/oauth/clients", limitPreAuth, requireAuth(authService, apiKeyService, authCfg), limitAPI, authorize(service.ActionManageOAuthClients))
+		{
+			clients.POST("", oauthClientHandler.Create)
+			clients.GET("", oauthClientHandler.List)
+			clients.GET("/:client_id", oauthClientHandler.Get)
+			clients.DELETE("/:client_id", oauthClientHandler.Delete)
 		}
 	}
 
+	if err := spec.Verify(documentedRoutes(router)); err != nil {
+		log.Error("API documentation does not match the routes", "error", err)
+	}
+
 	return router
 }
 
+// documentedRoutes returns the routes of router that the OpenAPI document
+// must describe, which is all of them except the Swagger UI
+func documentedRoutes(router *gin.Engine) []openapi.Route {
+	var routes []openapi.Route
+	for _, route := range router.Routes() {
+		if route.Path == "/swagger" || strings.HasPrefix(route.Path, "/swagger/") {
+			continue
+		}
+		routes = append(routes, openapi.Route{Method: route.Method, Path: route.Path})
+	}
+	return routes
+}
+
 // loggerMiddleware creates a gin middleware for logging requests
 func loggerMiddleware(log logger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -67,13 +202,11 @@ func loggerMiddleware(log logger.Logger) gin.HandlerFunc {
 		// Log request
 		latency := time.Since(start)
 		status := c.Writer.Status()
-		method := c.Request.Method
 		path := c.Request.URL.Path
 		ip := c.ClientIP()
 
-		log.Info("Request",
+		log.FromContext(c.Request.Context()).Info("Request",
 			"status", status,
-			"method", method,
 			"path", path,
 			"ip", ip,
 			"latency", latency,
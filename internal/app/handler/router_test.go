@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/pkg/config"
+	"github.com/ThePotatoVerse/pkg/health"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/ThePotatoVerse/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TestOpenAPIMatchesRoutes fails when routes are added to or removed from
+// NewRouter without updating NewOpenAPI
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+
+	// Building the routes calls no services, so none are needed
+	router := NewRouter(logger.New(), nil, nil, nil, nil, nil, nil, nil, nil, ratelimit.NewMemoryStore(),
+		&cfg.Server, &cfg.Auth, &cfg.RateLimit, health.NewRegistry(time.Second), prometheus.NewRegistry())
+	engine, ok := router.(*gin.Engine)
+	if !ok {
+		t.Fatalf("NewRouter returned %T, want *gin.Engine", router)
+	}
+
+	if err := NewOpenAPI(&cfg.Auth).Verify(documentedRoutes(engine)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestOpenAPIDocumentUpToDate fails when api/openapi.json no longer matches
+// NewOpenAPI. Run make docs to regenerate it.
+func TestOpenAPIDocumentUpToDate(t *testing.T) {
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+
+	// Encode like cmd/openapi
+	var generated bytes.Buffer
+	encoder := json.NewEncoder(&generated)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(NewOpenAPI(&cfg.Auth)); err != nil {
+		t.Fatalf("encoding OpenAPI document: %v", err)
+	}
+
+	committed, err := os.ReadFile("../../../api/openapi.json")
+	if err != nil {
+		t.Fatalf("reading committed OpenAPI document: %v", err)
+	}
+
+	if !bytes.Equal(generated.Bytes(), committed) {
+		t.Fatal("api/openapi.json is out of date; run make docs")
+	}
+}
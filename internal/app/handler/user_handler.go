@@ -1,9 +1,15 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
 	"github.com/ThePotatoVerse/internal/app/service"
 	"github.com/ThePotatoVerse/pkg/logger"
 	"github.com/gin-gonic/gin"
@@ -13,40 +19,167 @@ import (
 type UserHandler struct {
 	log         logger.Logger
 	userService service.UserService
+	authService service.AuthService
 }
 
 // NewUserHandler creates a new user handler
-func NewUserHandler(log logger.Logger, userService service.UserService) *UserHandler {
+func NewUserHandler(log logger.Logger, userService service.UserService, authService service.AuthService) *UserHandler {
 	return &UserHandler{
 		log:         log,
 		userService: userService,
+		authService: authService,
 	}
 }
 
-// List returns a list of users
+// userInput is the request body of user create and update requests. Only
+// presence is checked here, the service validates the values.
+type userInput struct {
+	Name  string `json:"name" binding:"required"`
+	Email string `json:"email" binding:"required" format:"email"`
+}
+
+// createUserInput is the request body of user create requests, which also
+// set the password the user logs in with
+type createUserInput struct {
+	userInput
+	Password string `json:"password" binding:"required" format:"password"`
+}
+
+// roleInput is the request body of set role requests
+type roleInput struct {
+	Role model.Role `json:"role" binding:"required" enum:"admin,member,read-only"`
+}
+
+// listUsersResponse is a single page of users
+type listUsersResponse struct {
+	Data       []model.User `json:"data"`
+	Total      int          `json:"total"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+	Links      pageLinks    `json:"links"`
+}
+
+// pageLinks holds links to related pages
+type pageLinks struct {
+	Next string `json:"next,omitempty"`
+}
+
+// List returns a page of users
 func (h *UserHandler) List(c *gin.Context) {
-	h.log.Info("Handling list users request")
+	h.log.FromContext(c.Request.Context()).Info("Handling list users request")
+
+	query, err := parseUserQuery(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if query.IncludeDeleted {
+		if err := authorizeRequest(c, service.ActionReadDeletedUser); err != nil {
+			c.Error(err)
+			return
+		}
+	}
 
-	users, err := h.userService.List(c.Request.Context())
+	page, err := h.userService.List(c.Request.Context(), query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list users"})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, users)
+	response := listUsersResponse{
+		Data:       page.Users,
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
+	}
+	if page.NextCursor != "" {
+		response.Links.Next = nextPageURL(c.Request.URL, page.NextCursor)
+		c.Header("Link", fmt.Sprintf(`<%s>; rel="next"`, response.Links.Next))
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
-// Create creates a new user
-func (h *UserHandler) Create(c *gin.Context) {
-	h.log.Info("Handling create user request")
+// parseUserQuery reads pagination, sorting and filtering parameters from the request
+func parseUserQuery(c *gin.Context) (repository.UserQuery, error) {
+	var query repository.UserQuery
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return query, invalidParam("limit", "must be a positive integer")
+		}
+		query.Limit = limit
+	}
+
+	if v := c.Query("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return query, invalidParam("offset", "must be a non-negative integer")
+		}
+		query.Offset = offset
+	}
+
+	// Sort is a field name, prefixed with "-" for descending order
+	if v := c.Query("sort"); v != "" {
+		query.SortDesc = strings.HasPrefix(v, "-")
+		query.SortBy = repository.UserSortField(strings.TrimPrefix(v, "-"))
+		if !query.SortBy.Valid() {
+			return query, invalidParam("sort", fmt.Sprintf("cannot sort by %q", query.SortBy))
+		}
+	}
+
+	if v := c.Query("created_after"); v != "" {
+		createdAfter, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return query, invalidParam("created_after", "must be an RFC 3339 timestamp")
+		}
+		query.CreatedAfter = createdAfter
+	}
 
-	var input struct {
-		Name  string `json:"name" binding:"required"`
-		Email string `json:"email" binding:"required,email"`
+	includeDeleted, err := boolQuery(c, "include_deleted")
+	if err != nil {
+		return query, err
 	}
+	query.IncludeDeleted = includeDeleted
+
+	query.Cursor = c.Query("cursor")
+	query.NamePrefix = c.Query("name_prefix")
+
+	return query, nil
+}
+
+// boolQuery reads an optional boolean query parameter
+func boolQuery(c *gin.Context, name string) (bool, error) {
+	v := c.Query(name)
+	if v == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, invalidParam(name, "must be a boolean")
+	}
+	return b, nil
+}
+
+// nextPageURL returns the request URL with its cursor replaced and offset removed
+func nextPageURL(current *url.URL, cursor string) string {
+	values := current.Query()
+	values.Del("offset")
+	values.Set("cursor", cursor)
+
+	next := url.URL{Path: current.Path, RawQuery: values.Encode()}
+	return next.String()
+}
+
+// Create registers a new user with a password
+func (h *UserHandler) Create(c *gin.Context) {
+	h.log.FromContext(c.Request.Context()).Info("Handling create user request")
+
+	var input createUserInput
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -55,31 +188,46 @@ func (h *UserHandler) Create(c *gin.Context) {
 		Email: input.Email,
 	}
 
-	createdUser, err := h.userService.Create(c.Request.Context(), user)
+	createdUser, err := h.authService.Register(c.Request.Context(), user, input.Password)
 	if err != nil {
-		if err == service.ErrInvalidInput {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		c.Error(err)
 		return
 	}
 
+	c.Header("ETag", userETag(createdUser))
 	c.JSON(http.StatusCreated, createdUser)
 }
 
 // Get returns a user by ID
 func (h *UserHandler) Get(c *gin.Context) {
 	id := c.Param("id")
-	h.log.Info("Handling get user request", "id", id)
+	h.log.FromContext(c.Request.Context()).Info("Handling get user request", "id", id)
 
-	user, err := h.userService.Get(c.Request.Context(), id)
+	includeDeleted, err := boolQuery(c, "include_deleted")
 	if err != nil {
-		if err == service.ErrUserNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		c.Error(err)
+		return
+	}
+
+	var user model.User
+	if includeDeleted {
+		if err := authorizeRequest(c, service.ActionReadDeletedUser); err != nil {
+			c.Error(err)
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		user, err = h.userService.GetWithDeleted(c.Request.Context(), id)
+	} else {
+		user, err = h.userService.Get(c.Request.Context(), id)
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", userETag(user))
+
+	if versions, wildcard := parseETags(c.GetHeader("If-None-Match"), true); wildcard || containsVersion(versions, user.Version) {
+		c.Status(http.StatusNotModified)
 		return
 	}
 
@@ -89,54 +237,178 @@ func (h *UserHandler) Get(c *gin.Context) {
 // Update updates a user
 func (h *UserHandler) Update(c *gin.Context) {
 	id := c.Param("id")
-	h.log.Info("Handling update user request", "id", id)
+	h.log.FromContext(c.Request.Context()).Info("Handling update user request", "id", id)
 
-	var input struct {
-		Name  string `json:"name" binding:"required"`
-		Email string `json:"email" binding:"required,email"`
-	}
+	var input userInput
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(err)
+		return
+	}
+
+	version, ok := h.expectedVersion(c, id)
+	if !ok {
 		return
 	}
 
 	user := model.User{
-		ID:    id,
-		Name:  input.Name,
-		Email: input.Email,
+		ID:      id,
+		Name:    input.Name,
+		Email:   input.Email,
+		Version: version,
 	}
 
-	err := h.userService.Update(c.Request.Context(), user)
+	updatedUser, err := h.userService.Update(c.Request.Context(), user)
 	if err != nil {
-		switch err {
-		case service.ErrUserNotFound:
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		case service.ErrInvalidInput:
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
-		}
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", userETag(updatedUser))
+	c.JSON(http.StatusOK, updatedUser)
+}
+
+// Patch partially updates a user with a JSON Merge Patch or JSON Patch document
+func (h *UserHandler) Patch(c *gin.Context) {
+	id := c.Param("id")
+	h.log.FromContext(c.Request.Context()).Info("Handling patch user request", "id", id)
+
+	patchType := service.PatchType(c.ContentType())
+	if patchType != service.MergePatch && patchType != service.JSONPatch {
+		c.Error(service.ErrUnsupportedPatch)
+		return
+	}
+
+	patch, err := c.GetRawData()
+	if err != nil {
+		c.Error(malformedBody(err))
+		return
+	}
+
+	version, ok := h.expectedVersion(c, id)
+	if !ok {
+		return
+	}
+
+	updatedUser, err := h.userService.Patch(c.Request.Context(), id, version, patchType, patch)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	c.Status(http.StatusOK)
+	c.Header("ETag", userETag(updatedUser))
+	c.JSON(http.StatusOK, updatedUser)
 }
 
-// Delete deletes a user
+// Delete soft-deletes a user, or permanently deletes it when hard=true
 func (h *UserHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
-	h.log.Info("Handling delete user request", "id", id)
+	h.log.FromContext(c.Request.Context()).Info("Handling delete user request", "id", id)
 
-	err := h.userService.Delete(c.Request.Context(), id)
+	hard, err := boolQuery(c, "hard")
 	if err != nil {
-		if err == service.ErrUserNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		c.Error(err)
+		return
+	}
+
+	if hard {
+		if err := authorizeRequest(c, service.ActionHardDeleteUser); err != nil {
+			c.Error(err)
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+	}
+
+	version, ok := h.expectedVersion(c, id)
+	if !ok {
+		return
+	}
+
+	if hard {
+		err = h.userService.HardDelete(c.Request.Context(), id, version)
+	} else {
+		err = h.userService.Delete(c.Request.Context(), id, version)
+	}
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
+
+// Restore restores a soft-deleted user
+func (h *UserHandler) Restore(c *gin.Context) {
+	id := c.Param("id")
+	h.log.FromContext(c.Request.Context()).Info("Handling restore user request", "id", id)
+
+	user, err := h.userService.Restore(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", userETag(user))
+	c.JSON(http.StatusOK, user)
+}
+
+// SetRole changes the role of a user
+func (h *UserHandler) SetRole(c *gin.Context) {
+	id := c.Param("id")
+	h.log.FromContext(c.Request.Context()).Info("Handling set user role request", "id", id)
+
+	var input roleInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(err)
+		return
+	}
+
+	version, ok := h.expectedVersion(c, id)
+	if !ok {
+		return
+	}
+
+	user, err := h.userService.SetRole(c.Request.Context(), id, version, input.Role)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", userETag(user))
+	c.JSON(http.StatusOK, user)
+}
+
+// expectedVersion resolves the If-Match header to the user version a write
+// must match, or 0 when the write is unconditional. It reports the error and
+// returns false when the precondition cannot be met.
+func (h *UserHandler) expectedVersion(c *gin.Context, id string) (int, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return 0, true
+	}
+
+	versions, wildcard := parseETags(header, false)
+	switch {
+	case wildcard:
+		return 0, true
+	case len(versions) == 1:
+		return versions[0], true
+	case len(versions) == 0:
+		c.Error(service.ErrVersionMismatch)
+		return 0, false
+	}
+
+	// Several tags were listed, so match them against the current version
+	user, err := h.userService.Get(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return 0, false
+	}
+
+	if !containsVersion(versions, user.Version) {
+		c.Error(service.ErrVersionMismatch)
+		return 0, false
+	}
+
+	return user.Version, true
+}
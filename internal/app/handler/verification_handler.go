@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/ThePotatoVerse/internal/app/service"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// VerificationHandler handles HTTP requests for email verification
+type VerificationHandler struct {
+	log                 logger.Logger
+	verificationService service.VerificationService
+}
+
+// NewVerificationHandler creates a new verification handler
+func NewVerificationHandler(log logger.Logger, verificationService service.VerificationService) *VerificationHandler {
+	return &VerificationHandler{
+		log:                 log,
+		verificationService: verificationService,
+	}
+}
+
+// Verify confirms the email address a verification link was sent to. It is
+// reached from the link itself, so it needs no authentication.
+func (h *VerificationHandler) Verify(c *gin.Context) {
+	h.log.FromContext(c.Request.Context()).Info("Handling verify email request")
+
+	value := c.Query("token")
+	if value == "" {
+		c.Error(service.ErrInvalidVerification)
+		return
+	}
+
+	user, err := h.verificationService.Verify(c.Request.Context(), value)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", userETag(user))
+	c.JSON(http.StatusOK, user)
+}
+
+// Resend sends another verification email to a user
+func (h *VerificationHandler) Resend(c *gin.Context) {
+	id := c.Param("id")
+	h.log.FromContext(c.Request.Context()).Info("Handling resend verification request", "id", id)
+
+	if err := h.verificationService.SendVerification(c.Request.Context(), id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
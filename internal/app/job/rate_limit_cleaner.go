@@ -0,0 +1,72 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/ThePotatoVerse/pkg/ratelimit"
+)
+
+// RateLimitCleaner periodically deletes rate limit buckets that have
+// refilled, so that clients seen once do not keep a bucket forever
+type RateLimitCleaner struct {
+	log      logger.Logger
+	store    ratelimit.Store
+	interval time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewRateLimitCleaner creates a new rate limit cleaner
+func NewRateLimitCleaner(log logger.Logger, store ratelimit.Store, interval time.Duration) *RateLimitCleaner {
+	return &RateLimitCleaner{
+		log:      log,
+		store:    store,
+		interval: interval,
+	}
+}
+
+// Start runs a cleanup immediately and then once every interval until Stop is called
+func (c *RateLimitCleaner) Start(ctx context.Context) {
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.done = make(chan struct{})
+
+	c.log.Info("Starting rate limit cleaner", "interval", c.interval)
+
+	go func() {
+		defer close(c.done)
+
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			c.clean(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the cleaner and waits for a running cleanup to finish
+func (c *RateLimitCleaner) Stop() {
+	if c.cancel == nil {
+		return
+	}
+
+	c.log.Info("Stopping rate limit cleaner")
+	c.cancel()
+	<-c.done
+}
+
+// clean runs a single cleanup
+func (c *RateLimitCleaner) clean(ctx context.Context) {
+	if _, err := c.store.DeleteFull(ctx, time.Now()); err != nil && ctx.Err() == nil {
+		c.log.Error("Failed to delete refilled rate limit buckets", "error", err)
+	}
+}
@@ -0,0 +1,71 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/service"
+	"github.com/ThePotatoVerse/pkg/logger"
+)
+
+// SessionCleaner periodically deletes expired login sessions
+type SessionCleaner struct {
+	log         logger.Logger
+	authService service.AuthService
+	interval    time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewSessionCleaner creates a new session cleaner
+func NewSessionCleaner(log logger.Logger, authService service.AuthService, interval time.Duration) *SessionCleaner {
+	return &SessionCleaner{
+		log:         log,
+		authService: authService,
+		interval:    interval,
+	}
+}
+
+// Start runs a cleanup immediately and then once every interval until Stop is called
+func (c *SessionCleaner) Start(ctx context.Context) {
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.done = make(chan struct{})
+
+	c.log.Info("Starting session cleaner", "interval", c.interval)
+
+	go func() {
+		defer close(c.done)
+
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			c.clean(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the cleaner and waits for a running cleanup to finish
+func (c *SessionCleaner) Stop() {
+	if c.cancel == nil {
+		return
+	}
+
+	c.log.Info("Stopping session cleaner")
+	c.cancel()
+	<-c.done
+}
+
+// clean runs a single cleanup
+func (c *SessionCleaner) clean(ctx context.Context) {
+	if _, err := c.authService.PurgeExpiredSessions(ctx); err != nil && ctx.Err() == nil {
+		c.log.Error("Failed to purge expired sessions", "error", err)
+	}
+}
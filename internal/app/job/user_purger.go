@@ -0,0 +1,74 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/service"
+	"github.com/ThePotatoVerse/pkg/logger"
+)
+
+// UserPurger periodically hard-deletes users that were soft-deleted longer
+// ago than the retention window
+type UserPurger struct {
+	log         logger.Logger
+	userService service.UserService
+	interval    time.Duration
+	retention   time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewUserPurger creates a new user purger
+func NewUserPurger(log logger.Logger, userService service.UserService, interval, retention time.Duration) *UserPurger {
+	return &UserPurger{
+		log:         log,
+		userService: userService,
+		interval:    interval,
+		retention:   retention,
+	}
+}
+
+// Start runs a purge immediately and then once every interval until Stop is called
+func (p *UserPurger) Start(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.done = make(chan struct{})
+
+	p.log.Info("Starting user purger", "interval", p.interval, "retention", p.retention)
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			p.purge(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the purger and waits for a running purge to finish
+func (p *UserPurger) Stop() {
+	if p.cancel == nil {
+		return
+	}
+
+	p.log.Info("Stopping user purger")
+	p.cancel()
+	<-p.done
+}
+
+// purge runs a single purge
+func (p *UserPurger) purge(ctx context.Context) {
+	if _, err := p.userService.PurgeDeleted(ctx, p.retention); err != nil && ctx.Err() == nil {
+		p.log.Error("Failed to purge deleted users", "error", err)
+	}
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// APIKey is a long-lived credential for programmatic access. Only a hash of
+// the key is stored; its prefix is stored in clear to look the key up and to
+// let users tell their keys apart.
+type APIKey struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes" doc:"Actions the key is limited to; empty if it may do everything its user may"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// Expired reports whether the key has expired at now. Keys without an
+// expiry never expire.
+func (k APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// Credential holds the password hash a user logs in with
+type Credential struct {
+	UserID       string
+	PasswordHash string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// Session is a server-side login session. Its ID is the hash of the token
+// handed to the client, so stored sessions cannot be replayed.
+type Session struct {
+	ID        string
+	UserID    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Expired reports whether the session has expired at now
+func (s Session) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+// RefreshToken is a single-use token that is exchanged for a new access
+// token. Like sessions, its ID is the hash of the token handed to the client.
+// Tokens issued by refreshing another token share its FamilyID.
+type RefreshToken struct {
+	ID        string
+	FamilyID  string
+	UserID    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// Expired reports whether the refresh token has expired at now
+func (t RefreshToken) Expired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// PasswordReset is a single-use token that lets a user set a new password
+// without knowing the old one. Its ID is the hash of the token emailed to
+// the user.
+type PasswordReset struct {
+	ID        string
+	UserID    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Expired reports whether the password reset has expired at now
+func (r PasswordReset) Expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// ExternalIdentity links a user to their account at an external OpenID
+// Connect provider, so that they can log in through it. Subject is the
+// provider's ID for the account, and Email the address it had when linked.
+type ExternalIdentity struct {
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// TOTPFactor is the authenticator app secret of a user. It only takes part
+// in logins once confirmed with a first code. LastUsedStep is the time step
+// of the last accepted code, so that codes cannot be replayed.
+// FailedAttempts counts wrong codes since the last accepted one, and no code
+// is accepted before LockedUntil.
+type TOTPFactor struct {
+	UserID         string
+	Secret         string
+	CreatedAt      time.Time
+	ConfirmedAt    *time.Time
+	LastUsedStep   int64
+	FailedAttempts int
+	LockedUntil    *time.Time
+}
+
+// Confirmed reports whether the factor has been confirmed
+func (f TOTPFactor) Confirmed() bool {
+	return f.ConfirmedAt != nil
+}
+
+// Locked reports whether the factor is locked at now
+func (f TOTPFactor) Locked(now time.Time) bool {
+	return f.LockedUntil != nil && now.Before(*f.LockedUntil)
+}
+
+// RecoveryCode is a single-use code that replaces a TOTP code, for users who
+// lost their authenticator. Its ID is the hash of the code.
+type RecoveryCode struct {
+	ID        string
+	UserID    string
+	CreatedAt time.Time
+}
+
+// MFAChallenge is a login whose password was correct, waiting for its
+// second factor. Its ID is the hash of the token handed to the client.
+type MFAChallenge struct {
+	ID        string
+	UserID    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	Attempts  int
+}
+
+// Expired reports whether the challenge has expired at now
+func (c MFAChallenge) Expired(now time.Time) bool {
+	return !now.Before(c.ExpiresAt)
+}
@@ -0,0 +1,78 @@
+package model
+
+import "time"
+
+// OAuthClient is an application that signs users in through the built-in
+// OAuth 2.0 and OpenID Connect provider. Confidential clients authenticate
+// with a secret, of which only a hash is stored; public clients, such as
+// single-page and native apps, have none and rely on PKCE alone.
+type OAuthClient struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	SecretHash   string    `json:"-"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Confidential reports whether the client authenticates with a secret
+func (c OAuthClient) Confidential() bool {
+	return c.SecretHash != ""
+}
+
+// AllowsRedirect reports whether uri is one of the registered redirect URIs.
+// URIs are compared exactly.
+func (c OAuthClient) AllowsRedirect(uri string) bool {
+	for _, allowed := range c.RedirectURIs {
+		if uri == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// OAuthConsent records the scopes a user has allowed a client to access, so
+// that they are not asked again
+type OAuthConsent struct {
+	UserID    string
+	ClientID  string
+	Scopes    []string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Covers reports whether the consent includes every scope in scopes
+func (c OAuthConsent) Covers(scopes []string) bool {
+	for _, scope := range scopes {
+		found := false
+		for _, granted := range c.Scopes {
+			if scope == granted {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// OAuthAuthorizationCode is a single-use code a client exchanges for tokens.
+// Its ID is the hash of the code handed to the client. CodeChallenge is the
+// PKCE S256 challenge the exchange must answer.
+type OAuthAuthorizationCode struct {
+	ID            string
+	ClientID      string
+	UserID        string
+	RedirectURI   string
+	Scopes        []string
+	CodeChallenge string
+	Nonce         string
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+}
+
+// Expired reports whether the code has expired at now
+func (c OAuthAuthorizationCode) Expired(now time.Time) bool {
+	return !now.Before(c.ExpiresAt)
+}
@@ -0,0 +1,20 @@
+package model
+
+// Role determines what a user is allowed to do
+type Role string
+
+// Roles
+const (
+	RoleAdmin    Role = "admin"
+	RoleMember   Role = "member"
+	RoleReadOnly Role = "read-only"
+)
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	switch r {
+	case RoleAdmin, RoleMember, RoleReadOnly:
+		return true
+	}
+	return false
+}
@@ -4,9 +4,23 @@ import "time"
 
 // User represents a user in the system
 type User struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              string     `json:"id"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at" doc:"When the current email was verified; null until it is"`
+	Role            Role       `json:"role" enum:"admin,member,read-only"`
+	Version         int        `json:"version"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+}
+
+// EmailVerified reports whether the user has verified their current email
+func (u User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// Deleted reports whether the user has been soft-deleted
+func (u User) Deleted() bool {
+	return u.DeletedAt != nil
 }
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// EmailVerification records a verification token sent to an email address,
+// so that each token can be used only once. The token itself is signed and
+// carries its ID, user and email; only the ID is looked up.
+type EmailVerification struct {
+	ID        string
+	UserID    string
+	Email     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+)
+
+// APIKeyRepository defines the interface for API key storage. Prefixes are
+// unique; Create returns ErrConflict for a prefix already in use.
+// FindByPrefix returns expired keys, so that callers decide how to treat
+// them. Delete only deletes a key of the given user, and returns ErrNotFound
+// otherwise. API keys are removed together with their user.
+type APIKeyRepository interface {
+	Create(ctx context.Context, key model.APIKey) error
+	FindByPrefix(ctx context.Context, prefix string) (model.APIKey, error)
+	ListByUser(ctx context.Context, userID string) ([]model.APIKey, error)
+	Delete(ctx context.Context, userID, id string) error
+	TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+)
+
+// CredentialRepository defines the interface for password credential access.
+// Credentials are removed together with their user.
+type CredentialRepository interface {
+	Get(ctx context.Context, userID string) (model.Credential, error)
+	Upsert(ctx context.Context, credential model.Credential) error
+}
+
+// SessionRepository defines the interface for login session storage.
+// FindByID returns ErrNotFound for unknown sessions, but returns expired
+// sessions so that callers decide how to treat them. Sessions are removed
+// together with their user.
+type SessionRepository interface {
+	Create(ctx context.Context, session model.Session) error
+	FindByID(ctx context.Context, id string) (model.Session, error)
+	Delete(ctx context.Context, id string) error
+	DeleteByUser(ctx context.Context, userID string) (int64, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// RefreshTokenRepository defines the interface for refresh token storage.
+// FindByID returns used and expired tokens, so that callers can detect reuse.
+// MarkUsed returns ErrConflict when the token has already been used, so that
+// only one of several concurrent refreshes succeeds. Refresh tokens are
+// removed together with their user.
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token model.RefreshToken) error
+	FindByID(ctx context.Context, id string) (model.RefreshToken, error)
+	MarkUsed(ctx context.Context, id string, usedAt time.Time) error
+	DeleteFamily(ctx context.Context, familyID string) (int64, error)
+	DeleteByUser(ctx context.Context, userID string) (int64, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// PasswordResetRepository defines the interface for password reset storage.
+// Resets are deleted when used; Delete returns ErrNotFound for unknown
+// resets, so that only one of several concurrent uses succeeds. FindByID
+// returns expired resets. CountSince counts the resets created for a user
+// since the given time, whether used or not. Resets are removed together
+// with their user.
+type PasswordResetRepository interface {
+	Create(ctx context.Context, reset model.PasswordReset) error
+	FindByID(ctx context.Context, id string) (model.PasswordReset, error)
+	CountSince(ctx context.Context, userID string, since time.Time) (int, error)
+	Delete(ctx context.Context, id string) error
+	DeleteByUser(ctx context.Context, userID string) (int64, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// EmailVerificationRepository defines the interface for email verification
+// storage. Consume marks a verification as used, returning ErrNotFound for
+// unknown verifications and ErrConflict for used ones. Verifications are
+// removed together with their user.
+type EmailVerificationRepository interface {
+	Create(ctx context.Context, verification model.EmailVerification) error
+	Consume(ctx context.Context, id string, usedAt time.Time) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
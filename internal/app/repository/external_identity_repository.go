@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+)
+
+// ExternalIdentityRepository defines the interface for storing the links
+// between users and their accounts at external identity providers. An
+// account links to one user, and a user to at most one account per
+// provider; Create returns ErrConflict otherwise. Delete returns ErrNotFound
+// if there is no link. Links are removed together with their user.
+type ExternalIdentityRepository interface {
+	Create(ctx context.Context, identity model.ExternalIdentity) error
+	FindBySubject(ctx context.Context, provider, subject string) (model.ExternalIdentity, error)
+	ListByUser(ctx context.Context, userID string) ([]model.ExternalIdentity, error)
+	Delete(ctx context.Context, userID, provider string) error
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+)
+
+// apiKeyRepository implements repository.APIKeyRepository with an in-memory store
+type apiKeyRepository struct {
+	tx   *TxManager
+	keys *table[model.APIKey]
+}
+
+// NewAPIKeyRepository creates a new in-memory API key repository whose
+// writes take part in transactions run by tx
+func NewAPIKeyRepository(tx *TxManager) repository.APIKeyRepository {
+	return &apiKeyRepository{
+		tx:   tx,
+		keys: newTable[model.APIKey](tx),
+	}
+}
+
+// Create stores a new API key
+func (r *apiKeyRepository) Create(ctx context.Context, key model.APIKey) error {
+	defer r.tx.lock(ctx)()
+
+	for _, existing := range r.keys.rows {
+		if existing.ID == key.ID || existing.Prefix == key.Prefix {
+			return repository.ErrConflict
+		}
+	}
+
+	r.keys.put(ctx, key.ID, key)
+
+	return nil
+}
+
+// FindByPrefix returns the API key with the prefix, even if it has expired
+func (r *apiKeyRepository) FindByPrefix(ctx context.Context, prefix string) (model.APIKey, error) {
+	defer r.tx.rlock(ctx)()
+
+	for _, key := range r.keys.rows {
+		if key.Prefix == prefix {
+			return key, nil
+		}
+	}
+
+	return model.APIKey{}, repository.ErrNotFound
+}
+
+// ListByUser returns the API keys of a user, oldest first
+func (r *apiKeyRepository) ListByUser(ctx context.Context, userID string) ([]model.APIKey, error) {
+	defer r.tx.rlock(ctx)()
+
+	keys := make([]model.APIKey, 0)
+	for _, key := range r.keys.rows {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+
+	return keys, nil
+}
+
+// Delete deletes an API key of a user
+func (r *apiKeyRepository) Delete(ctx context.Context, userID, id string) error {
+	defer r.tx.lock(ctx)()
+
+	key, ok := r.keys.rows[id]
+	if !ok || key.UserID != userID {
+		return repository.ErrNotFound
+	}
+
+	r.keys.remove(ctx, id)
+
+	return nil
+}
+
+// TouchLastUsed records when an API key was last used
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	defer r.tx.lock(ctx)()
+
+	key, ok := r.keys.rows[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	key.LastUsedAt = &usedAt
+	r.keys.put(ctx, id, key)
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+)
+
+// credentialRepository implements repository.CredentialRepository with an in-memory store
+type credentialRepository struct {
+	tx          *TxManager
+	credentials *table[model.Credential]
+}
+
+// NewCredentialRepository creates a new in-memory credential repository
+// whose writes take part in transactions run by tx
+func NewCredentialRepository(tx *TxManager) repository.CredentialRepository {
+	return &credentialRepository{
+		tx:          tx,
+		credentials: newTable[model.Credential](tx),
+	}
+}
+
+// Get returns the credential of a user
+func (r *credentialRepository) Get(ctx context.Context, userID string) (model.Credential, error) {
+	defer r.tx.rlock(ctx)()
+
+	credential, ok := r.credentials.rows[userID]
+	if !ok {
+		return model.Credential{}, repository.ErrNotFound
+	}
+
+	return credential, nil
+}
+
+// Upsert creates or replaces the credential of a user
+func (r *credentialRepository) Upsert(ctx context.Context, credential model.Credential) error {
+	defer r.tx.lock(ctx)()
+
+	// Set timestamps, keeping the creation time of a replaced credential
+	now := time.Now()
+	credential.CreatedAt = now
+	credential.UpdatedAt = now
+	if existing, ok := r.credentials.rows[credential.UserID]; ok {
+		credential.CreatedAt = existing.CreatedAt
+	}
+
+	r.credentials.put(ctx, credential.UserID, credential)
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+)
+
+// emailVerificationRepository implements repository.EmailVerificationRepository with an in-memory store
+type emailVerificationRepository struct {
+	tx            *TxManager
+	verifications *table[model.EmailVerification]
+}
+
+// NewEmailVerificationRepository creates a new in-memory email verification
+// repository whose writes take part in transactions run by tx
+func NewEmailVerificationRepository(tx *TxManager) repository.EmailVerificationRepository {
+	return &emailVerificationRepository{
+		tx:            tx,
+		verifications: newTable[model.EmailVerification](tx),
+	}
+}
+
+// Create stores a new email verification
+func (r *emailVerificationRepository) Create(ctx context.Context, verification model.EmailVerification) error {
+	defer r.tx.lock(ctx)()
+
+	if _, ok := r.verifications.rows[verification.ID]; ok {
+		return repository.ErrConflict
+	}
+
+	r.verifications.put(ctx, verification.ID, verification)
+
+	return nil
+}
+
+// Consume marks an email verification as used, unless it already is
+func (r *emailVerificationRepository) Consume(ctx context.Context, id string, usedAt time.Time) error {
+	defer r.tx.lock(ctx)()
+
+	verification, ok := r.verifications.rows[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if verification.UsedAt != nil {
+		return repository.ErrConflict
+	}
+
+	verification.UsedAt = &usedAt
+	r.verifications.put(ctx, id, verification)
+
+	return nil
+}
+
+// DeleteExpired deletes email verifications that expired before the given time
+func (r *emailVerificationRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	defer r.tx.lock(ctx)()
+
+	var deleted int64
+	for id, verification := range r.verifications.rows {
+		if verification.ExpiresAt.Before(before) {
+			r.verifications.remove(ctx, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+)
+
+// externalIdentityRepository implements repository.ExternalIdentityRepository with an in-memory store
+type externalIdentityRepository struct {
+	tx         *TxManager
+	identities *table[model.ExternalIdentity]
+}
+
+// NewExternalIdentityRepository creates a new in-memory external identity
+// repository whose writes take part in transactions run by tx
+func NewExternalIdentityRepository(tx *TxManager) repository.ExternalIdentityRepository {
+	return &externalIdentityRepository{
+		tx:         tx,
+		identities: newTable[model.ExternalIdentity](tx),
+	}
+}
+
+// identityKey is the key an identity is stored under
+func identityKey(provider, subject string) string {
+	return provider + "/" + subject
+}
+
+// Create links a user to an external account
+func (r *externalIdentityRepository) Create(ctx context.Context, identity model.ExternalIdentity) error {
+	defer r.tx.lock(ctx)()
+
+	key := identityKey(identity.Provider, identity.Subject)
+	if _, ok := r.identities.rows[key]; ok {
+		return repository.ErrConflict
+	}
+	for _, existing := range r.identities.rows {
+		if existing.UserID == identity.UserID && existing.Provider == identity.Provider {
+			return repository.ErrConflict
+		}
+	}
+
+	r.identities.put(ctx, key, identity)
+
+	return nil
+}
+
+// FindBySubject returns the link to an external account
+func (r *externalIdentityRepository) FindBySubject(ctx context.Context, provider, subject string) (model.ExternalIdentity, error) {
+	defer r.tx.rlock(ctx)()
+
+	identity, ok := r.identities.rows[identityKey(provider, subject)]
+	if !ok {
+		return model.ExternalIdentity{}, repository.ErrNotFound
+	}
+
+	return identity, nil
+}
+
+// ListByUser returns the external accounts linked to a user, oldest first
+func (r *externalIdentityRepository) ListByUser(ctx context.Context, userID string) ([]model.ExternalIdentity, error) {
+	defer r.tx.rlock(ctx)()
+
+	identities := make([]model.ExternalIdentity, 0)
+	for _, identity := range r.identities.rows {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+
+	sort.Slice(identities, func(i, j int) bool {
+		if identities[i].CreatedAt.Equal(identities[j].CreatedAt) {
+			return identities[i].Provider < identities[j].Provider
+		}
+		return identities[i].CreatedAt.Before(identities[j].CreatedAt)
+	})
+
+	return identities, nil
+}
+
+// Delete unlinks a user from their account at a provider
+func (r *externalIdentityRepository) Delete(ctx context.Context, userID, provider string) error {
+	defer r.tx.lock(ctx)()
+
+	for key, identity := range r.identities.rows {
+		if identity.UserID == userID && identity.Provider == provider {
+			r.identities.remove(ctx, key)
+			return nil
+		}
+	}
+
+	return repository.ErrNotFound
+}
@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+)
+
+// mfaChallengeRepository implements repository.MFAChallengeRepository with an in-memory store
+type mfaChallengeRepository struct {
+	tx         *TxManager
+	challenges *table[model.MFAChallenge]
+}
+
+// NewMFAChallengeRepository creates a new in-memory MFA challenge repository
+// whose writes take part in transactions run by tx
+func NewMFAChallengeRepository(tx *TxManager) repository.MFAChallengeRepository {
+	return &mfaChallengeRepository{
+		tx:         tx,
+		challenges: newTable[model.MFAChallenge](tx),
+	}
+}
+
+// Create stores a new MFA challenge
+func (r *mfaChallengeRepository) Create(ctx context.Context, challenge model.MFAChallenge) error {
+	defer r.tx.lock(ctx)()
+
+	if _, ok := r.challenges.rows[challenge.ID]; ok {
+		return repository.ErrConflict
+	}
+
+	r.challenges.put(ctx, challenge.ID, challenge)
+
+	return nil
+}
+
+// FindByID returns an MFA challenge by ID, even if it has expired
+func (r *mfaChallengeRepository) FindByID(ctx context.Context, id string) (model.MFAChallenge, error) {
+	defer r.tx.rlock(ctx)()
+
+	challenge, ok := r.challenges.rows[id]
+	if !ok {
+		return model.MFAChallenge{}, repository.ErrNotFound
+	}
+
+	return challenge, nil
+}
+
+// RecordFailure counts a wrong code for an MFA challenge
+func (r *mfaChallengeRepository) RecordFailure(ctx context.Context, id string) (int, error) {
+	defer r.tx.lock(ctx)()
+
+	challenge, ok := r.challenges.rows[id]
+	if !ok {
+		return 0, repository.ErrNotFound
+	}
+
+	challenge.Attempts++
+	r.challenges.put(ctx, id, challenge)
+
+	return challenge.Attempts, nil
+}
+
+// Delete deletes an MFA challenge
+func (r *mfaChallengeRepository) Delete(ctx context.Context, id string) error {
+	defer r.tx.lock(ctx)()
+
+	if _, ok := r.challenges.rows[id]; !ok {
+		return repository.ErrNotFound
+	}
+
+	r.challenges.remove(ctx, id)
+
+	return nil
+}
+
+// DeleteExpired deletes MFA challenges that expired before the given time
+func (r *mfaChallengeRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	defer r.tx.lock(ctx)()
+
+	var deleted int64
+	for id, challenge := range r.challenges.rows {
+		if challenge.ExpiresAt.Before(before) {
+			r.challenges.remove(ctx, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,170 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+)
+
+// mfaRepository implements repository.MFARepository with an in-memory store
+type mfaRepository struct {
+	tx            *TxManager
+	factors       *table[model.TOTPFactor]
+	recoveryCodes *table[model.RecoveryCode]
+}
+
+// NewMFARepository creates a new in-memory MFA repository whose writes take
+// part in transactions run by tx
+func NewMFARepository(tx *TxManager) repository.MFARepository {
+	return &mfaRepository{
+		tx:            tx,
+		factors:       newTable[model.TOTPFactor](tx),
+		recoveryCodes: newTable[model.RecoveryCode](tx),
+	}
+}
+
+// GetTOTP returns the TOTP factor of a user, confirmed or not
+func (r *mfaRepository) GetTOTP(ctx context.Context, userID string) (model.TOTPFactor, error) {
+	defer r.tx.rlock(ctx)()
+
+	factor, ok := r.factors.rows[userID]
+	if !ok {
+		return model.TOTPFactor{}, repository.ErrNotFound
+	}
+
+	return factor, nil
+}
+
+// SaveTOTP creates or replaces the TOTP factor of a user
+func (r *mfaRepository) SaveTOTP(ctx context.Context, factor model.TOTPFactor) error {
+	defer r.tx.lock(ctx)()
+
+	r.factors.put(ctx, factor.UserID, factor)
+
+	return nil
+}
+
+// UseTOTPStep records the time step of an accepted code, unless a code of
+// the same or a later step was accepted before
+func (r *mfaRepository) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	defer r.tx.lock(ctx)()
+
+	factor, ok := r.factors.rows[userID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if step <= factor.LastUsedStep {
+		return repository.ErrConflict
+	}
+
+	factor.LastUsedStep = step
+	r.factors.put(ctx, userID, factor)
+
+	return nil
+}
+
+// RecordTOTPFailure counts a wrong code against the TOTP factor of a user
+func (r *mfaRepository) RecordTOTPFailure(ctx context.Context, userID string) (int, error) {
+	defer r.tx.lock(ctx)()
+
+	factor, ok := r.factors.rows[userID]
+	if !ok {
+		return 0, repository.ErrNotFound
+	}
+
+	factor.FailedAttempts++
+	r.factors.put(ctx, userID, factor)
+
+	return factor.FailedAttempts, nil
+}
+
+// LockTOTP stops the TOTP factor of a user from accepting codes until then
+func (r *mfaRepository) LockTOTP(ctx context.Context, userID string, until time.Time) error {
+	defer r.tx.lock(ctx)()
+
+	factor, ok := r.factors.rows[userID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	factor.LockedUntil = &until
+	r.factors.put(ctx, userID, factor)
+
+	return nil
+}
+
+// ClearTOTPFailures forgets the wrong codes and lock of the TOTP factor of a
+// user
+func (r *mfaRepository) ClearTOTPFailures(ctx context.Context, userID string) error {
+	defer r.tx.lock(ctx)()
+
+	factor, ok := r.factors.rows[userID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	factor.FailedAttempts = 0
+	factor.LockedUntil = nil
+	r.factors.put(ctx, userID, factor)
+
+	return nil
+}
+
+// DeleteTOTP deletes the TOTP factor of a user, if any
+func (r *mfaRepository) DeleteTOTP(ctx context.Context, userID string) error {
+	defer r.tx.lock(ctx)()
+
+	r.factors.remove(ctx, userID)
+
+	return nil
+}
+
+// ReplaceRecoveryCodes replaces every recovery code of a user
+func (r *mfaRepository) ReplaceRecoveryCodes(ctx context.Context, userID string, codes []model.RecoveryCode) error {
+	defer r.tx.lock(ctx)()
+
+	for id, code := range r.recoveryCodes.rows {
+		if code.UserID == userID {
+			r.recoveryCodes.remove(ctx, id)
+		}
+	}
+
+	for _, code := range codes {
+		if _, ok := r.recoveryCodes.rows[code.ID]; ok {
+			return repository.ErrConflict
+		}
+		r.recoveryCodes.put(ctx, code.ID, code)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode deletes a recovery code of a user
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID, id string) error {
+	defer r.tx.lock(ctx)()
+
+	code, ok := r.recoveryCodes.rows[id]
+	if !ok || code.UserID != userID {
+		return repository.ErrNotFound
+	}
+
+	r.recoveryCodes.remove(ctx, id)
+
+	return nil
+}
+
+// CountRecoveryCodes counts the unused recovery codes of a user
+func (r *mfaRepository) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	defer r.tx.rlock(ctx)()
+
+	count := 0
+	for _, code := range r.recoveryCodes.rows {
+		if code.UserID == userID {
+			count++
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+)
+
+// oauthClientRepository implements repository.OAuthClientRepository with an in-memory store
+type oauthClientRepository struct {
+	tx      *TxManager
+	clients *table[model.OAuthClient]
+}
+
+// NewOAuthClientRepository creates a new in-memory OAuth client repository
+// whose writes take part in transactions run by tx
+func NewOAuthClientRepository(tx *TxManager) repository.OAuthClientRepository {
+	return &oauthClientRepository{
+		tx:      tx,
+		clients: newTable[model.OAuthClient](tx),
+	}
+}
+
+// Create stores a new OAuth client
+func (r *oauthClientRepository) Create(ctx context.Context, client model.OAuthClient) error {
+	defer r.tx.lock(ctx)()
+
+	if _, ok := r.clients.rows[client.ID]; ok {
+		return repository.ErrConflict
+	}
+
+	r.clients.put(ctx, client.ID, client)
+
+	return nil
+}
+
+// FindByID returns an OAuth client by ID
+func (r *oauthClientRepository) FindByID(ctx context.Context, id string) (model.OAuthClient, error) {
+	defer r.tx.rlock(ctx)()
+
+	client, ok := r.clients.rows[id]
+	if !ok {
+		return model.OAuthClient{}, repository.ErrNotFound
+	}
+
+	return client, nil
+}
+
+// List returns every OAuth client, oldest first
+func (r *oauthClientRepository) List(ctx context.Context) ([]model.OAuthClient, error) {
+	defer r.tx.rlock(ctx)()
+
+	clients := make([]model.OAuthClient, 0, len(r.clients.rows))
+	for _, client := range r.clients.rows {
+		clients = append(clients, client)
+	}
+
+	sort.Slice(clients, func(i, j int) bool {
+		if clients[i].CreatedAt.Equal(clients[j].CreatedAt) {
+			return clients[i].ID < clients[j].ID
+		}
+		return clients[i].CreatedAt.Before(clients[j].CreatedAt)
+	})
+
+	return clients, nil
+}
+
+// Delete deletes an OAuth client
+func (r *oauthClientRepository) Delete(ctx context.Context, id string) error {
+	defer r.tx.lock(ctx)()
+
+	if _, ok := r.clients.rows[id]; !ok {
+		return repository.ErrNotFound
+	}
+
+	r.clients.remove(ctx, id)
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+)
+
+// oauthCodeRepository implements repository.OAuthCodeRepository with an in-memory store
+type oauthCodeRepository struct {
+	tx    *TxManager
+	codes *table[model.OAuthAuthorizationCode]
+}
+
+// NewOAuthCodeRepository creates a new in-memory authorization code
+// repository whose writes take part in transactions run by tx
+func NewOAuthCodeRepository(tx *TxManager) repository.OAuthCodeRepository {
+	return &oauthCodeRepository{
+		tx:    tx,
+		codes: newTable[model.OAuthAuthorizationCode](tx),
+	}
+}
+
+// Create stores a new authorization code
+func (r *oauthCodeRepository) Create(ctx context.Context, code model.OAuthAuthorizationCode) error {
+	defer r.tx.lock(ctx)()
+
+	if _, ok := r.codes.rows[code.ID]; ok {
+		return repository.ErrConflict
+	}
+
+	r.codes.put(ctx, code.ID, code)
+
+	return nil
+}
+
+// Consume deletes an authorization code and returns it
+func (r *oauthCodeRepository) Consume(ctx context.Context, id string) (model.OAuthAuthorizationCode, error) {
+	defer r.tx.lock(ctx)()
+
+	code, ok := r.codes.rows[id]
+	if !ok {
+		return model.OAuthAuthorizationCode{}, repository.ErrNotFound
+	}
+
+	r.codes.remove(ctx, id)
+
+	return code, nil
+}
+
+// DeleteExpired deletes authorization codes that expired before the given time
+func (r *oauthCodeRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	defer r.tx.lock(ctx)()
+
+	var deleted int64
+	for id, code := range r.codes.rows {
+		if code.ExpiresAt.Before(before) {
+			r.codes.remove(ctx, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+)
+
+// oauthConsentRepository implements repository.OAuthConsentRepository with an in-memory store
+type oauthConsentRepository struct {
+	tx       *TxManager
+	consents *table[model.OAuthConsent]
+}
+
+// NewOAuthConsentRepository creates a new in-memory OAuth consent repository
+// whose writes take part in transactions run by tx
+func NewOAuthConsentRepository(tx *TxManager) repository.OAuthConsentRepository {
+	return &oauthConsentRepository{
+		tx:       tx,
+		consents: newTable[model.OAuthConsent](tx),
+	}
+}
+
+// consentKey is the key a consent is stored under
+func consentKey(userID, clientID string) string {
+	return userID + "/" + clientID
+}
+
+// Get returns the consent of a user to a client
+func (r *oauthConsentRepository) Get(ctx context.Context, userID, clientID string) (model.OAuthConsent, error) {
+	defer r.tx.rlock(ctx)()
+
+	consent, ok := r.consents.rows[consentKey(userID, clientID)]
+	if !ok {
+		return model.OAuthConsent{}, repository.ErrNotFound
+	}
+
+	return consent, nil
+}
+
+// Save creates or replaces the consent of a user to a client
+func (r *oauthConsentRepository) Save(ctx context.Context, consent model.OAuthConsent) error {
+	defer r.tx.lock(ctx)()
+
+	r.consents.put(ctx, consentKey(consent.UserID, consent.ClientID), consent)
+
+	return nil
+}
+
+// ListByUser returns the consents of a user, oldest first
+func (r *oauthConsentRepository) ListByUser(ctx context.Context, userID string) ([]model.OAuthConsent, error) {
+	defer r.tx.rlock(ctx)()
+
+	consents := make([]model.OAuthConsent, 0)
+	for _, consent := range r.consents.rows {
+		if consent.UserID == userID {
+			consents = append(consents, consent)
+		}
+	}
+
+	sort.Slice(consents, func(i, j int) bool {
+		if consents[i].CreatedAt.Equal(consents[j].CreatedAt) {
+			return consents[i].ClientID < consents[j].ClientID
+		}
+		return consents[i].CreatedAt.Before(consents[j].CreatedAt)
+	})
+
+	return consents, nil
+}
+
+// Delete deletes the consent of a user to a client
+func (r *oauthConsentRepository) Delete(ctx context.Context, userID, clientID string) error {
+	defer r.tx.lock(ctx)()
+
+	key := consentKey(userID, clientID)
+	if _, ok := r.consents.rows[key]; !ok {
+		return repository.ErrNotFound
+	}
+
+	r.consents.remove(ctx, key)
+
+	return nil
+}
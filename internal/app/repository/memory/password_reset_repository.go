@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+)
+
+// passwordResetRepository implements repository.PasswordResetRepository with an in-memory store
+type passwordResetRepository struct {
+	tx     *TxManager
+	resets *table[model.PasswordReset]
+}
+
+// NewPasswordResetRepository creates a new in-memory password reset
+// repository whose writes take part in transactions run by tx
+func NewPasswordResetRepository(tx *TxManager) repository.PasswordResetRepository {
+	return &passwordResetRepository{
+		tx:     tx,
+		resets: newTable[model.PasswordReset](tx),
+	}
+}
+
+// Create stores a new password reset
+func (r *passwordResetRepository) Create(ctx context.Context, reset model.PasswordReset) error {
+	defer r.tx.lock(ctx)()
+
+	if _, ok := r.resets.rows[reset.ID]; ok {
+		return repository.ErrConflict
+	}
+
+	r.resets.put(ctx, reset.ID, reset)
+
+	return nil
+}
+
+// FindByID returns a password reset by ID, even if it has expired
+func (r *passwordResetRepository) FindByID(ctx context.Context, id string) (model.PasswordReset, error) {
+	defer r.tx.rlock(ctx)()
+
+	reset, ok := r.resets.rows[id]
+	if !ok {
+		return model.PasswordReset{}, repository.ErrNotFound
+	}
+
+	return reset, nil
+}
+
+// CountSince counts the password resets created for a user since the given time
+func (r *passwordResetRepository) CountSince(ctx context.Context, userID string, since time.Time) (int, error) {
+	defer r.tx.rlock(ctx)()
+
+	count := 0
+	for _, reset := range r.resets.rows {
+		if reset.UserID == userID && !reset.CreatedAt.Before(since) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// Delete deletes a password reset
+func (r *passwordResetRepository) Delete(ctx context.Context, id string) error {
+	defer r.tx.lock(ctx)()
+
+	if _, ok := r.resets.rows[id]; !ok {
+		return repository.ErrNotFound
+	}
+
+	r.resets.remove(ctx, id)
+
+	return nil
+}
+
+// DeleteByUser deletes every password reset of a user
+func (r *passwordResetRepository) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	return r.deleteWhere(ctx, func(reset model.PasswordReset) bool {
+		return reset.UserID == userID
+	})
+}
+
+// DeleteExpired deletes password resets that expired before the given time
+func (r *passwordResetRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	return r.deleteWhere(ctx, func(reset model.PasswordReset) bool {
+		return reset.ExpiresAt.Before(before)
+	})
+}
+
+// deleteWhere deletes every password reset matching match
+func (r *passwordResetRepository) deleteWhere(ctx context.Context, match func(model.PasswordReset) bool) (int64, error) {
+	defer r.tx.lock(ctx)()
+
+	var deleted int64
+	for id, reset := range r.resets.rows {
+		if match(reset) {
+			r.resets.remove(ctx, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+)
+
+// refreshTokenRepository implements repository.RefreshTokenRepository with an in-memory store
+type refreshTokenRepository struct {
+	tx     *TxManager
+	tokens *table[model.RefreshToken]
+}
+
+// NewRefreshTokenRepository creates a new in-memory refresh token repository
+// whose writes take part in transactions run by tx
+func NewRefreshTokenRepository(tx *TxManager) repository.RefreshTokenRepository {
+	return &refreshTokenRepository{
+		tx:     tx,
+		tokens: newTable[model.RefreshToken](tx),
+	}
+}
+
+// Create stores a new refresh token
+func (r *refreshTokenRepository) Create(ctx context.Context, token model.RefreshToken) error {
+	defer r.tx.lock(ctx)()
+
+	if _, ok := r.tokens.rows[token.ID]; ok {
+		return repository.ErrConflict
+	}
+
+	r.tokens.put(ctx, token.ID, token)
+
+	return nil
+}
+
+// FindByID returns a refresh token by ID, even if it has been used or has expired
+func (r *refreshTokenRepository) FindByID(ctx context.Context, id string) (model.RefreshToken, error) {
+	defer r.tx.rlock(ctx)()
+
+	token, ok := r.tokens.rows[id]
+	if !ok {
+		return model.RefreshToken{}, repository.ErrNotFound
+	}
+
+	return token, nil
+}
+
+// MarkUsed marks a refresh token as used, unless it already is
+func (r *refreshTokenRepository) MarkUsed(ctx context.Context, id string, usedAt time.Time) error {
+	defer r.tx.lock(ctx)()
+
+	token, ok := r.tokens.rows[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if token.UsedAt != nil {
+		return repository.ErrConflict
+	}
+
+	token.UsedAt = &usedAt
+	r.tokens.put(ctx, id, token)
+
+	return nil
+}
+
+// DeleteFamily deletes every refresh token of a family
+func (r *refreshTokenRepository) DeleteFamily(ctx context.Context, familyID string) (int64, error) {
+	return r.deleteWhere(ctx, func(token model.RefreshToken) bool {
+		return token.FamilyID == familyID
+	})
+}
+
+// DeleteByUser deletes every refresh token of a user
+func (r *refreshTokenRepository) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	return r.deleteWhere(ctx, func(token model.RefreshToken) bool {
+		return token.UserID == userID
+	})
+}
+
+// DeleteExpired deletes refresh tokens that expired before the given time
+func (r *refreshTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	return r.deleteWhere(ctx, func(token model.RefreshToken) bool {
+		return token.ExpiresAt.Before(before)
+	})
+}
+
+// deleteWhere deletes every refresh token matching match
+func (r *refreshTokenRepository) deleteWhere(ctx context.Context, match func(model.RefreshToken) bool) (int64, error) {
+	defer r.tx.lock(ctx)()
+
+	var deleted int64
+	for id, token := range r.tokens.rows {
+		if match(token) {
+			r.tokens.remove(ctx, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
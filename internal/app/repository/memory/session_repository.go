@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+)
+
+// sessionRepository implements repository.SessionRepository with an in-memory store
+type sessionRepository struct {
+	tx       *TxManager
+	sessions *table[model.Session]
+}
+
+// NewSessionRepository creates a new in-memory session repository whose
+// writes take part in transactions run by tx
+func NewSessionRepository(tx *TxManager) repository.SessionRepository {
+	return &sessionRepository{
+		tx:       tx,
+		sessions: newTable[model.Session](tx),
+	}
+}
+
+// Create stores a new session
+func (r *sessionRepository) Create(ctx context.Context, session model.Session) error {
+	defer r.tx.lock(ctx)()
+
+	if _, ok := r.sessions.rows[session.ID]; ok {
+		return repository.ErrConflict
+	}
+
+	r.sessions.put(ctx, session.ID, session)
+
+	return nil
+}
+
+// FindByID returns a session by ID, even if it has expired
+func (r *sessionRepository) FindByID(ctx context.Context, id string) (model.Session, error) {
+	defer r.tx.rlock(ctx)()
+
+	session, ok := r.sessions.rows[id]
+	if !ok {
+		return model.Session{}, repository.ErrNotFound
+	}
+
+	return session, nil
+}
+
+// Delete deletes a session
+func (r *sessionRepository) Delete(ctx context.Context, id string) error {
+	defer r.tx.lock(ctx)()
+
+	if _, ok := r.sessions.rows[id]; !ok {
+		return repository.ErrNotFound
+	}
+
+	r.sessions.remove(ctx, id)
+
+	return nil
+}
+
+// DeleteByUser deletes every session of a user
+func (r *sessionRepository) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	defer r.tx.lock(ctx)()
+
+	var deleted int64
+	for id, session := range r.sessions.rows {
+		if session.UserID == userID {
+			r.sessions.remove(ctx, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// DeleteExpired deletes sessions that expired before the given time
+func (r *sessionRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	defer r.tx.lock(ctx)()
+
+	var deleted int64
+	for id, session := range r.sessions.rows {
+		if session.ExpiresAt.Before(before) {
+			r.sessions.remove(ctx, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,45 @@
+package memory
+
+import "context"
+
+// table is a map whose writes are undone when the transaction they were
+// made in rolls back. Callers must hold the lock of tx.
+type table[V any] struct {
+	tx   *TxManager
+	rows map[string]V
+}
+
+// newTable creates an empty table whose writes take part in transactions run by tx
+func newTable[V any](tx *TxManager) *table[V] {
+	return &table[V]{
+		tx:   tx,
+		rows: make(map[string]V),
+	}
+}
+
+// put stores value under key, recording how to undo the write
+func (t *table[V]) put(ctx context.Context, key string, value V) {
+	previous, existed := t.rows[key]
+	t.tx.onRollback(ctx, func() {
+		if existed {
+			t.rows[key] = previous
+		} else {
+			delete(t.rows, key)
+		}
+	})
+
+	t.rows[key] = value
+}
+
+// remove deletes the value under key, recording how to undo the write
+func (t *table[V]) remove(ctx context.Context, key string) {
+	previous, existed := t.rows[key]
+	if !existed {
+		return
+	}
+	t.tx.onRollback(ctx, func() {
+		t.rows[key] = previous
+	})
+
+	delete(t.rows, key)
+}
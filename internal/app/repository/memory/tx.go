@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"context"
+	"sync"
+)
+
+// TxManager implements database.TxManager for the in-memory repositories.
+// Transactions hold an exclusive lock on every repository sharing the
+// manager, so they are serialized, and writes made inside a transaction are
+// undone in reverse order when it rolls back.
+type TxManager struct {
+	mu sync.RWMutex
+}
+
+// NewTxManager creates a new in-memory transaction manager
+func NewTxManager() *TxManager {
+	return &TxManager{}
+}
+
+// txState records the undo operations of a running transaction, and the
+// functions to run once it has committed
+type txState struct {
+	manager     *TxManager
+	undo        []func()
+	afterCommit []func(ctx context.Context)
+}
+
+// txKey is the context key holding the current txState
+type txKey struct{}
+
+// WithinTx runs fn while holding the store lock, rolling back its writes if
+// fn returns an error or panics
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	// Join the transaction already in progress
+	if m.current(ctx) != nil {
+		return fn(ctx)
+	}
+
+	state := &txState{manager: m}
+	if err := m.run(ctx, state, fn); err != nil {
+		return err
+	}
+
+	// Run after the lock is released, as the functions may use the store
+	for _, hook := range state.afterCommit {
+		hook(ctx)
+	}
+
+	return nil
+}
+
+// AfterCommit runs fn after the transaction carried by ctx commits
+func (m *TxManager) AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if state := m.current(ctx); state != nil {
+		state.afterCommit = append(state.afterCommit, fn)
+		return
+	}
+	fn(ctx)
+}
+
+// run runs fn as the transaction state while holding the store lock
+func (m *TxManager) run(ctx context.Context, state *txState, fn func(ctx context.Context) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	defer func() {
+		if p := recover(); p != nil {
+			state.rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, state)); err != nil {
+		state.rollback()
+		return err
+	}
+
+	return nil
+}
+
+// lock acquires the store lock for a single write unless ctx is already in
+// a transaction of this manager, and returns the matching unlock function
+func (m *TxManager) lock(ctx context.Context) func() {
+	if m.current(ctx) != nil {
+		return func() {}
+	}
+	m.mu.Lock()
+	return m.mu.Unlock
+}
+
+// rlock is like lock for reads
+func (m *TxManager) rlock(ctx context.Context) func() {
+	if m.current(ctx) != nil {
+		return func() {}
+	}
+	m.mu.RLock()
+	return m.mu.RUnlock
+}
+
+// onRollback registers undo to run if the transaction in ctx rolls back
+func (m *TxManager) onRollback(ctx context.Context, undo func()) {
+	if state := m.current(ctx); state != nil {
+		state.undo = append(state.undo, undo)
+	}
+}
+
+// current returns the transaction of this manager carried by ctx, if any
+func (m *TxManager) current(ctx context.Context) *txState {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok || state.manager != m {
+		return nil
+	}
+	return state
+}
+
+// rollback runs the undo operations in reverse order
+func (s *txState) rollback() {
+	for i := len(s.undo) - 1; i >= 0; i-- {
+		s.undo[i]()
+	}
+}
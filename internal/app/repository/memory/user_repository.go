@@ -2,7 +2,8 @@ package memory
 
 import (
 	"context"
-	"sync"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/ThePotatoVerse/internal/app/model"
@@ -12,34 +13,145 @@ import (
 
 // userRepository implements repository.UserRepository with an in-memory store
 type userRepository struct {
-	mu    sync.RWMutex
+	tx    *TxManager
 	users map[string]model.User
 }
 
-// NewUserRepository creates a new in-memory user repository
-func NewUserRepository() repository.UserRepository {
+// NewUserRepository creates a new in-memory user repository whose writes
+// take part in transactions run by tx
+func NewUserRepository(tx *TxManager) repository.UserRepository {
 	return &userRepository{
+		tx:    tx,
 		users: make(map[string]model.User),
 	}
 }
 
-// FindAll returns all users
-func (r *userRepository) FindAll(ctx context.Context) ([]model.User, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+// FindAll returns a page of users matching the query
+func (r *userRepository) FindAll(ctx context.Context, query repository.UserQuery) (repository.UserPage, error) {
+	defer r.tx.rlock(ctx)()
 
+	var after *model.User
+	if query.Cursor != "" {
+		cursor, err := repository.DecodeUserCursor(query)
+		if err != nil {
+			return repository.UserPage{}, err
+		}
+		user := cursorUser(cursor)
+		after = &user
+	}
+
+	// Apply filters
 	users := make([]model.User, 0, len(r.users))
 	for _, user := range r.users {
-		users = append(users, user)
+		if matchesQuery(query, user) {
+			users = append(users, user)
+		}
 	}
 
-	return users, nil
+	// Sort users
+	sort.Slice(users, func(i, j int) bool {
+		return compareUsers(query, users[i], users[j]) < 0
+	})
+
+	// Find the start of the page
+	start := query.Offset
+	if after != nil {
+		start = sort.Search(len(users), func(i int) bool {
+			return compareUsers(query, users[i], *after) > 0
+		})
+	}
+	if start > len(users) {
+		start = len(users)
+	}
+
+	end := len(users)
+	if query.Limit > 0 && start+query.Limit < end {
+		end = start + query.Limit
+	}
+
+	page := repository.UserPage{
+		Users: users[start:end],
+		Total: len(users),
+	}
+	if end < len(users) && end > start {
+		page.NextCursor = repository.NewUserCursor(query, users[end-1])
+	}
+
+	return page, nil
+}
+
+// matchesQuery reports whether user passes the query filters
+func matchesQuery(query repository.UserQuery, user model.User) bool {
+	if user.Deleted() && !query.IncludeDeleted {
+		return false
+	}
+	if query.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(user.Name), strings.ToLower(query.NamePrefix)) {
+		return false
+	}
+	if !query.CreatedAfter.IsZero() && !user.CreatedAt.After(query.CreatedAfter) {
+		return false
+	}
+	return true
 }
 
-// FindByID returns a user by ID
+// compareUsers orders users by the query sort field, breaking ties by ID.
+// Text is compared lowercased byte by byte, like the postgres store does.
+func compareUsers(query repository.UserQuery, a, b model.User) int {
+	var result int
+	switch query.SortBy {
+	case repository.SortByName:
+		result = strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	case repository.SortByEmail:
+		result = strings.Compare(strings.ToLower(a.Email), strings.ToLower(b.Email))
+	default:
+		result = a.CreatedAt.Compare(b.CreatedAt)
+	}
+
+	if result == 0 {
+		result = strings.Compare(a.ID, b.ID)
+	}
+
+	if query.SortDesc {
+		return -result
+	}
+	return result
+}
+
+// cursorUser builds a user holding just the fields a cursor positions on
+func cursorUser(cursor repository.UserCursor) model.User {
+	user := model.User{ID: cursor.ID}
+	switch cursor.SortBy {
+	case repository.SortByName:
+		user.Name = cursor.Value
+	case repository.SortByEmail:
+		user.Email = cursor.Value
+	default:
+		user.CreatedAt, _ = time.Parse(time.RFC3339Nano, cursor.Value)
+	}
+	return user
+}
+
+// FindByID returns an active user by ID
 func (r *userRepository) FindByID(ctx context.Context, id string) (model.User, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	defer r.tx.rlock(ctx)()
+
+	user, ok := r.users[id]
+	if !ok || user.Deleted() {
+		return model.User{}, repository.ErrNotFound
+	}
+
+	return user, nil
+}
+
+// FindByIDForUpdate returns an active user by ID. Transactions already hold
+// the store lock exclusively, so no extra locking is needed.
+func (r *userRepository) FindByIDForUpdate(ctx context.Context, id string) (model.User, error) {
+	return r.FindByID(ctx, id)
+}
+
+// FindByIDWithDeleted returns a user by ID, including soft-deleted users
+func (r *userRepository) FindByIDWithDeleted(ctx context.Context, id string) (model.User, error) {
+	defer r.tx.rlock(ctx)()
 
 	user, ok := r.users[id]
 	if !ok {
@@ -49,60 +161,265 @@ func (r *userRepository) FindByID(ctx context.Context, id string) (model.User, e
 	return user, nil
 }
 
+// FindByEmail returns the active user with the email, compared case-insensitively
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (model.User, error) {
+	defer r.tx.rlock(ctx)()
+
+	for _, user := range r.users {
+		if !user.Deleted() && strings.EqualFold(user.Email, email) {
+			return user, nil
+		}
+	}
+
+	return model.User{}, repository.ErrNotFound
+}
+
 // Create creates a new user
 func (r *userRepository) Create(ctx context.Context, user model.User) (model.User, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	defer r.tx.lock(ctx)()
+
+	// Check email uniqueness
+	if r.emailTaken(user.Email, "") {
+		return model.User{}, repository.ErrConflict
+	}
 
 	// Generate ID if not provided
 	if user.ID == "" {
 		user.ID = uuid.New().String()
 	}
 
-	// Set timestamps
+	// Set timestamps and initial version
 	now := time.Now()
 	user.CreatedAt = now
 	user.UpdatedAt = now
+	user.Version = 1
 
 	// Store user
-	r.users[user.ID] = user
+	r.put(ctx, user)
 
 	return user, nil
 }
 
-// Update updates a user
-func (r *userRepository) Update(ctx context.Context, user model.User) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// Update updates a user if its version matches the stored version. Changing
+// the email clears its verification.
+func (r *userRepository) Update(ctx context.Context, user model.User) (model.User, error) {
+	defer r.tx.lock(ctx)()
 
 	// Check if user exists
-	_, ok := r.users[user.ID]
-	if !ok {
-		return repository.ErrNotFound
+	existing, ok := r.users[user.ID]
+	if !ok || existing.Deleted() {
+		return model.User{}, repository.ErrNotFound
+	}
+
+	// Check version
+	if existing.Version != user.Version {
+		return model.User{}, repository.ErrVersionMismatch
+	}
+
+	// Check email uniqueness
+	if r.emailTaken(user.Email, user.ID) {
+		return model.User{}, repository.ErrConflict
+	}
+
+	// Keep fields an update does not change
+	user.Role = existing.Role
+	user.EmailVerifiedAt = nil
+	if user.Email == existing.Email {
+		user.EmailVerifiedAt = existing.EmailVerifiedAt
 	}
 
-	// Update timestamp
+	// Update timestamp and version
+	user.CreatedAt = existing.CreatedAt
 	user.UpdatedAt = time.Now()
+	user.Version = existing.Version + 1
 
 	// Store updated user
-	r.users[user.ID] = user
+	r.put(ctx, user)
+
+	return user, nil
+}
+
+// Patch updates the given fields of a user if its version matches the stored
+// version. Changing the email clears its verification.
+func (r *userRepository) Patch(ctx context.Context, patch repository.UserPatch) (model.User, error) {
+	defer r.tx.lock(ctx)()
+
+	// Check if user exists
+	user, ok := r.users[patch.ID]
+	if !ok || user.Deleted() {
+		return model.User{}, repository.ErrNotFound
+	}
+
+	// Check version
+	if user.Version != patch.Version {
+		return model.User{}, repository.ErrVersionMismatch
+	}
+
+	// Apply changes
+	if patch.Name != nil {
+		user.Name = *patch.Name
+	}
+	if patch.Email != nil {
+		if r.emailTaken(*patch.Email, user.ID) {
+			return model.User{}, repository.ErrConflict
+		}
+		if *patch.Email != user.Email {
+			user.EmailVerifiedAt = nil
+		}
+		user.Email = *patch.Email
+	}
+	if patch.Role != nil {
+		user.Role = *patch.Role
+	}
+
+	// Update timestamp and version
+	user.UpdatedAt = time.Now()
+	user.Version++
+
+	// Store updated user
+	r.put(ctx, user)
+
+	return user, nil
+}
+
+// MarkEmailVerified records that an active user verified the email, if it
+// is still their email
+func (r *userRepository) MarkEmailVerified(ctx context.Context, id, email string, at time.Time) (model.User, error) {
+	defer r.tx.lock(ctx)()
+
+	user, ok := r.users[id]
+	if !ok || user.Deleted() || user.Email != email {
+		return model.User{}, repository.ErrNotFound
+	}
+
+	user.EmailVerifiedAt = &at
+	user.UpdatedAt = at
+	user.Version++
+	r.put(ctx, user)
+
+	return user, nil
+}
+
+// Delete soft-deletes a user, checking its version unless version is 0
+func (r *userRepository) Delete(ctx context.Context, id string, version int) error {
+	defer r.tx.lock(ctx)()
+
+	// Check if user exists
+	user, ok := r.users[id]
+	if !ok || user.Deleted() {
+		return repository.ErrNotFound
+	}
+
+	// Check version
+	if version != 0 && user.Version != version {
+		return repository.ErrVersionMismatch
+	}
+
+	// Mark user as deleted
+	now := time.Now()
+	user.DeletedAt = &now
+	user.UpdatedAt = now
+	user.Version++
+	r.put(ctx, user)
 
 	return nil
 }
 
-// Delete deletes a user
-func (r *userRepository) Delete(ctx context.Context, id string) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// Restore restores a soft-deleted user
+func (r *userRepository) Restore(ctx context.Context, id string) (model.User, error) {
+	defer r.tx.lock(ctx)()
+
+	// Check if user is deleted
+	user, ok := r.users[id]
+	if !ok || !user.Deleted() {
+		return model.User{}, repository.ErrNotFound
+	}
+
+	// Check email uniqueness
+	if r.emailTaken(user.Email, id) {
+		return model.User{}, repository.ErrConflict
+	}
+
+	// Clear deletion
+	user.DeletedAt = nil
+	user.UpdatedAt = time.Now()
+	user.Version++
+	r.put(ctx, user)
+
+	return user, nil
+}
+
+// HardDelete permanently deletes a user, checking its version unless version is 0
+func (r *userRepository) HardDelete(ctx context.Context, id string, version int) error {
+	defer r.tx.lock(ctx)()
 
 	// Check if user exists
-	_, ok := r.users[id]
+	user, ok := r.users[id]
 	if !ok {
 		return repository.ErrNotFound
 	}
 
+	// Check version
+	if version != 0 && user.Version != version {
+		return repository.ErrVersionMismatch
+	}
+
 	// Delete user
-	delete(r.users, id)
+	r.remove(ctx, id)
 
 	return nil
 }
+
+// PurgeDeleted permanently deletes users soft-deleted before the given time
+func (r *userRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	defer r.tx.lock(ctx)()
+
+	var purged int64
+	for id, user := range r.users {
+		if user.Deleted() && user.DeletedAt.Before(before) {
+			r.remove(ctx, id)
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+// emailTaken reports whether an active user other than exceptID has the email.
+// Callers must hold the lock.
+func (r *userRepository) emailTaken(email, exceptID string) bool {
+	for id, user := range r.users {
+		if id != exceptID && !user.Deleted() && strings.EqualFold(user.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// put stores user, recording how to undo the write. Callers must hold the lock.
+func (r *userRepository) put(ctx context.Context, user model.User) {
+	previous, existed := r.users[user.ID]
+	r.tx.onRollback(ctx, func() {
+		if existed {
+			r.users[user.ID] = previous
+		} else {
+			delete(r.users, user.ID)
+		}
+	})
+
+	r.users[user.ID] = user
+}
+
+// remove deletes the user with id, recording how to undo the write.
+// Callers must hold the lock.
+func (r *userRepository) remove(ctx context.Context, id string) {
+	previous, existed := r.users[id]
+	if !existed {
+		return
+	}
+	r.tx.onRollback(ctx, func() {
+		r.users[id] = previous
+	})
+
+	delete(r.users, id)
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+)
+
+// MFARepository defines the interface for second factor storage. Each user
+// has at most one TOTP factor, which SaveTOTP creates or replaces.
+// UseTOTPStep records the time step of an accepted code and returns
+// ErrConflict unless it is later than the last recorded step, so that every
+// code is accepted once. RecordTOTPFailure counts a wrong code and returns
+// the wrong codes since ClearTOTPFailures last ran; LockTOTP sets the time
+// until which no code is accepted. ReplaceRecoveryCodes replaces every
+// recovery code of a user; UseRecoveryCode deletes one, returning ErrNotFound
+// if the user has no such code. Factors and codes are removed together with
+// their user.
+type MFARepository interface {
+	GetTOTP(ctx context.Context, userID string) (model.TOTPFactor, error)
+	SaveTOTP(ctx context.Context, factor model.TOTPFactor) error
+	UseTOTPStep(ctx context.Context, userID string, step int64) error
+	RecordTOTPFailure(ctx context.Context, userID string) (int, error)
+	LockTOTP(ctx context.Context, userID string, until time.Time) error
+	ClearTOTPFailures(ctx context.Context, userID string) error
+	DeleteTOTP(ctx context.Context, userID string) error
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codes []model.RecoveryCode) error
+	UseRecoveryCode(ctx context.Context, userID, id string) error
+	CountRecoveryCodes(ctx context.Context, userID string) (int, error)
+}
+
+// MFAChallengeRepository defines the interface for storing logins waiting
+// for their second factor. FindByID returns expired challenges.
+// RecordFailure counts a wrong code and returns the attempts made so far.
+// Delete returns ErrNotFound for unknown challenges, so that only one of
+// several concurrent completions succeeds. Challenges are removed together
+// with their user.
+type MFAChallengeRepository interface {
+	Create(ctx context.Context, challenge model.MFAChallenge) error
+	FindByID(ctx context.Context, id string) (model.MFAChallenge, error)
+	RecordFailure(ctx context.Context, id string) (int, error)
+	Delete(ctx context.Context, id string) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
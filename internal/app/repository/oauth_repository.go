@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+)
+
+// OAuthClientRepository defines the interface for OAuth client storage.
+// Delete returns ErrNotFound for unknown clients. The consents and
+// authorization codes of a client are removed together with it.
+type OAuthClientRepository interface {
+	Create(ctx context.Context, client model.OAuthClient) error
+	FindByID(ctx context.Context, id string) (model.OAuthClient, error)
+	List(ctx context.Context) ([]model.OAuthClient, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// OAuthConsentRepository defines the interface for storing the consent of
+// users to OAuth clients, one per user and client. Save creates or replaces
+// a consent. Delete returns ErrNotFound if there is none. Consents are
+// removed together with their user.
+type OAuthConsentRepository interface {
+	Get(ctx context.Context, userID, clientID string) (model.OAuthConsent, error)
+	Save(ctx context.Context, consent model.OAuthConsent) error
+	ListByUser(ctx context.Context, userID string) ([]model.OAuthConsent, error)
+	Delete(ctx context.Context, userID, clientID string) error
+}
+
+// OAuthCodeRepository defines the interface for authorization code storage.
+// Consume deletes a code and returns it, even if it has expired, or returns
+// ErrNotFound, so that each code is exchanged at most once. Codes are
+// removed together with their user.
+type OAuthCodeRepository interface {
+	Create(ctx context.Context, code model.OAuthAuthorizationCode) error
+	Consume(ctx context.Context, id string) (model.OAuthAuthorizationCode, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/pkg/database"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/jackc/pgx/v4"
+)
+
+// apiKeyRepository implements repository.APIKeyRepository with PostgreSQL
+type apiKeyRepository struct {
+	db  *database.Postgres
+	log logger.Logger
+}
+
+// NewAPIKeyRepository creates a new PostgreSQL API key repository
+func NewAPIKeyRepository(db *database.Postgres, log logger.Logger) repository.APIKeyRepository {
+	return &apiKeyRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// apiKeyColumns lists the columns scanned by scanAPIKey, in order
+const apiKeyColumns = "id, user_id, name, prefix, key_hash, scopes, created_at, expires_at, last_used_at"
+
+// scanAPIKey scans a row selected with apiKeyColumns
+func scanAPIKey(row scanner) (model.APIKey, error) {
+	var key model.APIKey
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &key.Scopes,
+		&key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt)
+	return key, err
+}
+
+// Create stores a new API key
+func (r *apiKeyRepository) Create(ctx context.Context, key model.APIKey) error {
+	query := `
+		INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	_, err := r.db.Conn(ctx).Exec(ctx, query,
+		key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash, scopes, key.CreatedAt, key.ExpiresAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrConflict
+		}
+		return err
+	}
+
+	return nil
+}
+
+// FindByPrefix returns the API key with the prefix, even if it has expired
+func (r *apiKeyRepository) FindByPrefix(ctx context.Context, prefix string) (model.APIKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE prefix = $1
+	`
+
+	key, err := scanAPIKey(r.db.Conn(ctx).QueryRow(ctx, query, prefix))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.APIKey{}, repository.ErrNotFound
+		}
+		return model.APIKey{}, err
+	}
+
+	return key, nil
+}
+
+// ListByUser returns the API keys of a user, oldest first
+func (r *apiKeyRepository) ListByUser(ctx context.Context, userID string) ([]model.APIKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.Conn(ctx).Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]model.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// Delete deletes an API key of a user
+func (r *apiKeyRepository) Delete(ctx context.Context, userID, id string) error {
+	result, err := r.db.Conn(ctx).Exec(ctx, "DELETE FROM api_keys WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// TouchLastUsed records when an API key was last used
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	result, err := r.db.Conn(ctx).Exec(ctx, "UPDATE api_keys SET last_used_at = $2 WHERE id = $1", id, usedAt)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/pkg/database"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/jackc/pgx/v4"
+)
+
+// credentialRepository implements repository.CredentialRepository with PostgreSQL
+type credentialRepository struct {
+	db  *database.Postgres
+	log logger.Logger
+}
+
+// NewCredentialRepository creates a new PostgreSQL credential repository
+func NewCredentialRepository(db *database.Postgres, log logger.Logger) repository.CredentialRepository {
+	return &credentialRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Get returns the credential of a user
+func (r *credentialRepository) Get(ctx context.Context, userID string) (model.Credential, error) {
+	query := `
+		SELECT user_id, password_hash, created_at, updated_at
+		FROM user_credentials
+		WHERE user_id = $1
+	`
+
+	var credential model.Credential
+	err := r.db.Conn(ctx).QueryRow(ctx, query, userID).Scan(
+		&credential.UserID, &credential.PasswordHash, &credential.CreatedAt, &credential.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Credential{}, repository.ErrNotFound
+		}
+		return model.Credential{}, err
+	}
+
+	return credential, nil
+}
+
+// Upsert creates or replaces the credential of a user
+func (r *credentialRepository) Upsert(ctx context.Context, credential model.Credential) error {
+	query := `
+		INSERT INTO user_credentials (user_id, password_hash, created_at, updated_at)
+		VALUES ($1, $2, $3, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET password_hash = EXCLUDED.password_hash, updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.db.Conn(ctx).Exec(ctx, query, credential.UserID, credential.PasswordHash, time.Now())
+	return err
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/pkg/database"
+	"github.com/ThePotatoVerse/pkg/logger"
+)
+
+// emailVerificationRepository implements repository.EmailVerificationRepository with PostgreSQL
+type emailVerificationRepository struct {
+	db  *database.Postgres
+	log logger.Logger
+}
+
+// NewEmailVerificationRepository creates a new PostgreSQL email verification repository
+func NewEmailVerificationRepository(db *database.Postgres, log logger.Logger) repository.EmailVerificationRepository {
+	return &emailVerificationRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Create stores a new email verification
+func (r *emailVerificationRepository) Create(ctx context.Context, verification model.EmailVerification) error {
+	query := `
+		INSERT INTO email_verifications (id, user_id, email, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.db.Conn(ctx).Exec(ctx, query,
+		verification.ID, verification.UserID, verification.Email, verification.CreatedAt, verification.ExpiresAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrConflict
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Consume marks an email verification as used, unless it already is
+func (r *emailVerificationRepository) Consume(ctx context.Context, id string, usedAt time.Time) error {
+	result, err := r.db.Conn(ctx).Exec(ctx,
+		"UPDATE email_verifications SET used_at = $2 WHERE id = $1 AND used_at IS NULL", id, usedAt)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		// Tell a missing verification from one that has already been used
+		var exists bool
+		err := r.db.Conn(ctx).QueryRow(ctx,
+			"SELECT EXISTS (SELECT 1 FROM email_verifications WHERE id = $1)", id).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return repository.ErrNotFound
+		}
+		return repository.ErrConflict
+	}
+
+	return nil
+}
+
+// DeleteExpired deletes email verifications that expired before the given time
+func (r *emailVerificationRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.Conn(ctx).Exec(ctx, "DELETE FROM email_verifications WHERE expires_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/pkg/database"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/jackc/pgx/v4"
+)
+
+// externalIdentityRepository implements repository.ExternalIdentityRepository with PostgreSQL
+type externalIdentityRepository struct {
+	db  *database.Postgres
+	log logger.Logger
+}
+
+// NewExternalIdentityRepository creates a new PostgreSQL external identity repository
+func NewExternalIdentityRepository(db *database.Postgres, log logger.Logger) repository.ExternalIdentityRepository {
+	return &externalIdentityRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// externalIdentityColumns lists the columns scanned by scanExternalIdentity, in order
+const externalIdentityColumns = "provider, subject, user_id, email, created_at"
+
+// scanExternalIdentity scans a row selected with externalIdentityColumns
+func scanExternalIdentity(row scanner) (model.ExternalIdentity, error) {
+	var identity model.ExternalIdentity
+	err := row.Scan(&identity.Provider, &identity.Subject, &identity.UserID, &identity.Email, &identity.CreatedAt)
+	return identity, err
+}
+
+// Create links a user to an external account
+func (r *externalIdentityRepository) Create(ctx context.Context, identity model.ExternalIdentity) error {
+	query := `
+		INSERT INTO external_identities (provider, subject, user_id, email, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.db.Conn(ctx).Exec(ctx, query,
+		identity.Provider, identity.Subject, identity.UserID, identity.Email, identity.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrConflict
+		}
+		// The user was deleted meanwhile
+		if isForeignKeyViolation(err) {
+			return repository.ErrNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// FindBySubject returns the link to an external account
+func (r *externalIdentityRepository) FindBySubject(ctx context.Context, provider, subject string) (model.ExternalIdentity, error) {
+	query := `
+		SELECT ` + externalIdentityColumns + `
+		FROM external_identities
+		WHERE provider = $1 AND subject = $2
+	`
+
+	identity, err := scanExternalIdentity(r.db.Conn(ctx).QueryRow(ctx, query, provider, subject))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.ExternalIdentity{}, repository.ErrNotFound
+		}
+		return model.ExternalIdentity{}, err
+	}
+
+	return identity, nil
+}
+
+// ListByUser returns the external accounts linked to a user, oldest first
+func (r *externalIdentityRepository) ListByUser(ctx context.Context, userID string) ([]model.ExternalIdentity, error) {
+	query := `
+		SELECT ` + externalIdentityColumns + `
+		FROM external_identities
+		WHERE user_id = $1
+		ORDER BY created_at, provider
+	`
+
+	rows, err := r.db.Conn(ctx).Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := make([]model.ExternalIdentity, 0)
+	for rows.Next() {
+		identity, err := scanExternalIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+
+	return identities, rows.Err()
+}
+
+// Delete unlinks a user from their account at a provider
+func (r *externalIdentityRepository) Delete(ctx context.Context, userID, provider string) error {
+	result, err := r.db.Conn(ctx).Exec(ctx,
+		"DELETE FROM external_identities WHERE user_id = $1 AND provider = $2", userID, provider)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/pkg/database"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/jackc/pgx/v4"
+)
+
+// mfaChallengeRepository implements repository.MFAChallengeRepository with PostgreSQL
+type mfaChallengeRepository struct {
+	db  *database.Postgres
+	log logger.Logger
+}
+
+// NewMFAChallengeRepository creates a new PostgreSQL MFA challenge repository
+func NewMFAChallengeRepository(db *database.Postgres, log logger.Logger) repository.MFAChallengeRepository {
+	return &mfaChallengeRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Create stores a new MFA challenge
+func (r *mfaChallengeRepository) Create(ctx context.Context, challenge model.MFAChallenge) error {
+	query := `
+		INSERT INTO mfa_challenges (id, user_id, created_at, expires_at, attempts)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.db.Conn(ctx).Exec(ctx, query,
+		challenge.ID,
+		challenge.UserID,
+		challenge.CreatedAt,
+		challenge.ExpiresAt,
+		challenge.Attempts,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrConflict
+		}
+		return err
+	}
+
+	return nil
+}
+
+// FindByID returns an MFA challenge by ID, even if it has expired
+func (r *mfaChallengeRepository) FindByID(ctx context.Context, id string) (model.MFAChallenge, error) {
+	query := `
+		SELECT id, user_id, created_at, expires_at, attempts
+		FROM mfa_challenges
+		WHERE id = $1
+	`
+
+	var challenge model.MFAChallenge
+	err := r.db.Conn(ctx).QueryRow(ctx, query, id).Scan(
+		&challenge.ID,
+		&challenge.UserID,
+		&challenge.CreatedAt,
+		&challenge.ExpiresAt,
+		&challenge.Attempts,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.MFAChallenge{}, repository.ErrNotFound
+		}
+		return model.MFAChallenge{}, err
+	}
+
+	return challenge, nil
+}
+
+// RecordFailure counts a wrong code for an MFA challenge
+func (r *mfaChallengeRepository) RecordFailure(ctx context.Context, id string) (int, error) {
+	var attempts int
+	err := r.db.Conn(ctx).QueryRow(ctx,
+		"UPDATE mfa_challenges SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts", id).Scan(&attempts)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, repository.ErrNotFound
+		}
+		return 0, err
+	}
+
+	return attempts, nil
+}
+
+// Delete deletes an MFA challenge
+func (r *mfaChallengeRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.Conn(ctx).Exec(ctx, "DELETE FROM mfa_challenges WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteExpired deletes MFA challenges that expired before the given time
+func (r *mfaChallengeRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.Conn(ctx).Exec(ctx, "DELETE FROM mfa_challenges WHERE expires_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,200 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/pkg/database"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/jackc/pgx/v4"
+)
+
+// mfaRepository implements repository.MFARepository with PostgreSQL
+type mfaRepository struct {
+	db  *database.Postgres
+	log logger.Logger
+}
+
+// NewMFARepository creates a new PostgreSQL MFA repository
+func NewMFARepository(db *database.Postgres, log logger.Logger) repository.MFARepository {
+	return &mfaRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// GetTOTP returns the TOTP factor of a user, confirmed or not
+func (r *mfaRepository) GetTOTP(ctx context.Context, userID string) (model.TOTPFactor, error) {
+	query := `
+		SELECT user_id, secret, created_at, confirmed_at, last_used_step, failed_attempts, locked_until
+		FROM totp_factors
+		WHERE user_id = $1
+	`
+
+	var factor model.TOTPFactor
+	err := r.db.Conn(ctx).QueryRow(ctx, query, userID).Scan(
+		&factor.UserID,
+		&factor.Secret,
+		&factor.CreatedAt,
+		&factor.ConfirmedAt,
+		&factor.LastUsedStep,
+		&factor.FailedAttempts,
+		&factor.LockedUntil,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.TOTPFactor{}, repository.ErrNotFound
+		}
+		return model.TOTPFactor{}, err
+	}
+
+	return factor, nil
+}
+
+// SaveTOTP creates or replaces the TOTP factor of a user
+func (r *mfaRepository) SaveTOTP(ctx context.Context, factor model.TOTPFactor) error {
+	query := `
+		INSERT INTO totp_factors (user_id, secret, created_at, confirmed_at, last_used_step, failed_attempts, locked_until)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret,
+			created_at = EXCLUDED.created_at,
+			confirmed_at = EXCLUDED.confirmed_at,
+			last_used_step = EXCLUDED.last_used_step,
+			failed_attempts = EXCLUDED.failed_attempts,
+			locked_until = EXCLUDED.locked_until
+	`
+
+	_, err := r.db.Conn(ctx).Exec(ctx, query,
+		factor.UserID,
+		factor.Secret,
+		factor.CreatedAt,
+		factor.ConfirmedAt,
+		factor.LastUsedStep,
+		factor.FailedAttempts,
+		factor.LockedUntil,
+	)
+	return err
+}
+
+// UseTOTPStep records the time step of an accepted code, unless a code of
+// the same or a later step was accepted before
+func (r *mfaRepository) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	result, err := r.db.Conn(ctx).Exec(ctx,
+		"UPDATE totp_factors SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2", userID, step)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		// Tell a replayed code apart from a missing factor
+		if _, err := r.GetTOTP(ctx, userID); err != nil {
+			return err
+		}
+		return repository.ErrConflict
+	}
+
+	return nil
+}
+
+// RecordTOTPFailure counts a wrong code against the TOTP factor of a user
+func (r *mfaRepository) RecordTOTPFailure(ctx context.Context, userID string) (int, error) {
+	var attempts int
+	err := r.db.Conn(ctx).QueryRow(ctx,
+		"UPDATE totp_factors SET failed_attempts = failed_attempts + 1 WHERE user_id = $1 RETURNING failed_attempts",
+		userID).Scan(&attempts)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, repository.ErrNotFound
+		}
+		return 0, err
+	}
+
+	return attempts, nil
+}
+
+// LockTOTP stops the TOTP factor of a user from accepting codes until then.
+// A later lock set concurrently is kept.
+func (r *mfaRepository) LockTOTP(ctx context.Context, userID string, until time.Time) error {
+	result, err := r.db.Conn(ctx).Exec(ctx,
+		"UPDATE totp_factors SET locked_until = GREATEST(locked_until, $2) WHERE user_id = $1", userID, until)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// ClearTOTPFailures forgets the wrong codes and lock of the TOTP factor of a
+// user
+func (r *mfaRepository) ClearTOTPFailures(ctx context.Context, userID string) error {
+	result, err := r.db.Conn(ctx).Exec(ctx,
+		"UPDATE totp_factors SET failed_attempts = 0, locked_until = NULL WHERE user_id = $1", userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteTOTP deletes the TOTP factor of a user, if any
+func (r *mfaRepository) DeleteTOTP(ctx context.Context, userID string) error {
+	_, err := r.db.Conn(ctx).Exec(ctx, "DELETE FROM totp_factors WHERE user_id = $1", userID)
+	return err
+}
+
+// ReplaceRecoveryCodes replaces every recovery code of a user. Callers run it
+// within a transaction, so that a user never ends up with only some codes.
+func (r *mfaRepository) ReplaceRecoveryCodes(ctx context.Context, userID string, codes []model.RecoveryCode) error {
+	conn := r.db.Conn(ctx)
+
+	if _, err := conn.Exec(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	for _, code := range codes {
+		_, err := conn.Exec(ctx,
+			"INSERT INTO recovery_codes (id, user_id, created_at) VALUES ($1, $2, $3)",
+			code.ID, code.UserID, code.CreatedAt)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return repository.ErrConflict
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UseRecoveryCode deletes a recovery code of a user
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID, id string) error {
+	result, err := r.db.Conn(ctx).Exec(ctx, "DELETE FROM recovery_codes WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// CountRecoveryCodes counts the unused recovery codes of a user
+func (r *mfaRepository) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := r.db.Conn(ctx).QueryRow(ctx, "SELECT count(*) FROM recovery_codes WHERE user_id = $1", userID).Scan(&count)
+	return count, err
+}
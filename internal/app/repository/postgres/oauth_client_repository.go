@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/pkg/database"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/jackc/pgx/v4"
+)
+
+// oauthClientRepository implements repository.OAuthClientRepository with PostgreSQL
+type oauthClientRepository struct {
+	db  *database.Postgres
+	log logger.Logger
+}
+
+// NewOAuthClientRepository creates a new PostgreSQL OAuth client repository
+func NewOAuthClientRepository(db *database.Postgres, log logger.Logger) repository.OAuthClientRepository {
+	return &oauthClientRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// oauthClientColumns lists the columns scanned by scanOAuthClient, in order
+const oauthClientColumns = "id, name, secret_hash, redirect_uris, created_at"
+
+// scanOAuthClient scans a row selected with oauthClientColumns
+func scanOAuthClient(row scanner) (model.OAuthClient, error) {
+	var client model.OAuthClient
+	err := row.Scan(&client.ID, &client.Name, &client.SecretHash, &client.RedirectURIs, &client.CreatedAt)
+	return client, err
+}
+
+// Create stores a new OAuth client
+func (r *oauthClientRepository) Create(ctx context.Context, client model.OAuthClient) error {
+	query := `
+		INSERT INTO oauth_clients (id, name, secret_hash, redirect_uris, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.db.Conn(ctx).Exec(ctx, query,
+		client.ID, client.Name, client.SecretHash, client.RedirectURIs, client.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrConflict
+		}
+		return err
+	}
+
+	return nil
+}
+
+// FindByID returns an OAuth client by ID
+func (r *oauthClientRepository) FindByID(ctx context.Context, id string) (model.OAuthClient, error) {
+	query := `
+		SELECT ` + oauthClientColumns + `
+		FROM oauth_clients
+		WHERE id = $1
+	`
+
+	client, err := scanOAuthClient(r.db.Conn(ctx).QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.OAuthClient{}, repository.ErrNotFound
+		}
+		return model.OAuthClient{}, err
+	}
+
+	return client, nil
+}
+
+// List returns every OAuth client, oldest first
+func (r *oauthClientRepository) List(ctx context.Context) ([]model.OAuthClient, error) {
+	query := `
+		SELECT ` + oauthClientColumns + `
+		FROM oauth_clients
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.Conn(ctx).Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clients := make([]model.OAuthClient, 0)
+	for rows.Next() {
+		client, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+
+	return clients, rows.Err()
+}
+
+// Delete deletes an OAuth client
+func (r *oauthClientRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.Conn(ctx).Exec(ctx, "DELETE FROM oauth_clients WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
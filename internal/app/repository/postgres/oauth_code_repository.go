@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/pkg/database"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/jackc/pgx/v4"
+)
+
+// oauthCodeRepository implements repository.OAuthCodeRepository with PostgreSQL
+type oauthCodeRepository struct {
+	db  *database.Postgres
+	log logger.Logger
+}
+
+// NewOAuthCodeRepository creates a new PostgreSQL authorization code repository
+func NewOAuthCodeRepository(db *database.Postgres, log logger.Logger) repository.OAuthCodeRepository {
+	return &oauthCodeRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Create stores a new authorization code
+func (r *oauthCodeRepository) Create(ctx context.Context, code model.OAuthAuthorizationCode) error {
+	query := `
+		INSERT INTO oauth_authorization_codes
+			(id, client_id, user_id, redirect_uri, scopes, code_challenge, nonce, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.db.Conn(ctx).Exec(ctx, query,
+		code.ID,
+		code.ClientID,
+		code.UserID,
+		code.RedirectURI,
+		code.Scopes,
+		code.CodeChallenge,
+		code.Nonce,
+		code.CreatedAt,
+		code.ExpiresAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrConflict
+		}
+		if isForeignKeyViolation(err) {
+			return repository.ErrNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Consume deletes an authorization code and returns it
+func (r *oauthCodeRepository) Consume(ctx context.Context, id string) (model.OAuthAuthorizationCode, error) {
+	query := `
+		DELETE FROM oauth_authorization_codes
+		WHERE id = $1
+		RETURNING id, client_id, user_id, redirect_uri, scopes, code_challenge, nonce, created_at, expires_at
+	`
+
+	var code model.OAuthAuthorizationCode
+	err := r.db.Conn(ctx).QueryRow(ctx, query, id).Scan(
+		&code.ID,
+		&code.ClientID,
+		&code.UserID,
+		&code.RedirectURI,
+		&code.Scopes,
+		&code.CodeChallenge,
+		&code.Nonce,
+		&code.CreatedAt,
+		&code.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.OAuthAuthorizationCode{}, repository.ErrNotFound
+		}
+		return model.OAuthAuthorizationCode{}, err
+	}
+
+	return code, nil
+}
+
+// DeleteExpired deletes authorization codes that expired before the given time
+func (r *oauthCodeRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.Conn(ctx).Exec(ctx, "DELETE FROM oauth_authorization_codes WHERE expires_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
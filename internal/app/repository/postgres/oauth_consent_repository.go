@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/pkg/database"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/jackc/pgx/v4"
+)
+
+// oauthConsentRepository implements repository.OAuthConsentRepository with PostgreSQL
+type oauthConsentRepository struct {
+	db  *database.Postgres
+	log logger.Logger
+}
+
+// NewOAuthConsentRepository creates a new PostgreSQL OAuth consent repository
+func NewOAuthConsentRepository(db *database.Postgres, log logger.Logger) repository.OAuthConsentRepository {
+	return &oauthConsentRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// oauthConsentColumns lists the columns scanned by scanOAuthConsent, in order
+const oauthConsentColumns = "user_id, client_id, scopes, created_at, updated_at"
+
+// scanOAuthConsent scans a row selected with oauthConsentColumns
+func scanOAuthConsent(row scanner) (model.OAuthConsent, error) {
+	var consent model.OAuthConsent
+	err := row.Scan(&consent.UserID, &consent.ClientID, &consent.Scopes, &consent.CreatedAt, &consent.UpdatedAt)
+	return consent, err
+}
+
+// Get returns the consent of a user to a client
+func (r *oauthConsentRepository) Get(ctx context.Context, userID, clientID string) (model.OAuthConsent, error) {
+	query := `
+		SELECT ` + oauthConsentColumns + `
+		FROM oauth_consents
+		WHERE user_id = $1 AND client_id = $2
+	`
+
+	consent, err := scanOAuthConsent(r.db.Conn(ctx).QueryRow(ctx, query, userID, clientID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.OAuthConsent{}, repository.ErrNotFound
+		}
+		return model.OAuthConsent{}, err
+	}
+
+	return consent, nil
+}
+
+// Save creates or replaces the consent of a user to a client
+func (r *oauthConsentRepository) Save(ctx context.Context, consent model.OAuthConsent) error {
+	query := `
+		INSERT INTO oauth_consents (user_id, client_id, scopes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, client_id) DO UPDATE
+		SET scopes = EXCLUDED.scopes,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.db.Conn(ctx).Exec(ctx, query,
+		consent.UserID, consent.ClientID, consent.Scopes, consent.CreatedAt, consent.UpdatedAt)
+	if err != nil {
+		// The user or client was deleted meanwhile
+		if isForeignKeyViolation(err) {
+			return repository.ErrNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// ListByUser returns the consents of a user, oldest first
+func (r *oauthConsentRepository) ListByUser(ctx context.Context, userID string) ([]model.OAuthConsent, error) {
+	query := `
+		SELECT ` + oauthConsentColumns + `
+		FROM oauth_consents
+		WHERE user_id = $1
+		ORDER BY created_at, client_id
+	`
+
+	rows, err := r.db.Conn(ctx).Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	consents := make([]model.OAuthConsent, 0)
+	for rows.Next() {
+		consent, err := scanOAuthConsent(rows)
+		if err != nil {
+			return nil, err
+		}
+		consents = append(consents, consent)
+	}
+
+	return consents, rows.Err()
+}
+
+// Delete deletes the consent of a user to a client
+func (r *oauthConsentRepository) Delete(ctx context.Context, userID, clientID string) error {
+	result, err := r.db.Conn(ctx).Exec(ctx,
+		"DELETE FROM oauth_consents WHERE user_id = $1 AND client_id = $2", userID, clientID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/pkg/database"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/jackc/pgx/v4"
+)
+
+// passwordResetRepository implements repository.PasswordResetRepository with PostgreSQL
+type passwordResetRepository struct {
+	db  *database.Postgres
+	log logger.Logger
+}
+
+// NewPasswordResetRepository creates a new PostgreSQL password reset repository
+func NewPasswordResetRepository(db *database.Postgres, log logger.Logger) repository.PasswordResetRepository {
+	return &passwordResetRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Create stores a new password reset
+func (r *passwordResetRepository) Create(ctx context.Context, reset model.PasswordReset) error {
+	query := `
+		INSERT INTO password_resets (id, user_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	_, err := r.db.Conn(ctx).Exec(ctx, query, reset.ID, reset.UserID, reset.CreatedAt, reset.ExpiresAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrConflict
+		}
+		return err
+	}
+
+	return nil
+}
+
+// FindByID returns a password reset by ID, even if it has expired
+func (r *passwordResetRepository) FindByID(ctx context.Context, id string) (model.PasswordReset, error) {
+	query := `
+		SELECT id, user_id, created_at, expires_at
+		FROM password_resets
+		WHERE id = $1
+	`
+
+	var reset model.PasswordReset
+	err := r.db.Conn(ctx).QueryRow(ctx, query, id).Scan(&reset.ID, &reset.UserID, &reset.CreatedAt, &reset.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.PasswordReset{}, repository.ErrNotFound
+		}
+		return model.PasswordReset{}, err
+	}
+
+	return reset, nil
+}
+
+// CountSince counts the password resets created for a user since the given time
+func (r *passwordResetRepository) CountSince(ctx context.Context, userID string, since time.Time) (int, error) {
+	var count int
+	err := r.db.Conn(ctx).QueryRow(ctx,
+		"SELECT count(*) FROM password_resets WHERE user_id = $1 AND created_at >= $2", userID, since).Scan(&count)
+	return count, err
+}
+
+// Delete deletes a password reset
+func (r *passwordResetRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.Conn(ctx).Exec(ctx, "DELETE FROM password_resets WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteByUser deletes every password reset of a user
+func (r *passwordResetRepository) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	result, err := r.db.Conn(ctx).Exec(ctx, "DELETE FROM password_resets WHERE user_id = $1", userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// DeleteExpired deletes password resets that expired before the given time
+func (r *passwordResetRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.Conn(ctx).Exec(ctx, "DELETE FROM password_resets WHERE expires_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
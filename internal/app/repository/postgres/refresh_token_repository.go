@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/pkg/database"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/jackc/pgx/v4"
+)
+
+// refreshTokenRepository implements repository.RefreshTokenRepository with PostgreSQL
+type refreshTokenRepository struct {
+	db  *database.Postgres
+	log logger.Logger
+}
+
+// NewRefreshTokenRepository creates a new PostgreSQL refresh token repository
+func NewRefreshTokenRepository(db *database.Postgres, log logger.Logger) repository.RefreshTokenRepository {
+	return &refreshTokenRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Create stores a new refresh token
+func (r *refreshTokenRepository) Create(ctx context.Context, token model.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (id, family_id, user_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.db.Conn(ctx).Exec(ctx, query, token.ID, token.FamilyID, token.UserID, token.CreatedAt, token.ExpiresAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrConflict
+		}
+		return err
+	}
+
+	return nil
+}
+
+// FindByID returns a refresh token by ID, even if it has been used or has expired
+func (r *refreshTokenRepository) FindByID(ctx context.Context, id string) (model.RefreshToken, error) {
+	query := `
+		SELECT id, family_id, user_id, created_at, expires_at, used_at
+		FROM refresh_tokens
+		WHERE id = $1
+	`
+
+	var token model.RefreshToken
+	err := r.db.Conn(ctx).QueryRow(ctx, query, id).Scan(
+		&token.ID, &token.FamilyID, &token.UserID, &token.CreatedAt, &token.ExpiresAt, &token.UsedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.RefreshToken{}, repository.ErrNotFound
+		}
+		return model.RefreshToken{}, err
+	}
+
+	return token, nil
+}
+
+// MarkUsed marks a refresh token as used, unless it already is
+func (r *refreshTokenRepository) MarkUsed(ctx context.Context, id string, usedAt time.Time) error {
+	result, err := r.db.Conn(ctx).Exec(ctx,
+		"UPDATE refresh_tokens SET used_at = $2 WHERE id = $1 AND used_at IS NULL", id, usedAt)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		// Tell a missing token from one that has already been used
+		if _, err := r.FindByID(ctx, id); err != nil {
+			return err
+		}
+		return repository.ErrConflict
+	}
+
+	return nil
+}
+
+// DeleteFamily deletes every refresh token of a family
+func (r *refreshTokenRepository) DeleteFamily(ctx context.Context, familyID string) (int64, error) {
+	result, err := r.db.Conn(ctx).Exec(ctx, "DELETE FROM refresh_tokens WHERE family_id = $1", familyID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// DeleteByUser deletes every refresh token of a user
+func (r *refreshTokenRepository) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	result, err := r.db.Conn(ctx).Exec(ctx, "DELETE FROM refresh_tokens WHERE user_id = $1", userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// DeleteExpired deletes refresh tokens that expired before the given time
+func (r *refreshTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.Conn(ctx).Exec(ctx, "DELETE FROM refresh_tokens WHERE expires_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
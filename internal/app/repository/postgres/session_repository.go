@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/pkg/database"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/jackc/pgx/v4"
+)
+
+// sessionRepository implements repository.SessionRepository with PostgreSQL
+type sessionRepository struct {
+	db  *database.Postgres
+	log logger.Logger
+}
+
+// NewSessionRepository creates a new PostgreSQL session repository
+func NewSessionRepository(db *database.Postgres, log logger.Logger) repository.SessionRepository {
+	return &sessionRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+// Create stores a new session
+func (r *sessionRepository) Create(ctx context.Context, session model.Session) error {
+	query := `
+		INSERT INTO sessions (id, user_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	_, err := r.db.Conn(ctx).Exec(ctx, query, session.ID, session.UserID, session.CreatedAt, session.ExpiresAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrConflict
+		}
+		return err
+	}
+
+	return nil
+}
+
+// FindByID returns a session by ID, even if it has expired
+func (r *sessionRepository) FindByID(ctx context.Context, id string) (model.Session, error) {
+	query := `
+		SELECT id, user_id, created_at, expires_at
+		FROM sessions
+		WHERE id = $1
+	`
+
+	var session model.Session
+	err := r.db.Conn(ctx).QueryRow(ctx, query, id).Scan(
+		&session.ID, &session.UserID, &session.CreatedAt, &session.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Session{}, repository.ErrNotFound
+		}
+		return model.Session{}, err
+	}
+
+	return session, nil
+}
+
+// Delete deletes a session
+func (r *sessionRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.Conn(ctx).Exec(ctx, "DELETE FROM sessions WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteByUser deletes every session of a user
+func (r *sessionRepository) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	result, err := r.db.Conn(ctx).Exec(ctx, "DELETE FROM sessions WHERE user_id = $1", userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// DeleteExpired deletes sessions that expired before the given time
+func (r *sessionRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.Conn(ctx).Exec(ctx, "DELETE FROM sessions WHERE expires_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
@@ -3,6 +3,8 @@ package postgres
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ThePotatoVerse/internal/app/model"
@@ -10,6 +12,7 @@ import (
 	"github.com/ThePotatoVerse/pkg/database"
 	"github.com/ThePotatoVerse/pkg/logger"
 	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 )
 
@@ -27,48 +30,199 @@ func NewUserRepository(db *database.Postgres, log logger.Logger) repository.User
 	}
 }
 
-// FindAll returns all users
-func (r *userRepository) FindAll(ctx context.Context) ([]model.User, error) {
-	query := `
-		SELECT id, name, email, created_at, updated_at
+// userColumns lists the columns scanned by scanUser, in order
+const userColumns = "id, name, email, email_verified_at, role, version, created_at, updated_at, deleted_at"
+
+// scanner is implemented by pgx.Row and pgx.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser scans a row selected with userColumns
+func scanUser(row scanner) (model.User, error) {
+	var user model.User
+	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.EmailVerifiedAt, &user.Role, &user.Version, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt)
+	return user, err
+}
+
+// userSortColumns maps sort fields to their columns
+var userSortColumns = map[repository.UserSortField]string{
+	repository.SortByName:      "name",
+	repository.SortByEmail:     "email",
+	repository.SortByCreatedAt: "created_at",
+}
+
+// userSortKey returns the expression users are ordered by for a sort field.
+// Text is compared lowercased byte by byte, rather than by the database
+// collation, so that the order matches the memory store.
+func userSortKey(sortBy repository.UserSortField, expr string) string {
+	if sortBy == repository.SortByCreatedAt {
+		return expr
+	}
+	return fmt.Sprintf(`lower(%s) COLLATE "C"`, expr)
+}
+
+// likeEscaper escapes LIKE wildcards in user supplied patterns
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// FindAll returns a page of users matching the query
+func (r *userRepository) FindAll(ctx context.Context, query repository.UserQuery) (repository.UserPage, error) {
+	sortBy := query.SortBy
+	column, ok := userSortColumns[sortBy]
+	if !ok {
+		sortBy = repository.SortByCreatedAt
+		column = userSortColumns[sortBy]
+	}
+
+	// Build filters
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	if !query.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	if query.NamePrefix != "" {
+		args = append(args, likeEscaper.Replace(strings.ToLower(query.NamePrefix))+"%")
+		conditions = append(conditions, fmt.Sprintf("lower(name) LIKE $%d", len(args)))
+	}
+	if !query.CreatedAfter.IsZero() {
+		args = append(args, query.CreatedAfter)
+		conditions = append(conditions, fmt.Sprintf("created_at > $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// Count matching users
+	var total int
+	countQuery := fmt.Sprintf("SELECT count(*) FROM users %s", where)
+	if err := r.db.Conn(ctx).QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return repository.UserPage{}, err
+	}
+
+	// Position after the cursor, if any
+	direction, comparator := "ASC", ">"
+	if query.SortDesc {
+		direction, comparator = "DESC", "<"
+	}
+
+	if query.Cursor != "" {
+		cursor, err := repository.DecodeUserCursor(query)
+		if err != nil {
+			return repository.UserPage{}, err
+		}
+
+		var value interface{} = cursor.Value
+		if sortBy == repository.SortByCreatedAt {
+			value, _ = time.Parse(time.RFC3339Nano, cursor.Value)
+		}
+
+		args = append(args, value, cursor.ID)
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s, $%d)",
+			userSortKey(sortBy, column), comparator, userSortKey(sortBy, fmt.Sprintf("$%d", len(args)-1)), len(args)))
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	selectQuery := fmt.Sprintf(`
+		SELECT %s
 		FROM users
-		ORDER BY created_at DESC
-	`
+		%s
+		ORDER BY %s %s, id %s
+	`, userColumns, where, userSortKey(sortBy, column), direction, direction)
 
-	rows, err := r.db.Pool.Query(ctx, query)
+	// Fetch one extra row to find out whether there is a next page
+	if query.Limit > 0 {
+		args = append(args, query.Limit+1)
+		selectQuery += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if query.Offset > 0 && query.Cursor == "" {
+		args = append(args, query.Offset)
+		selectQuery += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := r.db.Conn(ctx).Query(ctx, selectQuery, args...)
 	if err != nil {
-		return nil, err
+		return repository.UserPage{}, err
 	}
 	defer rows.Close()
 
-	var users []model.User
+	users := make([]model.User, 0)
 	for rows.Next() {
-		var user model.User
-		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt); err != nil {
-			return nil, err
+		user, err := scanUser(rows)
+		if err != nil {
+			return repository.UserPage{}, err
 		}
 		users = append(users, user)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return repository.UserPage{}, err
 	}
 
-	return users, nil
+	page := repository.UserPage{
+		Users: users,
+		Total: total,
+	}
+	if query.Limit > 0 && len(users) > query.Limit {
+		page.Users = users[:query.Limit]
+		page.NextCursor = repository.NewUserCursor(query, page.Users[query.Limit-1])
+	}
+
+	return page, nil
 }
 
-// FindByID returns a user by ID
+// FindByID returns an active user by ID
 func (r *userRepository) FindByID(ctx context.Context, id string) (model.User, error) {
 	query := `
-		SELECT id, name, email, created_at, updated_at
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	return r.findOne(ctx, query, id)
+}
+
+// FindByIDForUpdate returns an active user by ID, locking its row until the
+// surrounding transaction ends
+func (r *userRepository) FindByIDForUpdate(ctx context.Context, id string) (model.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`
+
+	return r.findOne(ctx, query, id)
+}
+
+// FindByIDWithDeleted returns a user by ID, including soft-deleted users
+func (r *userRepository) FindByIDWithDeleted(ctx context.Context, id string) (model.User, error) {
+	query := `
+		SELECT ` + userColumns + `
 		FROM users
 		WHERE id = $1
 	`
 
-	var user model.User
-	err := r.db.Pool.QueryRow(ctx, query, id).Scan(
-		&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt,
-	)
+	return r.findOne(ctx, query, id)
+}
+
+// FindByEmail returns the active user with the email, compared case-insensitively
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (model.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE lower(email) = lower($1) AND deleted_at IS NULL
+	`
+
+	return r.findOne(ctx, query, email)
+}
+
+// findOne scans the single user selected by query
+func (r *userRepository) findOne(ctx context.Context, query string, args ...interface{}) (model.User, error) {
+	user, err := scanUser(r.db.Conn(ctx).QueryRow(ctx, query, args...))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return model.User{}, repository.ErrNotFound
@@ -82,10 +236,9 @@ func (r *userRepository) FindByID(ctx context.Context, id string) (model.User, e
 // Create creates a new user
 func (r *userRepository) Create(ctx context.Context, user model.User) (model.User, error) {
 	query := `
-		INSERT INTO users (id, name, email, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, name, email, created_at, updated_at
-	`
+		INSERT INTO users (id, name, email, role, version, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, 1, $5, $6)
+		RETURNING ` + userColumns
 
 	// Generate ID if not provided
 	if user.ID == "" {
@@ -97,54 +250,211 @@ func (r *userRepository) Create(ctx context.Context, user model.User) (model.Use
 	user.CreatedAt = now
 	user.UpdatedAt = now
 
-	err := r.db.Pool.QueryRow(
-		ctx, query, user.ID, user.Name, user.Email, user.CreatedAt, user.UpdatedAt,
-	).Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	createdUser, err := scanUser(r.db.Conn(ctx).QueryRow(
+		ctx, query, user.ID, user.Name, user.Email, string(user.Role), user.CreatedAt, user.UpdatedAt,
+	))
 	if err != nil {
+		if isUniqueViolation(err) {
+			return model.User{}, repository.ErrConflict
+		}
 		return model.User{}, err
 	}
 
-	return user, nil
+	return createdUser, nil
 }
 
-// Update updates a user
-func (r *userRepository) Update(ctx context.Context, user model.User) error {
+// Update updates a user if its version matches the stored version. Changing
+// the email clears its verification.
+func (r *userRepository) Update(ctx context.Context, user model.User) (model.User, error) {
 	query := `
 		UPDATE users
-		SET name = $1, email = $2, updated_at = $3
-		WHERE id = $4
-	`
+		SET name = $1, email = $2, updated_at = $3, version = version + 1,
+			email_verified_at = CASE WHEN email = $2 THEN email_verified_at END
+		WHERE id = $4 AND version = $5 AND deleted_at IS NULL
+		RETURNING ` + userColumns
 
 	// Update timestamp
 	user.UpdatedAt = time.Now()
 
-	result, err := r.db.Pool.Exec(ctx, query, user.Name, user.Email, user.UpdatedAt, user.ID)
+	updatedUser, err := scanUser(r.db.Conn(ctx).QueryRow(
+		ctx, query, user.Name, user.Email, user.UpdatedAt, user.ID, user.Version,
+	))
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return model.User{}, r.missOrMismatch(ctx, user.ID, false)
+		case isUniqueViolation(err):
+			return model.User{}, repository.ErrConflict
+		}
+		return model.User{}, err
+	}
+
+	return updatedUser, nil
+}
+
+// Patch updates the given fields of a user if its version matches the stored
+// version. Changing the email clears its verification.
+func (r *userRepository) Patch(ctx context.Context, patch repository.UserPatch) (model.User, error) {
+	// Only set the columns that changed
+	sets := []string{"updated_at = $1", "version = version + 1"}
+	args := []interface{}{time.Now()}
+	if patch.Name != nil {
+		args = append(args, *patch.Name)
+		sets = append(sets, fmt.Sprintf("name = $%d", len(args)))
+	}
+	if patch.Email != nil {
+		args = append(args, *patch.Email)
+		sets = append(sets, fmt.Sprintf("email = $%d", len(args)),
+			fmt.Sprintf("email_verified_at = CASE WHEN email = $%d THEN email_verified_at END", len(args)))
+	}
+	if patch.Role != nil {
+		args = append(args, string(*patch.Role))
+		sets = append(sets, fmt.Sprintf("role = $%d", len(args)))
+	}
+	args = append(args, patch.ID, patch.Version)
+
+	query := fmt.Sprintf(`
+		UPDATE users
+		SET %s
+		WHERE id = $%d AND version = $%d AND deleted_at IS NULL
+		RETURNING %s
+	`, strings.Join(sets, ", "), len(args)-1, len(args), userColumns)
+
+	user, err := scanUser(r.db.Conn(ctx).QueryRow(ctx, query, args...))
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return model.User{}, r.missOrMismatch(ctx, patch.ID, false)
+		case isUniqueViolation(err):
+			return model.User{}, repository.ErrConflict
+		}
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+// MarkEmailVerified records that an active user verified the email, if it
+// is still their email
+func (r *userRepository) MarkEmailVerified(ctx context.Context, id, email string, at time.Time) (model.User, error) {
+	query := `
+		UPDATE users
+		SET email_verified_at = $3, updated_at = $3, version = version + 1
+		WHERE id = $1 AND email = $2 AND deleted_at IS NULL
+		RETURNING ` + userColumns
+
+	return r.findOne(ctx, query, id, email, at)
+}
+
+// Delete soft-deletes a user, checking its version unless version is 0
+func (r *userRepository) Delete(ctx context.Context, id string, version int) error {
+	query := `
+		UPDATE users
+		SET deleted_at = $3, updated_at = $3, version = version + 1
+		WHERE id = $1 AND ($2 = 0 OR version = $2) AND deleted_at IS NULL
+	`
+
+	result, err := r.db.Conn(ctx).Exec(ctx, query, id, version, time.Now())
 	if err != nil {
 		return err
 	}
 
 	if result.RowsAffected() == 0 {
-		return repository.ErrNotFound
+		return r.missOrMismatch(ctx, id, false)
 	}
 
 	return nil
 }
 
-// Delete deletes a user
-func (r *userRepository) Delete(ctx context.Context, id string) error {
+// Restore restores a soft-deleted user
+func (r *userRepository) Restore(ctx context.Context, id string) (model.User, error) {
+	query := `
+		UPDATE users
+		SET deleted_at = NULL, updated_at = $2, version = version + 1
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING ` + userColumns
+
+	user, err := scanUser(r.db.Conn(ctx).QueryRow(ctx, query, id, time.Now()))
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return model.User{}, repository.ErrNotFound
+		case isUniqueViolation(err):
+			return model.User{}, repository.ErrConflict
+		}
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+// HardDelete permanently deletes a user, checking its version unless version is 0
+func (r *userRepository) HardDelete(ctx context.Context, id string, version int) error {
 	query := `
 		DELETE FROM users
-		WHERE id = $1
+		WHERE id = $1 AND ($2 = 0 OR version = $2)
 	`
 
-	result, err := r.db.Pool.Exec(ctx, query, id)
+	result, err := r.db.Conn(ctx).Exec(ctx, query, id, version)
 	if err != nil {
 		return err
 	}
 
 	if result.RowsAffected() == 0 {
-		return repository.ErrNotFound
+		return r.missOrMismatch(ctx, id, true)
 	}
 
 	return nil
 }
+
+// PurgeDeleted permanently deletes users soft-deleted before the given time
+func (r *userRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM users
+		WHERE deleted_at IS NOT NULL AND deleted_at < $1
+	`
+
+	result, err := r.db.Conn(ctx).Exec(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// missOrMismatch explains why a versioned write to id matched no rows
+func (r *userRepository) missOrMismatch(ctx context.Context, id string, includeDeleted bool) error {
+	query := "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)"
+	if includeDeleted {
+		query = "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)"
+	}
+
+	var exists bool
+	if err := r.db.Conn(ctx).QueryRow(ctx, query, id).Scan(&exists); err != nil {
+		return err
+	}
+
+	if exists {
+		return repository.ErrVersionMismatch
+	}
+	return repository.ErrNotFound
+}
+
+// PostgreSQL error codes for constraint violations
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+// isUniqueViolation reports whether err is a PostgreSQL unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// isForeignKeyViolation reports whether err is a PostgreSQL foreign key
+// violation, such as a row referring to a deleted user
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation
+}
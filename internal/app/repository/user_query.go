@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+// or does not match the requested sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// UserSortField is a field users can be sorted by
+type UserSortField string
+
+// Supported sort fields
+const (
+	SortByName      UserSortField = "name"
+	SortByEmail     UserSortField = "email"
+	SortByCreatedAt UserSortField = "created_at"
+)
+
+// Valid reports whether the sort field is supported
+func (f UserSortField) Valid() bool {
+	switch f {
+	case SortByName, SortByEmail, SortByCreatedAt:
+		return true
+	}
+	return false
+}
+
+// UserQuery describes which users to list and in which order.
+// When Cursor is set it takes precedence over Offset.
+type UserQuery struct {
+	Limit    int
+	Offset   int
+	Cursor   string
+	SortBy   UserSortField
+	SortDesc bool
+
+	// Filters
+	NamePrefix     string
+	CreatedAfter   time.Time
+	IncludeDeleted bool
+}
+
+// UserPage is a single page of users
+type UserPage struct {
+	Users      []model.User
+	Total      int
+	NextCursor string
+}
+
+// UserCursor is the decoded position of the last user on a page
+type UserCursor struct {
+	SortBy   UserSortField `json:"s"`
+	SortDesc bool          `json:"d"`
+	Value    string        `json:"v"`
+	ID       string        `json:"id"`
+}
+
+// NewUserCursor returns the opaque cursor pointing just after user
+func NewUserCursor(query UserQuery, user model.User) string {
+	cursor := UserCursor{
+		SortBy:   query.SortBy,
+		SortDesc: query.SortDesc,
+		Value:    UserSortValue(query.SortBy, user),
+		ID:       user.ID,
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeUserCursor decodes the query cursor and checks it matches the query sort order
+func DecodeUserCursor(query UserQuery) (UserCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(query.Cursor)
+	if err != nil {
+		return UserCursor{}, ErrInvalidCursor
+	}
+
+	var cursor UserCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return UserCursor{}, ErrInvalidCursor
+	}
+
+	if cursor.SortBy != query.SortBy || cursor.SortDesc != query.SortDesc || cursor.ID == "" {
+		return UserCursor{}, ErrInvalidCursor
+	}
+
+	if cursor.SortBy == SortByCreatedAt {
+		if _, err := time.Parse(time.RFC3339Nano, cursor.Value); err != nil {
+			return UserCursor{}, ErrInvalidCursor
+		}
+	}
+
+	return cursor, nil
+}
+
+// UserSortValue returns the string form of the sort field of user
+func UserSortValue(field UserSortField, user model.User) string {
+	switch field {
+	case SortByName:
+		return user.Name
+	case SortByEmail:
+		return user.Email
+	default:
+		return user.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+}
@@ -3,20 +3,58 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/ThePotatoVerse/internal/app/model"
 )
 
 // Common errors
 var (
-	ErrNotFound = errors.New("not found")
+	ErrNotFound        = errors.New("not found")
+	ErrConflict        = errors.New("conflict")
+	ErrVersionMismatch = errors.New("version mismatch")
 )
 
-// UserRepository defines the interface for user data access
+// UserPatch describes a partial update of a user; nil fields are left unchanged
+type UserPatch struct {
+	ID      string
+	Version int
+	Name    *string
+	Email   *string
+	Role    *model.Role
+}
+
+// UserRepository defines the interface for user data access. Update writes
+// the name and email only; roles are changed with Patch. Update and Patch
+// clear the email verification when they change the email, and
+// MarkEmailVerified sets it, returning ErrNotFound if the active user no
+// longer has the verified email.
+// Create, Update, Patch and Restore return ErrConflict when another active
+// user already has the same email, compared case-insensitively.
+//
+// Update and Patch only succeed when the given version matches the stored
+// version and increment it; Delete and HardDelete check the version unless it
+// is 0. They return ErrVersionMismatch when the stored user has changed.
+//
+// Delete soft-deletes a user. Soft-deleted users are hidden from FindAll
+// (unless UserQuery.IncludeDeleted is set) and FindByID, and cannot be
+// modified until restored. FindByEmail only finds active users, comparing
+// emails case-insensitively.
+//
+// Methods take part in the transaction carried by ctx, if any.
+// FindByIDForUpdate additionally locks the user until that transaction ends.
 type UserRepository interface {
-	FindAll(ctx context.Context) ([]model.User, error)
+	FindAll(ctx context.Context, query UserQuery) (UserPage, error)
 	FindByID(ctx context.Context, id string) (model.User, error)
+	FindByIDForUpdate(ctx context.Context, id string) (model.User, error)
+	FindByIDWithDeleted(ctx context.Context, id string) (model.User, error)
+	FindByEmail(ctx context.Context, email string) (model.User, error)
 	Create(ctx context.Context, user model.User) (model.User, error)
-	Update(ctx context.Context, user model.User) error
-	Delete(ctx context.Context, id string) error
+	Update(ctx context.Context, user model.User) (model.User, error)
+	Patch(ctx context.Context, patch UserPatch) (model.User, error)
+	MarkEmailVerified(ctx context.Context, id, email string, at time.Time) (model.User, error)
+	Delete(ctx context.Context, id string, version int) error
+	Restore(ctx context.Context, id string) (model.User, error)
+	HardDelete(ctx context.Context, id string, version int) error
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
 }
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/internal/pkg/validator"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// ErrAPIKeyNotFound is returned when a user has no API key with the given ID
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// API keys look like tpv_<prefix>_<secret>. The prefix identifies the key,
+// the secret proves possession.
+const (
+	apiKeyMarker      = "tpv"
+	apiKeyPrefixBytes = 6
+	apiKeySecretBytes = 32
+)
+
+// lastUsedResolution is how stale the recorded last use of a key may get,
+// so that busy keys are not written on every request
+const lastUsedResolution = time.Minute
+
+// IsAPIKey reports whether a bearer token is an API key rather than a JWT
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyMarker+"_")
+}
+
+// NewAPIKey describes an API key to create
+type NewAPIKey struct {
+	Name      string
+	Scopes    []Action
+	ExpiresAt *time.Time
+}
+
+// CreatedAPIKey is a newly created API key together with its secret value,
+// which cannot be retrieved again
+type CreatedAPIKey struct {
+	model.APIKey
+	Key string
+}
+
+// APIKeyService defines the interface for personal API keys. Keys act with
+// the role of their user, limited to their scopes if they have any.
+type APIKeyService interface {
+	Create(ctx context.Context, userID string, input NewAPIKey) (CreatedAPIKey, error)
+	List(ctx context.Context, userID string) ([]model.APIKey, error)
+	Revoke(ctx context.Context, userID, id string) error
+	Authenticate(ctx context.Context, key string) (Principal, error)
+}
+
+// apiKeyService implements APIKeyService
+type apiKeyService struct {
+	log       logger.Logger
+	userRepo  repository.UserRepository
+	apiKeys   repository.APIKeyRepository
+	validator *validator.Validator
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(log logger.Logger, userRepo repository.UserRepository, apiKeys repository.APIKeyRepository, validator *validator.Validator) APIKeyService {
+	return &apiKeyService{
+		log:       log,
+		userRepo:  userRepo,
+		apiKeys:   apiKeys,
+		validator: validator,
+	}
+}
+
+// Create creates an API key for a user. A principal limited to scopes can
+// only create keys limited to a subset of them, so that keys cannot be used
+// to widen their own access.
+func (s *apiKeyService) Create(ctx context.Context, userID string, input NewAPIKey) (CreatedAPIKey, error) {
+	s.log.FromContext(ctx).Info("Creating API key", "user_id", userID)
+
+	now := time.Now()
+
+	// Validate input
+	check := s.validator.Check()
+	input.Name = check.Name("name", input.Name)
+	for i, scope := range input.Scopes {
+		if !scope.Valid() {
+			check.Fail(fmt.Sprintf("scopes[%d]", i), "must be a known action")
+		}
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		check.Fail("expires_at", "must be in the future")
+	}
+	if err := check.Err(); err != nil {
+		return CreatedAPIKey{}, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+
+	if principal, ok := PrincipalFromContext(ctx); ok && principal.Scopes != nil {
+		if len(input.Scopes) == 0 {
+			return CreatedAPIKey{}, ErrForbidden
+		}
+		for _, scope := range input.Scopes {
+			if !principal.HasScope(scope) {
+				return CreatedAPIKey{}, ErrForbidden
+			}
+		}
+	}
+
+	// Check if user exists
+	if _, err := s.userRepo.FindByID(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return CreatedAPIKey{}, ErrUserNotFound
+		}
+		return CreatedAPIKey{}, err
+	}
+
+	prefix, secret, err := newAPIKeySecret()
+	if err != nil {
+		return CreatedAPIKey{}, err
+	}
+	value := apiKeyMarker + "_" + prefix + "_" + secret
+
+	scopes := make([]string, len(input.Scopes))
+	for i, scope := range input.Scopes {
+		scopes[i] = string(scope)
+	}
+
+	key := model.APIKey{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      input.Name,
+		Prefix:    prefix,
+		KeyHash:   hashToken(value),
+		Scopes:    scopes,
+		CreatedAt: now,
+		ExpiresAt: input.ExpiresAt,
+	}
+	if err := s.apiKeys.Create(ctx, key); err != nil {
+		// Prefixes are random enough that a collision is an internal error
+		if errors.Is(err, repository.ErrConflict) {
+			return CreatedAPIKey{}, errors.New("api key prefix collision")
+		}
+		return CreatedAPIKey{}, err
+	}
+
+	return CreatedAPIKey{APIKey: key, Key: value}, nil
+}
+
+// List returns the API keys of a user
+func (s *apiKeyService) List(ctx context.Context, userID string) ([]model.APIKey, error) {
+	s.log.FromContext(ctx).Info("Listing API keys", "user_id", userID)
+
+	return s.apiKeys.ListByUser(ctx, userID)
+}
+
+// Revoke deletes an API key of a user
+func (s *apiKeyService) Revoke(ctx context.Context, userID, id string) error {
+	s.log.FromContext(ctx).Info("Revoking API key", "user_id", userID, "id", id)
+
+	err := s.apiKeys.Delete(ctx, userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrAPIKeyNotFound
+	}
+	return err
+}
+
+// Authenticate resolves an API key to the principal it acts as
+func (s *apiKeyService) Authenticate(ctx context.Context, value string) (Principal, error) {
+	parts := strings.SplitN(value, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyMarker {
+		return Principal{}, ErrInvalidToken
+	}
+
+	key, err := s.apiKeys.FindByPrefix(ctx, parts[1])
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return Principal{}, ErrInvalidToken
+		}
+		return Principal{}, err
+	}
+
+	now := time.Now()
+	if subtle.ConstantTimeCompare([]byte(hashToken(value)), []byte(key.KeyHash)) != 1 || key.Expired(now) {
+		return Principal{}, ErrInvalidToken
+	}
+
+	// Keys of deleted users no longer authenticate
+	user, err := s.userRepo.FindByID(ctx, key.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return Principal{}, ErrInvalidToken
+		}
+		return Principal{}, err
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		if err := s.apiKeys.TouchLastUsed(ctx, key.ID, now); err != nil {
+			// The request can proceed without an up to date timestamp
+			s.log.FromContext(ctx).Warn("Failed to record API key use", "id", key.ID, "error", err)
+		}
+	}
+
+	principal := Principal{
+		UserID:   user.ID,
+		Role:     user.Role,
+		Method:   AuthMethodAPIKey,
+		APIKeyID: key.ID,
+	}
+	if len(key.Scopes) > 0 {
+		principal.Scopes = make([]Action, len(key.Scopes))
+		for i, scope := range key.Scopes {
+			principal.Scopes[i] = Action(scope)
+		}
+	}
+
+	return principal, nil
+}
+
+// newAPIKeySecret returns a random hex prefix and a random URL-safe secret
+func newAPIKeySecret() (prefix, secret string, err error) {
+	b := make([]byte, apiKeyPrefixBytes+apiKeySecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(b[:apiKeyPrefixBytes]), base64.RawURLEncoding.EncodeToString(b[apiKeyPrefixBytes:]), nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// apiKeyServiceMetrics decorates an APIKeyService with per-operation counters
+type apiKeyServiceMetrics struct {
+	next       APIKeyService
+	operations *prometheus.CounterVec
+}
+
+// NewAPIKeyServiceMetrics wraps next so that every call is counted by
+// operation and result in api_key_service_operations_total
+func NewAPIKeyServiceMetrics(next APIKeyService, registerer prometheus.Registerer) APIKeyService {
+	operations := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "api_key_service_operations_total",
+		Help: "Total number of API key service operations by operation and result.",
+	}, []string{"operation", "result"})
+
+	registerer.MustRegister(operations)
+
+	return &apiKeyServiceMetrics{
+		next:       next,
+		operations: operations,
+	}
+}
+
+// Create implements APIKeyService
+func (m *apiKeyServiceMetrics) Create(ctx context.Context, userID string, input NewAPIKey) (CreatedAPIKey, error) {
+	key, err := m.next.Create(ctx, userID, input)
+	observeOperation(m.operations, "create", err)
+	return key, err
+}
+
+// List implements APIKeyService
+func (m *apiKeyServiceMetrics) List(ctx context.Context, userID string) ([]model.APIKey, error) {
+	keys, err := m.next.List(ctx, userID)
+	observeOperation(m.operations, "list", err)
+	return keys, err
+}
+
+// Revoke implements APIKeyService
+func (m *apiKeyServiceMetrics) Revoke(ctx context.Context, userID, id string) error {
+	err := m.next.Revoke(ctx, userID, id)
+	observeOperation(m.operations, "revoke", err)
+	return err
+}
+
+// Authenticate implements APIKeyService
+func (m *apiKeyServiceMetrics) Authenticate(ctx context.Context, key string) (Principal, error) {
+	principal, err := m.next.Authenticate(ctx, key)
+	observeOperation(m.operations, "authenticate", err)
+	return principal, err
+}
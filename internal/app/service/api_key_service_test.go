@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository/memory"
+	"github.com/ThePotatoVerse/internal/pkg/config"
+	"github.com/ThePotatoVerse/internal/pkg/validator"
+	"github.com/ThePotatoVerse/pkg/logger"
+)
+
+// TestCreateAPIKeyScopes checks that keys can only be created with scopes
+// the caller holds, so that a scoped key cannot widen its own access
+func TestCreateAPIKeyScopes(t *testing.T) {
+	tests := []struct {
+		name string
+		// callerScopes are the scopes of the key making the request; nil
+		// means a caller without scope limits, such as a session
+		callerScopes []Action
+		scopes       []Action
+		wantErr      error
+	}{
+		{
+			name:   "unscoped caller, unscoped key",
+			scopes: nil,
+		},
+		{
+			name:   "unscoped caller, scoped key",
+			scopes: []Action{ActionReadUser},
+		},
+		{
+			name:         "same scopes",
+			callerScopes: []Action{ActionReadUser, ActionCreateAPIKey},
+			scopes:       []Action{ActionReadUser, ActionCreateAPIKey},
+		},
+		{
+			name:         "narrower scopes",
+			callerScopes: []Action{ActionReadUser, ActionCreateAPIKey},
+			scopes:       []Action{ActionReadUser},
+		},
+		{
+			name:         "wider scopes",
+			callerScopes: []Action{ActionCreateAPIKey},
+			scopes:       []Action{ActionCreateAPIKey, ActionUpdateUser},
+			wantErr:      ErrForbidden,
+		},
+		{
+			name:         "scoped caller, unscoped key",
+			callerScopes: []Action{ActionCreateAPIKey},
+			scopes:       nil,
+			wantErr:      ErrForbidden,
+		},
+		{
+			name:    "unknown scope",
+			scopes:  []Action{"users:everything"},
+			wantErr: ErrInvalidInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, user := newTestAPIKeyService(t)
+
+			ctx := ContextWithPrincipal(context.Background(), Principal{
+				UserID: user.ID,
+				Role:   user.Role,
+				Method: AuthMethodAPIKey,
+				Scopes: tt.callerScopes,
+			})
+
+			created, err := s.Create(ctx, user.ID, NewAPIKey{Name: "Deploy key", Scopes: tt.scopes})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			// The new key acts with exactly the requested scopes
+			principal, err := s.Authenticate(context.Background(), created.Key)
+			if err != nil {
+				t.Fatalf("authenticating the new key: %v", err)
+			}
+			if !slices.Equal(principal.Scopes, tt.scopes) {
+				t.Errorf("got scopes %v, want %v", principal.Scopes, tt.scopes)
+			}
+		})
+	}
+}
+
+// newTestAPIKeyService creates an API key service backed by memory
+// repositories, together with a user to create keys for
+func newTestAPIKeyService(t *testing.T) (APIKeyService, model.User) {
+	t.Helper()
+
+	tx := memory.NewTxManager()
+	userRepo := memory.NewUserRepository(tx)
+	user, err := userRepo.Create(context.Background(), model.User{Name: "Ada", Email: "ada@example.com", Role: model.RoleMember})
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+
+	validator := validator.New(&config.ValidationConfig{NameMinLength: 1, NameMaxLength: 100, PasswordMinLength: 8})
+	return NewAPIKeyService(logger.New(), userRepo, memory.NewAPIKeyRepository(tx), validator), user
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// apiKeyServiceTracing decorates an APIKeyService with a span per method.
+// Key values are never recorded.
+type apiKeyServiceTracing struct {
+	next   APIKeyService
+	tracer trace.Tracer
+}
+
+// NewAPIKeyServiceTracing wraps next so that every call runs in a child span
+func NewAPIKeyServiceTracing(next APIKeyService) APIKeyService {
+	return &apiKeyServiceTracing{
+		next:   next,
+		tracer: otel.Tracer(tracerName),
+	}
+}
+
+// start starts the span of an operation
+func (t *apiKeyServiceTracing) start(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, "APIKeyService."+operation, trace.WithAttributes(attrs...))
+}
+
+// Create implements APIKeyService
+func (t *apiKeyServiceTracing) Create(ctx context.Context, userID string, input NewAPIKey) (CreatedAPIKey, error) {
+	ctx, span := t.start(ctx, "Create", attribute.String("user.id", userID))
+	key, err := t.next.Create(ctx, userID, input)
+	if err == nil {
+		span.SetAttributes(attribute.String("api_key.id", key.ID))
+	}
+	endSpan(span, err)
+	return key, err
+}
+
+// List implements APIKeyService
+func (t *apiKeyServiceTracing) List(ctx context.Context, userID string) ([]model.APIKey, error) {
+	ctx, span := t.start(ctx, "List", attribute.String("user.id", userID))
+	keys, err := t.next.List(ctx, userID)
+	span.SetAttributes(attribute.Int("api_keys.count", len(keys)))
+	endSpan(span, err)
+	return keys, err
+}
+
+// Revoke implements APIKeyService
+func (t *apiKeyServiceTracing) Revoke(ctx context.Context, userID, id string) error {
+	ctx, span := t.start(ctx, "Revoke",
+		attribute.String("user.id", userID),
+		attribute.String("api_key.id", id),
+	)
+	err := t.next.Revoke(ctx, userID, id)
+	endSpan(span, err)
+	return err
+}
+
+// Authenticate implements APIKeyService
+func (t *apiKeyServiceTracing) Authenticate(ctx context.Context, key string) (Principal, error) {
+	ctx, span := t.start(ctx, "Authenticate")
+	principal, err := t.next.Authenticate(ctx, key)
+	if err == nil {
+		span.SetAttributes(
+			attribute.String("user.id", principal.UserID),
+			attribute.String("user.role", string(principal.Role)),
+			attribute.String("api_key.id", principal.APIKeyID),
+		)
+	}
+	endSpan(span, err)
+	return principal, err
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+)
+
+// MFAChallenge is handed out instead of a session or tokens when a user with
+// two-factor authentication logs in. Its token completes the login together
+// with a second factor.
+type MFAChallenge struct {
+	// Token is handed to the client, and is only stored hashed
+	Token     string
+	ExpiresAt time.Time
+}
+
+// LoginWithMFA completes a login with an MFA challenge token and a TOTP or
+// recovery code, and starts a new session
+func (s *authService) LoginWithMFA(ctx context.Context, mfaToken, code string) (LoginResult, error) {
+	s.log.FromContext(ctx).Info("Completing login with second factor")
+
+	user, err := s.completeMFA(ctx, mfaToken, code)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	return s.startSession(ctx, user)
+}
+
+// IssueTokensWithMFA completes a login with an MFA challenge token and a
+// TOTP or recovery code, and issues a new token pair
+func (s *authService) IssueTokensWithMFA(ctx context.Context, mfaToken, code string) (TokenPair, error) {
+	s.log.FromContext(ctx).Info("Issuing tokens with second factor")
+
+	user, err := s.completeMFA(ctx, mfaToken, code)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return s.issueTokens(ctx, user)
+}
+
+// challengeMFA creates an MFA challenge for a user whose password was
+// checked, if they have two-factor authentication enabled. It returns nil if
+// they do not.
+func (s *authService) challengeMFA(ctx context.Context, user model.User) (*MFAChallenge, error) {
+	status, err := s.mfaService.Status(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !status.TOTPEnabled {
+		return nil, nil
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	challenge := model.MFAChallenge{
+		ID:        hashToken(token),
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.cfg.MFA.ChallengeTTL),
+	}
+	if err := s.mfaChallenges.Create(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	s.log.FromContext(ctx).Info("Second factor required", "user_id", user.ID)
+	return &MFAChallenge{Token: token, ExpiresAt: challenge.ExpiresAt}, nil
+}
+
+// completeMFA checks a second factor against an MFA challenge and returns
+// the user it was created for. The challenge is used up on success, and
+// after too many wrong codes.
+func (s *authService) completeMFA(ctx context.Context, mfaToken, code string) (model.User, error) {
+	if mfaToken == "" {
+		return model.User{}, ErrInvalidToken
+	}
+
+	challenge, err := s.mfaChallenges.FindByID(ctx, hashToken(mfaToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return model.User{}, ErrInvalidToken
+		}
+		return model.User{}, err
+	}
+	if challenge.Expired(time.Now()) {
+		return model.User{}, ErrInvalidToken
+	}
+
+	if err := s.mfaService.Verify(ctx, challenge.UserID, code); err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidMFACode):
+			return model.User{}, s.recordMFAFailure(ctx, challenge)
+		case errors.Is(err, ErrMFANotEnabled):
+			// The factor was removed since the challenge was created, so
+			// the login must start over
+			if err := s.mfaChallenges.Delete(ctx, challenge.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+				return model.User{}, err
+			}
+			return model.User{}, ErrInvalidToken
+		}
+		return model.User{}, err
+	}
+
+	// Deleting the challenge makes concurrent completions fail
+	if err := s.mfaChallenges.Delete(ctx, challenge.ID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return model.User{}, ErrInvalidToken
+		}
+		return model.User{}, err
+	}
+
+	// Deleted users can no longer log in
+	user, err := s.userRepo.FindByID(ctx, challenge.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return model.User{}, ErrInvalidToken
+		}
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+// recordMFAFailure counts a wrong code against an MFA challenge, deletes the
+// challenge once it has had too many, and returns the error to report
+func (s *authService) recordMFAFailure(ctx context.Context, challenge model.MFAChallenge) error {
+	attempts, err := s.mfaChallenges.RecordFailure(ctx, challenge.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	if attempts >= s.cfg.MFA.MaxAttempts {
+		s.log.FromContext(ctx).Warn("Too many wrong second factors, ending login",
+			"user_id", challenge.UserID, "attempts", attempts)
+		if err := s.mfaChallenges.Delete(ctx, challenge.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+	}
+
+	return ErrInvalidMFACode
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/pkg/mailer"
+	"github.com/ThePotatoVerse/pkg/password"
+)
+
+// ErrInvalidPasswordReset is returned for password reset tokens that are
+// unknown, expired or already used
+var ErrInvalidPasswordReset = errors.New("invalid or expired password reset token")
+
+// RequestPasswordReset emails a password reset token to the user with the
+// given email. To not reveal which emails are registered, it succeeds
+// whether or not there is such a user, and the email is sent in the
+// background. Requests beyond the configured limit per email are ignored.
+func (s *authService) RequestPasswordReset(ctx context.Context, email string) error {
+	s.log.FromContext(ctx).Info("Requesting password reset")
+
+	user, err := s.userRepo.FindByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	now := time.Now()
+	cfg := s.cfg.PasswordReset
+
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// Lock the user so that concurrent requests are counted one by one
+		user, err := s.userRepo.FindByIDForUpdate(ctx, user.ID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		recent, err := s.passwordResets.CountSince(ctx, user.ID, now.Add(-cfg.RequestWindow))
+		if err != nil {
+			return err
+		}
+		if recent >= cfg.MaxRequests {
+			s.log.FromContext(ctx).Warn("Password reset requests limited", "user_id", user.ID, "recent", recent)
+			return nil
+		}
+
+		reset := model.PasswordReset{
+			ID:        hashToken(token),
+			UserID:    user.ID,
+			CreatedAt: now,
+			ExpiresAt: now.Add(cfg.TokenTTL),
+		}
+		if err := s.passwordResets.Create(ctx, reset); err != nil {
+			return err
+		}
+
+		msg := mailer.Message{
+			To:      user.Email,
+			Subject: "Reset your password",
+			Body: fmt.Sprintf("Hello %s,\n\n"+
+				"Someone asked to reset the password of your account. To choose a new password, "+
+				"send this token with it to POST %s/api/v1/auth/password-reset/confirm:\n\n"+
+				"%s\n\n"+
+				"The token expires in %s. If you did not ask for a new password, you can ignore this email.\n",
+				user.Name, s.publicURL, token, cfg.TokenTTL),
+		}
+
+		// Only mail tokens that were stored. Sending in the background keeps
+		// the response time the same for unknown emails.
+		s.txManager.AfterCommit(ctx, func(ctx context.Context) {
+			go func(ctx context.Context) {
+				if err := s.mailer.Send(ctx, msg); err != nil {
+					s.log.FromContext(ctx).Error("Failed to send password reset email", "user_id", user.ID, "error", err)
+				}
+			}(context.WithoutCancel(ctx))
+		})
+		return nil
+	})
+}
+
+// ResetPassword sets a new password with a password reset token. The token
+// is used up, and every session and refresh token of the user is revoked.
+func (s *authService) ResetPassword(ctx context.Context, token, secret string) error {
+	s.log.FromContext(ctx).Info("Resetting password")
+
+	check := s.validator.Check()
+	check.Password("password", secret)
+	if err := check.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+
+	if token == "" {
+		return ErrInvalidPasswordReset
+	}
+
+	reset, err := s.passwordResets.FindByID(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidPasswordReset
+		}
+		return err
+	}
+	if reset.Expired(time.Now()) {
+		return ErrInvalidPasswordReset
+	}
+
+	// Hash outside the transaction, as it is deliberately slow
+	hash, err := password.Hash(secret)
+	if err != nil {
+		return err
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// Deleting the reset first makes concurrent uses fail
+		if err := s.passwordResets.Delete(ctx, reset.ID); err != nil {
+			return err
+		}
+
+		// Deleted users cannot reset their password
+		if _, err := s.userRepo.FindByIDForUpdate(ctx, reset.UserID); err != nil {
+			return err
+		}
+
+		if err := s.credentials.Upsert(ctx, model.Credential{UserID: reset.UserID, PasswordHash: hash}); err != nil {
+			return err
+		}
+
+		// Whoever knew the old password loses access, and other reset tokens
+		// can no longer be used
+		if _, err := s.sessions.DeleteByUser(ctx, reset.UserID); err != nil {
+			return err
+		}
+		if _, err := s.refreshTokens.DeleteByUser(ctx, reset.UserID); err != nil {
+			return err
+		}
+		_, err := s.passwordResets.DeleteByUser(ctx, reset.UserID)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidPasswordReset
+		}
+		return err
+	}
+
+	s.log.FromContext(ctx).Info("Reset password", "user_id", reset.UserID)
+	return nil
+}
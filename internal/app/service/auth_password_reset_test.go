@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+)
+
+// TestRequestPasswordResetLimit checks that at most MaxRequests resets are
+// created per RequestWindow. The test service allows 3 requests an hour,
+// and its tokens expire after 15 minutes.
+func TestRequestPasswordResetLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		// earlier resets were requested age ago, before the test requests
+		earlier int
+		age     time.Duration
+		// purge runs the cleanup job before the test requests
+		purge      bool
+		requests   int
+		concurrent bool
+		want       int
+	}{
+		{
+			name:     "under the limit",
+			requests: 2,
+			want:     2,
+		},
+		{
+			name:     "over the limit",
+			requests: 5,
+			want:     3,
+		},
+		{
+			name:       "concurrent requests over the limit",
+			requests:   10,
+			concurrent: true,
+			want:       3,
+		},
+		{
+			name:     "earlier requests in the window",
+			earlier:  2,
+			age:      30 * time.Minute,
+			requests: 3,
+			want:     1,
+		},
+		{
+			name:     "expired requests in the window survive cleanup",
+			earlier:  3,
+			age:      30 * time.Minute,
+			purge:    true,
+			requests: 1,
+			want:     0,
+		},
+		{
+			name:     "requests outside the window",
+			earlier:  3,
+			age:      2 * time.Hour,
+			purge:    true,
+			requests: 3,
+			want:     3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, user := newTestAuthService(t)
+			start := time.Now()
+
+			for i := 0; i < tt.earlier; i++ {
+				createdAt := start.Add(-tt.age)
+				err := s.passwordResets.Create(ctx, model.PasswordReset{
+					ID:        hashToken(fmt.Sprintf("earlier-%d", i)),
+					UserID:    user.ID,
+					CreatedAt: createdAt,
+					ExpiresAt: createdAt.Add(s.cfg.PasswordReset.TokenTTL),
+				})
+				if err != nil {
+					t.Fatalf("creating earlier reset: %v", err)
+				}
+			}
+
+			if tt.purge {
+				if _, err := s.PurgeExpiredSessions(ctx); err != nil {
+					t.Fatalf("purging: %v", err)
+				}
+			}
+
+			var wg sync.WaitGroup
+			for i := 0; i < tt.requests; i++ {
+				request := func() {
+					defer wg.Done()
+					if err := s.RequestPasswordReset(ctx, user.Email); err != nil {
+						t.Errorf("requesting reset: %v", err)
+					}
+				}
+
+				wg.Add(1)
+				if tt.concurrent {
+					go request()
+				} else {
+					request()
+				}
+			}
+			wg.Wait()
+
+			got, err := s.passwordResets.CountSince(ctx, user.ID, start)
+			if err != nil {
+				t.Fatalf("counting resets: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %d new resets, want %d", got, tt.want)
+			}
+		})
+	}
+}
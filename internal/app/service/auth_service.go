@@ -0,0 +1,404 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/internal/pkg/config"
+	"github.com/ThePotatoVerse/internal/pkg/validator"
+	"github.com/ThePotatoVerse/pkg/database"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/ThePotatoVerse/pkg/mailer"
+	"github.com/ThePotatoVerse/pkg/password"
+	"github.com/ThePotatoVerse/pkg/token"
+)
+
+// Authentication errors
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrUnauthenticated    = errors.New("authentication required")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+)
+
+// tokenBytes is the amount of randomness in session, refresh, password reset
+// and MFA tokens
+const tokenBytes = 32
+
+// LoginResult is the outcome of a successful login
+type LoginResult struct {
+	User model.User
+
+	// Token is handed to the client, and is only stored hashed
+	Token     string
+	ExpiresAt time.Time
+
+	// MFA is set instead of Token when the user must also give a second
+	// factor to LoginWithMFA
+	MFA *MFAChallenge
+}
+
+// AuthService defines the interface for password authentication, sessions
+// and JWT access tokens.
+//
+// IssueTokens exchanges a password for an access token and a refresh token.
+// Refresh tokens are single-use: RefreshTokens rotates them, and presenting
+// a token that has already been rotated revokes every token descended from
+// the same login.
+//
+// Users with two-factor authentication get an MFA challenge instead of a
+// session or tokens, which LoginWithMFA and IssueTokensWithMFA complete with
+// a TOTP or recovery code.
+//
+// LoginExternal logs in a user whose identity an external provider vouched
+// for, without a password.
+//
+// RequestPasswordReset emails a single-use token that ResetPassword accepts
+// in place of the old password. PurgeExpiredSessions also purges expired
+// refresh tokens, password resets and MFA challenges.
+type AuthService interface {
+	Register(ctx context.Context, user model.User, secret string) (model.User, error)
+	Login(ctx context.Context, email, secret string) (LoginResult, error)
+	LoginWithMFA(ctx context.Context, mfaToken, code string) (LoginResult, error)
+	LoginExternal(ctx context.Context, userID string) (LoginResult, error)
+	Logout(ctx context.Context, token string) error
+	Authenticate(ctx context.Context, token string) (Principal, error)
+	IssueTokens(ctx context.Context, email, secret string) (TokenPair, error)
+	IssueTokensWithMFA(ctx context.Context, mfaToken, code string) (TokenPair, error)
+	RefreshTokens(ctx context.Context, refreshToken string) (TokenPair, error)
+	RevokeRefreshToken(ctx context.Context, refreshToken string) error
+	AuthenticateAccessToken(ctx context.Context, accessToken string) (Principal, error)
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, secret string) error
+	PurgeExpiredSessions(ctx context.Context) (int64, error)
+}
+
+// authService implements AuthService
+type authService struct {
+	log            logger.Logger
+	userService    UserService
+	mfaService     MFAService
+	userRepo       repository.UserRepository
+	credentials    repository.CredentialRepository
+	sessions       repository.SessionRepository
+	refreshTokens  repository.RefreshTokenRepository
+	passwordResets repository.PasswordResetRepository
+	mfaChallenges  repository.MFAChallengeRepository
+	txManager      database.TxManager
+	validator      *validator.Validator
+	keys           *token.KeySet
+	mailer         mailer.Mailer
+	publicURL      string
+	cfg            *config.AuthConfig
+
+	dummyHashOnce sync.Once
+	dummyHash     string
+}
+
+// NewAuthService creates a new auth service. Users are created through
+// userService, so registration follows the same rules as user creation, and
+// second factors are checked by mfaService. Password reset emails refer to
+// the API at publicURL.
+func NewAuthService(
+	log logger.Logger,
+	userService UserService,
+	mfaService MFAService,
+	userRepo repository.UserRepository,
+	credentials repository.CredentialRepository,
+	sessions repository.SessionRepository,
+	refreshTokens repository.RefreshTokenRepository,
+	passwordResets repository.PasswordResetRepository,
+	mfaChallenges repository.MFAChallengeRepository,
+	txManager database.TxManager,
+	validator *validator.Validator,
+	keys *token.KeySet,
+	mailer mailer.Mailer,
+	publicURL string,
+	cfg *config.AuthConfig,
+) AuthService {
+	return &authService{
+		log:            log,
+		userService:    userService,
+		mfaService:     mfaService,
+		userRepo:       userRepo,
+		credentials:    credentials,
+		sessions:       sessions,
+		refreshTokens:  refreshTokens,
+		passwordResets: passwordResets,
+		mfaChallenges:  mfaChallenges,
+		txManager:      txManager,
+		validator:      validator,
+		keys:           keys,
+		mailer:         mailer,
+		publicURL:      strings.TrimSuffix(publicURL, "/"),
+		cfg:            cfg,
+	}
+}
+
+// Register creates a user together with its password credential
+func (s *authService) Register(ctx context.Context, user model.User, secret string) (model.User, error) {
+	s.log.FromContext(ctx).Info("Registering user")
+
+	// Registered users are members. Those with an email configured as an
+	// admin become admins once they verify it.
+	user.Role = model.RoleMember
+
+	// Validate every field at once, so that all errors are reported together
+	check := s.validator.Check()
+	check.Name("name", user.Name)
+	check.Email("email", user.Email)
+	check.Password("password", secret)
+	if err := check.Err(); err != nil {
+		return model.User{}, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+
+	// Hash outside the transaction, as it is deliberately slow
+	hash, err := password.Hash(secret)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	var createdUser model.User
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		createdUser, err = s.userService.Create(ctx, user)
+		if err != nil {
+			return err
+		}
+
+		return s.credentials.Upsert(ctx, model.Credential{
+			UserID:       createdUser.ID,
+			PasswordHash: hash,
+		})
+	})
+	if err != nil {
+		return model.User{}, err
+	}
+
+	return createdUser, nil
+}
+
+// Login checks an email and password and starts a new session, unless the
+// user must also give a second factor
+func (s *authService) Login(ctx context.Context, email, secret string) (LoginResult, error) {
+	s.log.FromContext(ctx).Info("Logging in")
+
+	user, err := s.checkPassword(ctx, email, secret)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	challenge, err := s.challengeMFA(ctx, user)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	if challenge != nil {
+		return LoginResult{User: user, MFA: challenge}, nil
+	}
+
+	return s.startSession(ctx, user)
+}
+
+// LoginExternal starts a new session for a user who logged in through an
+// external identity provider, unless the user must also give a second factor
+func (s *authService) LoginExternal(ctx context.Context, userID string) (LoginResult, error) {
+	s.log.FromContext(ctx).Info("Logging in through external identity", "user_id", userID)
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return LoginResult{}, ErrUserNotFound
+		}
+		return LoginResult{}, err
+	}
+
+	challenge, err := s.challengeMFA(ctx, user)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	if challenge != nil {
+		return LoginResult{User: user, MFA: challenge}, nil
+	}
+
+	return s.startSession(ctx, user)
+}
+
+// startSession starts a new session for a user whose credentials were checked
+func (s *authService) startSession(ctx context.Context, user model.User) (LoginResult, error) {
+	token, err := newToken()
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	now := time.Now()
+	session := model.Session{
+		ID:        hashToken(token),
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.cfg.SessionTTL),
+	}
+	if err := s.sessions.Create(ctx, session); err != nil {
+		return LoginResult{}, err
+	}
+
+	s.log.FromContext(ctx).Info("Logged in", "user_id", user.ID)
+
+	return LoginResult{
+		User:      user,
+		Token:     token,
+		ExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// Logout ends the session of token. Unknown sessions are ignored.
+func (s *authService) Logout(ctx context.Context, token string) error {
+	s.log.FromContext(ctx).Info("Logging out")
+
+	if token == "" {
+		return nil
+	}
+
+	err := s.sessions.Delete(ctx, hashToken(token))
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// Authenticate resolves a session token to the principal it belongs to
+func (s *authService) Authenticate(ctx context.Context, token string) (Principal, error) {
+	if token == "" {
+		return Principal{}, ErrUnauthenticated
+	}
+
+	session, err := s.sessions.FindByID(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return Principal{}, ErrUnauthenticated
+		}
+		return Principal{}, err
+	}
+
+	if session.Expired(time.Now()) {
+		return Principal{}, ErrUnauthenticated
+	}
+
+	// Sessions of deleted users no longer authenticate. The role is read on
+	// every request, so role changes apply immediately.
+	user, err := s.userRepo.FindByID(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return Principal{}, ErrUnauthenticated
+		}
+		return Principal{}, err
+	}
+
+	return Principal{
+		UserID:    user.ID,
+		Role:      user.Role,
+		Method:    AuthMethodSession,
+		SessionID: session.ID,
+	}, nil
+}
+
+// PurgeExpiredSessions deletes every expired session, refresh token,
+// password reset and MFA challenge
+func (s *authService) PurgeExpiredSessions(ctx context.Context) (int64, error) {
+	now := time.Now()
+
+	sessions, err := s.sessions.DeleteExpired(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	refreshTokens, err := s.refreshTokens.DeleteExpired(ctx, now)
+	if err != nil {
+		return sessions, err
+	}
+
+	// Expired resets still count towards the request limit until they leave
+	// its window
+	cfg := s.cfg.PasswordReset
+	passwordResets, err := s.passwordResets.DeleteExpired(ctx, now.Add(min(0, cfg.TokenTTL-cfg.RequestWindow)))
+	if err != nil {
+		return sessions + refreshTokens, err
+	}
+
+	mfaChallenges, err := s.mfaChallenges.DeleteExpired(ctx, now)
+	if err != nil {
+		return sessions + refreshTokens + passwordResets, err
+	}
+
+	s.log.FromContext(ctx).Info("Purged expired sessions",
+		"sessions", sessions, "refresh_tokens", refreshTokens, "password_resets", passwordResets,
+		"mfa_challenges", mfaChallenges)
+	return sessions + refreshTokens + passwordResets + mfaChallenges, nil
+}
+
+// checkPassword returns the active user with the given email, if secret is
+// their password
+func (s *authService) checkPassword(ctx context.Context, email, secret string) (model.User, error) {
+	user, err := s.userRepo.FindByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			s.burnHash(secret)
+			return model.User{}, ErrInvalidCredentials
+		}
+		return model.User{}, err
+	}
+
+	credential, err := s.credentials.Get(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			s.burnHash(secret)
+			return model.User{}, ErrInvalidCredentials
+		}
+		return model.User{}, err
+	}
+
+	ok, err := password.Verify(secret, credential.PasswordHash)
+	if err != nil {
+		return model.User{}, err
+	}
+	if !ok {
+		s.log.FromContext(ctx).Warn("Login failed", "user_id", user.ID)
+		return model.User{}, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// burnHash verifies secret against a dummy hash, so that logins for unknown
+// users take as long as logins with a wrong password
+func (s *authService) burnHash(secret string) {
+	s.dummyHashOnce.Do(func() {
+		s.dummyHash, _ = password.Hash("dummy password")
+	})
+	_, _ = password.Verify(secret, s.dummyHash)
+}
+
+// newToken returns a random, URL-safe session, refresh, password reset or MFA
+// token
+func newToken() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the ID a session, refresh, password reset or MFA token,
+// or a recovery code, is stored under
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
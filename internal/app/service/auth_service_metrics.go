@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// authServiceMetrics decorates an AuthService with per-operation counters
+type authServiceMetrics struct {
+	next       AuthService
+	operations *prometheus.CounterVec
+}
+
+// NewAuthServiceMetrics wraps next so that every call is counted by
+// operation and result in auth_service_operations_total
+func NewAuthServiceMetrics(next AuthService, registerer prometheus.Registerer) AuthService {
+	operations := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_service_operations_total",
+		Help: "Total number of auth service operations by operation and result.",
+	}, []string{"operation", "result"})
+
+	registerer.MustRegister(operations)
+
+	return &authServiceMetrics{
+		next:       next,
+		operations: operations,
+	}
+}
+
+// Register implements AuthService
+func (m *authServiceMetrics) Register(ctx context.Context, user model.User, secret string) (model.User, error) {
+	user, err := m.next.Register(ctx, user, secret)
+	observeOperation(m.operations, "register", err)
+	return user, err
+}
+
+// Login implements AuthService
+func (m *authServiceMetrics) Login(ctx context.Context, email, secret string) (LoginResult, error) {
+	result, err := m.next.Login(ctx, email, secret)
+	observeOperation(m.operations, "login", err)
+	return result, err
+}
+
+// LoginExternal implements AuthService
+func (m *authServiceMetrics) LoginExternal(ctx context.Context, userID string) (LoginResult, error) {
+	result, err := m.next.LoginExternal(ctx, userID)
+	observeOperation(m.operations, "login_external", err)
+	return result, err
+}
+
+// LoginWithMFA implements AuthService
+func (m *authServiceMetrics) LoginWithMFA(ctx context.Context, mfaToken, code string) (LoginResult, error) {
+	result, err := m.next.LoginWithMFA(ctx, mfaToken, code)
+	observeOperation(m.operations, "login_with_mfa", err)
+	return result, err
+}
+
+// Logout implements AuthService
+func (m *authServiceMetrics) Logout(ctx context.Context, token string) error {
+	err := m.next.Logout(ctx, token)
+	observeOperation(m.operations, "logout", err)
+	return err
+}
+
+// Authenticate implements AuthService
+func (m *authServiceMetrics) Authenticate(ctx context.Context, token string) (Principal, error) {
+	principal, err := m.next.Authenticate(ctx, token)
+	observeOperation(m.operations, "authenticate", err)
+	return principal, err
+}
+
+// IssueTokens implements AuthService
+func (m *authServiceMetrics) IssueTokens(ctx context.Context, email, secret string) (TokenPair, error) {
+	pair, err := m.next.IssueTokens(ctx, email, secret)
+	observeOperation(m.operations, "issue_tokens", err)
+	return pair, err
+}
+
+// IssueTokensWithMFA implements AuthService
+func (m *authServiceMetrics) IssueTokensWithMFA(ctx context.Context, mfaToken, code string) (TokenPair, error) {
+	pair, err := m.next.IssueTokensWithMFA(ctx, mfaToken, code)
+	observeOperation(m.operations, "issue_tokens_with_mfa", err)
+	return pair, err
+}
+
+// RefreshTokens implements AuthService
+func (m *authServiceMetrics) RefreshTokens(ctx context.Context, refreshToken string) (TokenPair, error) {
+	pair, err := m.next.RefreshTokens(ctx, refreshToken)
+	observeOperation(m.operations, "refresh_tokens", err)
+	return pair, err
+}
+
+// RevokeRefreshToken implements AuthService
+func (m *authServiceMetrics) RevokeRefreshToken(ctx context.Context, refreshToken string) error {
+	err := m.next.RevokeRefreshToken(ctx, refreshToken)
+	observeOperation(m.operations, "revoke_refresh_token", err)
+	return err
+}
+
+// AuthenticateAccessToken implements AuthService
+func (m *authServiceMetrics) AuthenticateAccessToken(ctx context.Context, accessToken string) (Principal, error) {
+	principal, err := m.next.AuthenticateAccessToken(ctx, accessToken)
+	observeOperation(m.operations, "authenticate_access_token", err)
+	return principal, err
+}
+
+// RequestPasswordReset implements AuthService
+func (m *authServiceMetrics) RequestPasswordReset(ctx context.Context, email string) error {
+	err := m.next.RequestPasswordReset(ctx, email)
+	observeOperation(m.operations, "request_password_reset", err)
+	return err
+}
+
+// ResetPassword implements AuthService
+func (m *authServiceMetrics) ResetPassword(ctx context.Context, token, secret string) error {
+	err := m.next.ResetPassword(ctx, token, secret)
+	observeOperation(m.operations, "reset_password", err)
+	return err
+}
+
+// PurgeExpiredSessions implements AuthService
+func (m *authServiceMetrics) PurgeExpiredSessions(ctx context.Context) (int64, error) {
+	purged, err := m.next.PurgeExpiredSessions(ctx)
+	observeOperation(m.operations, "purge_expired_sessions", err)
+	return purged, err
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository/memory"
+	"github.com/ThePotatoVerse/internal/pkg/config"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/ThePotatoVerse/pkg/mailer"
+	"github.com/ThePotatoVerse/pkg/token"
+)
+
+// newTestAuthService creates an auth service backed by memory repositories,
+// together with a user to authenticate
+func newTestAuthService(t *testing.T) (*authService, model.User) {
+	t.Helper()
+
+	log := logger.New()
+	cfg := &config.AuthConfig{
+		JWT: config.JWTConfig{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: time.Hour,
+		},
+		PasswordReset: config.PasswordResetConfig{
+			TokenTTL:      15 * time.Minute,
+			MaxRequests:   3,
+			RequestWindow: time.Hour,
+		},
+	}
+
+	keys, err := token.LoadKeySet(&cfg.JWT, log)
+	if err != nil {
+		t.Fatalf("loading keys: %v", err)
+	}
+
+	tx := memory.NewTxManager()
+	userRepo := memory.NewUserRepository(tx)
+	user, err := userRepo.Create(context.Background(), model.User{Name: "Ada", Email: "ada@example.com"})
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+
+	return &authService{
+		log:            log,
+		userRepo:       userRepo,
+		sessions:       memory.NewSessionRepository(tx),
+		refreshTokens:  memory.NewRefreshTokenRepository(tx),
+		passwordResets: memory.NewPasswordResetRepository(tx),
+		mfaChallenges:  memory.NewMFAChallengeRepository(tx),
+		txManager:      tx,
+		keys:           keys,
+		mailer:         discardMailer{},
+		cfg:            cfg,
+	}, user
+}
+
+// discardMailer drops every message
+type discardMailer struct{}
+
+// Send does nothing
+func (discardMailer) Send(context.Context, mailer.Message) error {
+	return nil
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// authServiceTracing decorates an AuthService with a span per method.
+// Credentials and tokens are never recorded.
+type authServiceTracing struct {
+	next   AuthService
+	tracer trace.Tracer
+}
+
+// NewAuthServiceTracing wraps next so that every call runs in a child span
+func NewAuthServiceTracing(next AuthService) AuthService {
+	return &authServiceTracing{
+		next:   next,
+		tracer: otel.Tracer(tracerName),
+	}
+}
+
+// start starts the span of an operation
+func (t *authServiceTracing) start(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, "AuthService."+operation, trace.WithAttributes(attrs...))
+}
+
+// Register implements AuthService
+func (t *authServiceTracing) Register(ctx context.Context, user model.User, secret string) (model.User, error) {
+	ctx, span := t.start(ctx, "Register")
+	user, err := t.next.Register(ctx, user, secret)
+	if err == nil {
+		span.SetAttributes(attribute.String("user.id", user.ID))
+	}
+	endSpan(span, err)
+	return user, err
+}
+
+// Login implements AuthService
+func (t *authServiceTracing) Login(ctx context.Context, email, secret string) (LoginResult, error) {
+	ctx, span := t.start(ctx, "Login")
+	result, err := t.next.Login(ctx, email, secret)
+	if err == nil {
+		span.SetAttributes(
+			attribute.String("user.id", result.User.ID),
+			attribute.Bool("auth.mfa_required", result.MFA != nil),
+		)
+	}
+	endSpan(span, err)
+	return result, err
+}
+
+// LoginExternal implements AuthService
+func (t *authServiceTracing) LoginExternal(ctx context.Context, userID string) (LoginResult, error) {
+	ctx, span := t.start(ctx, "LoginExternal")
+	span.SetAttributes(attribute.String("user.id", userID))
+	result, err := t.next.LoginExternal(ctx, userID)
+	if err == nil {
+		span.SetAttributes(attribute.Bool("auth.mfa_required", result.MFA != nil))
+	}
+	endSpan(span, err)
+	return result, err
+}
+
+// LoginWithMFA implements AuthService
+func (t *authServiceTracing) LoginWithMFA(ctx context.Context, mfaToken, code string) (LoginResult, error) {
+	ctx, span := t.start(ctx, "LoginWithMFA")
+	result, err := t.next.LoginWithMFA(ctx, mfaToken, code)
+	if err == nil {
+		span.SetAttributes(attribute.String("user.id", result.User.ID))
+	}
+	endSpan(span, err)
+	return result, err
+}
+
+// Logout implements AuthService
+func (t *authServiceTracing) Logout(ctx context.Context, token string) error {
+	ctx, span := t.start(ctx, "Logout")
+	err := t.next.Logout(ctx, token)
+	endSpan(span, err)
+	return err
+}
+
+// Authenticate implements AuthService
+func (t *authServiceTracing) Authenticate(ctx context.Context, token string) (Principal, error) {
+	ctx, span := t.start(ctx, "Authenticate")
+	principal, err := t.next.Authenticate(ctx, token)
+	if err == nil {
+		span.SetAttributes(
+			attribute.String("user.id", principal.UserID),
+			attribute.String("user.role", string(principal.Role)),
+			attribute.String("auth.method", principal.Method),
+		)
+	}
+	endSpan(span, err)
+	return principal, err
+}
+
+// IssueTokens implements AuthService
+func (t *authServiceTracing) IssueTokens(ctx context.Context, email, secret string) (TokenPair, error) {
+	ctx, span := t.start(ctx, "IssueTokens")
+	pair, err := t.next.IssueTokens(ctx, email, secret)
+	if err == nil {
+		span.SetAttributes(
+			attribute.String("user.id", pair.User.ID),
+			attribute.Bool("auth.mfa_required", pair.MFA != nil),
+		)
+	}
+	endSpan(span, err)
+	return pair, err
+}
+
+// IssueTokensWithMFA implements AuthService
+func (t *authServiceTracing) IssueTokensWithMFA(ctx context.Context, mfaToken, code string) (TokenPair, error) {
+	ctx, span := t.start(ctx, "IssueTokensWithMFA")
+	pair, err := t.next.IssueTokensWithMFA(ctx, mfaToken, code)
+	if err == nil {
+		span.SetAttributes(attribute.String("user.id", pair.User.ID))
+	}
+	endSpan(span, err)
+	return pair, err
+}
+
+// RefreshTokens implements AuthService
+func (t *authServiceTracing) RefreshTokens(ctx context.Context, refreshToken string) (TokenPair, error) {
+	ctx, span := t.start(ctx, "RefreshTokens")
+	pair, err := t.next.RefreshTokens(ctx, refreshToken)
+	if err == nil {
+		span.SetAttributes(attribute.String("user.id", pair.User.ID))
+	}
+	endSpan(span, err)
+	return pair, err
+}
+
+// RevokeRefreshToken implements AuthService
+func (t *authServiceTracing) RevokeRefreshToken(ctx context.Context, refreshToken string) error {
+	ctx, span := t.start(ctx, "RevokeRefreshToken")
+	err := t.next.RevokeRefreshToken(ctx, refreshToken)
+	endSpan(span, err)
+	return err
+}
+
+// AuthenticateAccessToken implements AuthService
+func (t *authServiceTracing) AuthenticateAccessToken(ctx context.Context, accessToken string) (Principal, error) {
+	ctx, span := t.start(ctx, "AuthenticateAccessToken")
+	principal, err := t.next.AuthenticateAccessToken(ctx, accessToken)
+	if err == nil {
+		span.SetAttributes(
+			attribute.String("user.id", principal.UserID),
+			attribute.String("user.role", string(principal.Role)),
+			attribute.String("auth.method", principal.Method),
+		)
+	}
+	endSpan(span, err)
+	return principal, err
+}
+
+// RequestPasswordReset implements AuthService
+func (t *authServiceTracing) RequestPasswordReset(ctx context.Context, email string) error {
+	ctx, span := t.start(ctx, "RequestPasswordReset")
+	err := t.next.RequestPasswordReset(ctx, email)
+	endSpan(span, err)
+	return err
+}
+
+// ResetPassword implements AuthService
+func (t *authServiceTracing) ResetPassword(ctx context.Context, token, secret string) error {
+	ctx, span := t.start(ctx, "ResetPassword")
+	err := t.next.ResetPassword(ctx, token, secret)
+	endSpan(span, err)
+	return err
+}
+
+// PurgeExpiredSessions implements AuthService
+func (t *authServiceTracing) PurgeExpiredSessions(ctx context.Context) (int64, error) {
+	ctx, span := t.start(ctx, "PurgeExpiredSessions")
+	purged, err := t.next.PurgeExpiredSessions(ctx)
+	span.SetAttributes(attribute.Int64("purged", purged))
+	endSpan(span, err)
+	return purged, err
+}
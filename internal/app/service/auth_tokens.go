@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TokenPair is an access token together with the refresh token that renews it
+type TokenPair struct {
+	User model.User
+
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+
+	// MFA is set instead of the tokens when the user must also give a second
+	// factor to IssueTokensWithMFA
+	MFA *MFAChallenge
+}
+
+// IssueTokens checks an email and password and issues a new token pair,
+// unless the user must also give a second factor
+func (s *authService) IssueTokens(ctx context.Context, email, secret string) (TokenPair, error) {
+	s.log.FromContext(ctx).Info("Issuing tokens")
+
+	user, err := s.checkPassword(ctx, email, secret)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	challenge, err := s.challengeMFA(ctx, user)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if challenge != nil {
+		return TokenPair{User: user, MFA: challenge}, nil
+	}
+
+	return s.issueTokens(ctx, user)
+}
+
+// issueTokens issues a new token pair to a user whose credentials were checked
+func (s *authService) issueTokens(ctx context.Context, user model.User) (TokenPair, error) {
+	// Each login starts a new family of refresh tokens
+	pair, refreshToken, err := s.newTokenPair(user, uuid.New().String())
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if err := s.refreshTokens.Create(ctx, refreshToken); err != nil {
+		return TokenPair{}, err
+	}
+
+	s.log.FromContext(ctx).Info("Issued tokens", "user_id", user.ID)
+	return pair, nil
+}
+
+// RefreshTokens exchanges a refresh token for a new token pair. The refresh
+// token is used up; presenting it again revokes its whole family, since
+// either the client or an attacker holds a stolen copy.
+func (s *authService) RefreshTokens(ctx context.Context, refreshToken string) (TokenPair, error) {
+	s.log.FromContext(ctx).Info("Refreshing tokens")
+
+	if refreshToken == "" {
+		return TokenPair{}, ErrInvalidToken
+	}
+
+	current, err := s.refreshTokens.FindByID(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return TokenPair{}, ErrInvalidToken
+		}
+		return TokenPair{}, err
+	}
+
+	if current.UsedAt != nil {
+		return TokenPair{}, s.revokeReusedFamily(ctx, current)
+	}
+	if current.Expired(time.Now()) {
+		return TokenPair{}, ErrInvalidToken
+	}
+
+	// Deleted users can no longer refresh
+	user, err := s.userRepo.FindByID(ctx, current.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return TokenPair{}, ErrInvalidToken
+		}
+		return TokenPair{}, err
+	}
+
+	pair, next, err := s.newTokenPair(user, current.FamilyID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.refreshTokens.MarkUsed(ctx, current.ID, time.Now()); err != nil {
+			return err
+		}
+		return s.refreshTokens.Create(ctx, next)
+	})
+	if err != nil {
+		// Another request used the token first
+		if errors.Is(err, repository.ErrConflict) {
+			return TokenPair{}, s.revokeReusedFamily(ctx, current)
+		}
+		if errors.Is(err, repository.ErrNotFound) {
+			return TokenPair{}, ErrInvalidToken
+		}
+		return TokenPair{}, err
+	}
+
+	s.log.FromContext(ctx).Info("Refreshed tokens", "user_id", user.ID)
+	return pair, nil
+}
+
+// RevokeRefreshToken revokes a refresh token together with its family.
+// Unknown tokens are ignored.
+func (s *authService) RevokeRefreshToken(ctx context.Context, refreshToken string) error {
+	s.log.FromContext(ctx).Info("Revoking refresh token")
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	current, err := s.refreshTokens.FindByID(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	_, err = s.refreshTokens.DeleteFamily(ctx, current.FamilyID)
+	return err
+}
+
+// AuthenticateAccessToken resolves an access token to the principal it was
+// issued to
+func (s *authService) AuthenticateAccessToken(ctx context.Context, accessToken string) (Principal, error) {
+	if accessToken == "" {
+		return Principal{}, ErrUnauthenticated
+	}
+
+	claims, err := s.keys.Parse(accessToken)
+	if err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+
+	// Access tokens of deleted users no longer authenticate, and the role is
+	// read from the user rather than the token so that changes apply at once
+	user, err := s.userRepo.FindByID(ctx, claims.Subject)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return Principal{}, ErrInvalidToken
+		}
+		return Principal{}, err
+	}
+
+	return Principal{
+		UserID:  user.ID,
+		Role:    user.Role,
+		Method:  AuthMethodToken,
+		TokenID: claims.ID,
+	}, nil
+}
+
+// newTokenPair signs an access token for user and creates a refresh token in
+// the given family. The refresh token still has to be stored.
+func (s *authService) newTokenPair(user model.User, familyID string) (TokenPair, model.RefreshToken, error) {
+	now := time.Now()
+
+	pair := TokenPair{
+		User:             user,
+		AccessExpiresAt:  now.Add(s.cfg.JWT.AccessTokenTTL),
+		RefreshExpiresAt: now.Add(s.cfg.JWT.RefreshTokenTTL),
+	}
+
+	var err error
+	pair.AccessToken, err = s.keys.Sign(&jwt.RegisteredClaims{
+		ID:        uuid.New().String(),
+		Subject:   user.ID,
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(pair.AccessExpiresAt),
+	})
+	if err != nil {
+		return TokenPair{}, model.RefreshToken{}, err
+	}
+
+	pair.RefreshToken, err = newToken()
+	if err != nil {
+		return TokenPair{}, model.RefreshToken{}, err
+	}
+
+	return pair, model.RefreshToken{
+		ID:        hashToken(pair.RefreshToken),
+		FamilyID:  familyID,
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: pair.RefreshExpiresAt,
+	}, nil
+}
+
+// revokeReusedFamily revokes the family of a refresh token that was presented
+// after it had been used, and returns the error to report
+func (s *authService) revokeReusedFamily(ctx context.Context, reused model.RefreshToken) error {
+	s.log.FromContext(ctx).Warn("Refresh token reused, revoking its family",
+		"user_id", reused.UserID, "family_id", reused.FamilyID)
+
+	if _, err := s.refreshTokens.DeleteFamily(ctx, reused.FamilyID); err != nil {
+		return err
+	}
+
+	return ErrInvalidToken
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/google/uuid"
+)
+
+// TestRefreshTokens presents refresh tokens in sequence. "login" and "other"
+// are the refresh tokens of two logins of the same user, and "rotated" is the
+// token returned by the latest successful refresh.
+func TestRefreshTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		present []string
+		want    []error
+	}{
+		{
+			name:    "fresh token",
+			present: []string{"login"},
+			want:    []error{nil},
+		},
+		{
+			name:    "rotated token",
+			present: []string{"login", "rotated", "rotated"},
+			want:    []error{nil, nil, nil},
+		},
+		{
+			name:    "unknown token",
+			present: []string{"unknown"},
+			want:    []error{ErrInvalidToken},
+		},
+		{
+			name:    "reused token",
+			present: []string{"login", "login"},
+			want:    []error{nil, ErrInvalidToken},
+		},
+		{
+			name:    "reuse revokes the rotated token",
+			present: []string{"login", "login", "rotated"},
+			want:    []error{nil, ErrInvalidToken, ErrInvalidToken},
+		},
+		{
+			name:    "reuse spares other logins",
+			present: []string{"login", "login", "other"},
+			want:    []error{nil, ErrInvalidToken, nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, user := newTestAuthService(t)
+
+			tokens := map[string]string{
+				"login":   issueTestRefreshToken(t, s, user),
+				"other":   issueTestRefreshToken(t, s, user),
+				"unknown": "unknown",
+			}
+
+			for i, name := range tt.present {
+				pair, err := s.RefreshTokens(ctx, tokens[name])
+				if !errors.Is(err, tt.want[i]) {
+					t.Fatalf("refresh %d with %q token: got error %v, want %v", i+1, name, err, tt.want[i])
+				}
+				if err == nil {
+					tokens["rotated"] = pair.RefreshToken
+				}
+			}
+		})
+	}
+}
+
+// issueTestRefreshToken starts a new refresh token family for user, like a
+// login does
+func issueTestRefreshToken(t *testing.T, s *authService, user model.User) string {
+	t.Helper()
+
+	pair, refreshToken, err := s.newTokenPair(user, uuid.New().String())
+	if err != nil {
+		t.Fatalf("creating token pair: %v", err)
+	}
+	if err := s.refreshTokens.Create(context.Background(), refreshToken); err != nil {
+		t.Fatalf("storing refresh token: %v", err)
+	}
+	return pair.RefreshToken
+}
@@ -0,0 +1,515 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/internal/pkg/config"
+	"github.com/ThePotatoVerse/pkg/database"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/ThePotatoVerse/pkg/oidc"
+	"github.com/ThePotatoVerse/pkg/token"
+)
+
+// External login errors
+var (
+	ErrExternalProviderNotFound = errors.New("identity provider not found")
+	ErrIdentityNotFound         = errors.New("linked identity not found")
+
+	// ErrInvalidExternalLogin is returned for callbacks whose state does not
+	// match the login started in the same browser, or that come too late
+	ErrInvalidExternalLogin = errors.New("invalid or expired external login")
+
+	// ErrExternalLoginFailed is returned when the provider reports an error
+	// or its ID token cannot be verified
+	ErrExternalLoginFailed = errors.New("external login failed")
+
+	// ErrExternalAccountNotLinked is returned when an external account is
+	// not linked to a user and cannot sign up
+	ErrExternalAccountNotLinked = errors.New("external account not linked to a user")
+
+	// ErrIdentityAlreadyLinked is returned when an external account is
+	// linked to another user, or the user is already linked to another
+	// account at the provider
+	ErrIdentityAlreadyLinked = errors.New("identity already linked")
+
+	// ErrLastLoginMethod is returned when unlinking would leave a user
+	// without a password or linked identity to log in with
+	ErrLastLoginMethod = errors.New("cannot remove the last way to log in")
+)
+
+// externalLoginPurpose binds login state cookies to external logins
+const externalLoginPurpose = "oidc-login"
+
+// ExternalCallbackPath is the path providers redirect back to, with the
+// provider name in place of %s
+const ExternalCallbackPath = "/api/v1/auth/oidc/%s/callback"
+
+// ExternalProvider is an identity provider users can log in with
+type ExternalProvider struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// ExternalLoginStart is a login started at a provider. The user agent is
+// sent to AuthorizationURL, and State must come back with it to the
+// callback, in a cookie, so that only the browser that started the login
+// can complete it.
+type ExternalLoginStart struct {
+	AuthorizationURL string
+	State            string
+	ExpiresAt        time.Time
+}
+
+// ExternalCallback is what a provider redirected back with, together with
+// the state of the login
+type ExternalCallback struct {
+	Provider         string
+	LoginState       string
+	State            string
+	Code             string
+	Error            string
+	ErrorDescription string
+}
+
+// ExternalLoginResult is the outcome of a completed external login. When
+// Linked is set, the account was linked to the user who started the login
+// and Login is empty; otherwise Login is the login of the account's user.
+// ReturnTo is where the login asked to send the user agent afterwards.
+type ExternalLoginResult struct {
+	Login    LoginResult
+	Identity model.ExternalIdentity
+	Linked   bool
+	Created  bool
+	ReturnTo string
+}
+
+// externalLoginState is the signed payload of a login state cookie
+type externalLoginState struct {
+	Provider   string `json:"provider"`
+	State      string `json:"state"`
+	Nonce      string `json:"nonce"`
+	Verifier   string `json:"verifier"`
+	ReturnTo   string `json:"return_to,omitempty"`
+	LinkUserID string `json:"link,omitempty"`
+	ExpiresAt  int64  `json:"exp"`
+}
+
+// ExternalLoginService defines the interface for logging in through external
+// OpenID Connect providers.
+//
+// Begin starts a login and Complete finishes it when the provider redirects
+// back. An account that is linked to a user logs that user in, subject to
+// two-factor authentication like a password login. An unlinked account
+// signs up a new user, whose email is taken as verified, if the provider
+// allows signup, shares a verified email in an allowed domain, and no user
+// has the email yet; existing users must log in and link the provider
+// themselves, by starting the login with their user ID.
+type ExternalLoginService interface {
+	Providers(ctx context.Context) ([]ExternalProvider, error)
+	Begin(ctx context.Context, provider, returnTo, linkUserID string) (ExternalLoginStart, error)
+	Complete(ctx context.Context, callback ExternalCallback) (ExternalLoginResult, error)
+	ListIdentities(ctx context.Context, userID string) ([]model.ExternalIdentity, error)
+	Unlink(ctx context.Context, userID, provider string) error
+}
+
+// externalLoginService implements ExternalLoginService
+type externalLoginService struct {
+	log           logger.Logger
+	authService   AuthService
+	userService   UserService
+	userRepo      repository.UserRepository
+	credentials   repository.CredentialRepository
+	identities    repository.ExternalIdentityRepository
+	txManager     database.TxManager
+	signer        *token.Signer
+	providers     []*oidc.Provider
+	providerCfg   map[string]config.OIDCProviderConfig
+	publicURL     string
+	returnOrigins []string
+	cfg           *config.AuthConfig
+}
+
+// NewExternalLoginService creates a new external login service for the
+// providers in cfg, which redirect back to the server at publicURL. Users
+// sign up through userService and log in through authService, and login
+// state cookies are signed with signer.
+func NewExternalLoginService(
+	log logger.Logger,
+	authService AuthService,
+	userService UserService,
+	userRepo repository.UserRepository,
+	credentials repository.CredentialRepository,
+	identities repository.ExternalIdentityRepository,
+	txManager database.TxManager,
+	signer *token.Signer,
+	publicURL string,
+	cfg *config.AuthConfig,
+) ExternalLoginService {
+	publicURL = strings.TrimSuffix(publicURL, "/")
+
+	s := &externalLoginService{
+		log:         log,
+		authService: authService,
+		userService: userService,
+		userRepo:    userRepo,
+		credentials: credentials,
+		identities:  identities,
+		txManager:   txManager,
+		signer:      signer,
+		providerCfg: make(map[string]config.OIDCProviderConfig, len(cfg.OIDC.Providers)),
+		publicURL:   publicURL,
+		cfg:         cfg,
+	}
+
+	for _, providerCfg := range cfg.OIDC.Providers {
+		redirectURL := publicURL + fmt.Sprintf(ExternalCallbackPath, providerCfg.Name)
+		s.providers = append(s.providers, oidc.NewProvider(providerCfg, redirectURL))
+		s.providerCfg[providerCfg.Name] = providerCfg
+	}
+
+	// The server itself is always a valid place to return to
+	s.returnOrigins = append(s.returnOrigins, origin(publicURL))
+	for _, allowed := range cfg.OIDC.ReturnOrigins {
+		s.returnOrigins = append(s.returnOrigins, origin(allowed))
+	}
+
+	return s
+}
+
+// Providers returns the configured providers, in configuration order
+func (s *externalLoginService) Providers(ctx context.Context) ([]ExternalProvider, error) {
+	providers := make([]ExternalProvider, 0, len(s.providers))
+	for _, provider := range s.providers {
+		providers = append(providers, ExternalProvider{Name: provider.Name(), DisplayName: provider.DisplayName()})
+	}
+	return providers, nil
+}
+
+// Begin starts a login at a provider. After the login the user agent is
+// sent to returnTo, if given. With linkUserID, the account is linked to
+// that user instead of logging in.
+func (s *externalLoginService) Begin(ctx context.Context, providerName, returnTo, linkUserID string) (ExternalLoginStart, error) {
+	s.log.FromContext(ctx).Info("Starting external login", "provider", providerName)
+
+	provider, err := s.provider(providerName)
+	if err != nil {
+		return ExternalLoginStart{}, err
+	}
+	if returnTo != "" && !s.allowedReturnTo(returnTo) {
+		return ExternalLoginStart{}, fmt.Errorf("%w: return_to must be a path on this server or an allowed origin", ErrInvalidInput)
+	}
+
+	// The state, nonce and PKCE verifier are random tokens, which are also
+	// valid verifiers
+	var values [3]string
+	for i := range values {
+		if values[i], err = newToken(); err != nil {
+			return ExternalLoginStart{}, err
+		}
+	}
+	state := externalLoginState{
+		Provider:   providerName,
+		State:      values[0],
+		Nonce:      values[1],
+		Verifier:   values[2],
+		ReturnTo:   returnTo,
+		LinkUserID: linkUserID,
+		ExpiresAt:  time.Now().Add(s.cfg.OIDC.StateTTL).Unix(),
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state.State, state.Nonce, state.Verifier)
+	if err != nil {
+		return ExternalLoginStart{}, err
+	}
+	loginState, err := s.signer.Sign(externalLoginPurpose, state)
+	if err != nil {
+		return ExternalLoginStart{}, err
+	}
+
+	return ExternalLoginStart{
+		AuthorizationURL: authURL,
+		State:            loginState,
+		ExpiresAt:        time.Unix(state.ExpiresAt, 0),
+	}, nil
+}
+
+// Complete finishes a login when the provider redirects back, linking the
+// account or logging its user in
+func (s *externalLoginService) Complete(ctx context.Context, callback ExternalCallback) (ExternalLoginResult, error) {
+	log := s.log.FromContext(ctx)
+	log.Info("Completing external login", "provider", callback.Provider)
+
+	provider, err := s.provider(callback.Provider)
+	if err != nil {
+		return ExternalLoginResult{}, err
+	}
+
+	var state externalLoginState
+	if err := s.signer.Verify(externalLoginPurpose, callback.LoginState, &state); err != nil {
+		return ExternalLoginResult{}, ErrInvalidExternalLogin
+	}
+	if state.Provider != callback.Provider || time.Now().Unix() >= state.ExpiresAt ||
+		subtle.ConstantTimeCompare([]byte(state.State), []byte(callback.State)) != 1 {
+		return ExternalLoginResult{}, ErrInvalidExternalLogin
+	}
+
+	if callback.Error != "" {
+		log.Warn("Identity provider returned an error", "provider", callback.Provider,
+			"error", callback.Error, "error_description", callback.ErrorDescription)
+		return ExternalLoginResult{}, fmt.Errorf("%w: the identity provider returned %s", ErrExternalLoginFailed, callback.Error)
+	}
+	if callback.Code == "" {
+		return ExternalLoginResult{}, ErrInvalidExternalLogin
+	}
+
+	identity, err := provider.Exchange(ctx, callback.Code, state.Verifier, state.Nonce)
+	if err != nil {
+		log.Warn("External login failed", "provider", callback.Provider, "error", err)
+		return ExternalLoginResult{}, ErrExternalLoginFailed
+	}
+
+	result := ExternalLoginResult{ReturnTo: state.ReturnTo}
+	if state.LinkUserID != "" {
+		result.Identity, err = s.link(ctx, callback.Provider, identity, state.LinkUserID)
+		result.Linked = err == nil
+		return result, err
+	}
+
+	result.Identity, err = s.identities.FindBySubject(ctx, callback.Provider, identity.Subject)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		result.Identity, err = s.signUp(ctx, s.providerCfg[callback.Provider], identity)
+		if err != nil {
+			return ExternalLoginResult{}, err
+		}
+		result.Created = true
+	case err != nil:
+		return ExternalLoginResult{}, err
+	}
+
+	result.Login, err = s.authService.LoginExternal(ctx, result.Identity.UserID)
+	if err != nil {
+		// Users that were deleted no longer log in through their links
+		if errors.Is(err, ErrUserNotFound) {
+			return ExternalLoginResult{}, ErrExternalAccountNotLinked
+		}
+		return ExternalLoginResult{}, err
+	}
+
+	return result, nil
+}
+
+// link links an external account to a user. Linking an account again to
+// the same user succeeds.
+func (s *externalLoginService) link(ctx context.Context, providerName string, identity *oidc.Identity, userID string) (model.ExternalIdentity, error) {
+	existing, err := s.identities.FindBySubject(ctx, providerName, identity.Subject)
+	switch {
+	case err == nil && existing.UserID == userID:
+		return existing, nil
+	case err == nil:
+		return model.ExternalIdentity{}, fmt.Errorf("%w: the account is linked to another user", ErrIdentityAlreadyLinked)
+	case !errors.Is(err, repository.ErrNotFound):
+		return model.ExternalIdentity{}, err
+	}
+
+	linked := model.ExternalIdentity{
+		Provider:  providerName,
+		Subject:   identity.Subject,
+		UserID:    userID,
+		Email:     identity.Email,
+		CreatedAt: time.Now(),
+	}
+	if err := s.identities.Create(ctx, linked); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrConflict):
+			return model.ExternalIdentity{}, fmt.Errorf("%w: the user is linked to another account at %s", ErrIdentityAlreadyLinked, providerName)
+		case errors.Is(err, repository.ErrNotFound):
+			return model.ExternalIdentity{}, ErrUserNotFound
+		}
+		return model.ExternalIdentity{}, err
+	}
+
+	s.log.FromContext(ctx).Info("Linked external identity", "provider", providerName, "user_id", userID)
+	return linked, nil
+}
+
+// signUp creates a user for an external account that is not linked yet.
+// Their email is verified, as the provider vouches for it.
+func (s *externalLoginService) signUp(ctx context.Context, providerCfg config.OIDCProviderConfig, identity *oidc.Identity) (model.ExternalIdentity, error) {
+	if providerCfg.DisableSignup {
+		return model.ExternalIdentity{}, fmt.Errorf("%w: signing up through %s is disabled", ErrExternalAccountNotLinked, providerCfg.DisplayName)
+	}
+	if identity.Email == "" || !identity.EmailVerified {
+		return model.ExternalIdentity{}, fmt.Errorf("%w: %s did not share a verified email", ErrExternalAccountNotLinked, providerCfg.DisplayName)
+	}
+	if !allowedDomain(identity.Email, providerCfg.AllowedDomains) {
+		return model.ExternalIdentity{}, fmt.Errorf("%w: the email domain cannot sign up through %s", ErrExternalAccountNotLinked, providerCfg.DisplayName)
+	}
+
+	// Signed up users are members, and become admins through their verified
+	// email like registered users
+	user := model.User{Name: identity.Name, Email: identity.Email, Role: model.RoleMember}
+	if strings.TrimSpace(user.Name) == "" {
+		user.Name, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	var linked model.ExternalIdentity
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		created, err := s.userService.Create(ctx, user)
+		if err != nil {
+			return err
+		}
+		verified, err := s.userRepo.MarkEmailVerified(ctx, created.ID, created.Email, time.Now())
+		if err != nil {
+			return err
+		}
+		if _, err := grantConfiguredAdmin(ctx, s.userRepo, s.cfg.AdminEmails, verified); err != nil {
+			return err
+		}
+
+		linked = model.ExternalIdentity{
+			Provider:  providerCfg.Name,
+			Subject:   identity.Subject,
+			UserID:    created.ID,
+			Email:     identity.Email,
+			CreatedAt: time.Now(),
+		}
+		if err := s.identities.Create(ctx, linked); err != nil {
+			// Another login of the same account signed up first
+			if errors.Is(err, repository.ErrConflict) {
+				return ErrIdentityAlreadyLinked
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrEmailTaken) {
+			return model.ExternalIdentity{}, fmt.Errorf("%w: log in and link %s to your account instead", ErrEmailTaken, providerCfg.DisplayName)
+		}
+		return model.ExternalIdentity{}, err
+	}
+
+	s.log.FromContext(ctx).Info("Signed up through external identity", "provider", providerCfg.Name, "user_id", linked.UserID)
+	return linked, nil
+}
+
+// ListIdentities returns the external accounts linked to a user
+func (s *externalLoginService) ListIdentities(ctx context.Context, userID string) ([]model.ExternalIdentity, error) {
+	s.log.FromContext(ctx).Info("Listing linked identities", "user_id", userID)
+
+	if _, err := s.userService.Get(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return s.identities.ListByUser(ctx, userID)
+}
+
+// Unlink removes the link between a user and their account at a provider,
+// unless it is the last way the user can log in
+func (s *externalLoginService) Unlink(ctx context.Context, userID, providerName string) error {
+	s.log.FromContext(ctx).Info("Unlinking identity", "user_id", userID, "provider", providerName)
+
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		identities, err := s.ListIdentities(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		found := false
+		for _, identity := range identities {
+			found = found || identity.Provider == providerName
+		}
+		if !found {
+			return ErrIdentityNotFound
+		}
+
+		if len(identities) == 1 {
+			_, err := s.credentials.Get(ctx, userID)
+			if errors.Is(err, repository.ErrNotFound) {
+				return fmt.Errorf("%w: set a password or link another provider first", ErrLastLoginMethod)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := s.identities.Delete(ctx, userID, providerName); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return ErrIdentityNotFound
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+// provider returns the provider configured under name
+func (s *externalLoginService) provider(name string) (*oidc.Provider, error) {
+	for _, provider := range s.providers {
+		if provider.Name() == name {
+			return provider, nil
+		}
+	}
+	return nil, ErrExternalProviderNotFound
+}
+
+// allowedReturnTo reports whether the user agent may be sent to returnTo
+// after a login: a path on this server, or a URL at an allowed origin
+func (s *externalLoginService) allowedReturnTo(returnTo string) bool {
+	// Backslashes are treated as slashes by browsers, so \\host is //host
+	if strings.Contains(returnTo, `\`) {
+		return false
+	}
+	if strings.HasPrefix(returnTo, "/") && !strings.HasPrefix(returnTo, "//") {
+		return true
+	}
+
+	u, err := url.Parse(returnTo)
+	if err != nil || u.Scheme == "" || u.Host == "" || u.User != nil {
+		return false
+	}
+	target := origin(returnTo)
+	for _, allowed := range s.returnOrigins {
+		if target == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// origin returns the lowercased scheme://host[:port] of an absolute URL, or
+// "" if it has none
+func origin(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// allowedDomain reports whether the domain of email is one of domains, or
+// domains is empty
+func allowedDomain(email string, domains []string) bool {
+	if len(domains) == 0 {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	for _, domain := range domains {
+		if strings.EqualFold(email[at+1:], strings.TrimPrefix(domain, "@")) {
+			return true
+		}
+	}
+	return false
+}
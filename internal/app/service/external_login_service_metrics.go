@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// externalLoginServiceMetrics decorates an ExternalLoginService with per-operation counters
+type externalLoginServiceMetrics struct {
+	next       ExternalLoginService
+	operations *prometheus.CounterVec
+}
+
+// NewExternalLoginServiceMetrics wraps next so that every call is counted by
+// operation and result in external_login_service_operations_total
+func NewExternalLoginServiceMetrics(next ExternalLoginService, registerer prometheus.Registerer) ExternalLoginService {
+	operations := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "external_login_service_operations_total",
+		Help: "Total number of external login service operations by operation and result.",
+	}, []string{"operation", "result"})
+
+	registerer.MustRegister(operations)
+
+	return &externalLoginServiceMetrics{
+		next:       next,
+		operations: operations,
+	}
+}
+
+// Providers implements ExternalLoginService
+func (m *externalLoginServiceMetrics) Providers(ctx context.Context) ([]ExternalProvider, error) {
+	providers, err := m.next.Providers(ctx)
+	observeOperation(m.operations, "providers", err)
+	return providers, err
+}
+
+// Begin implements ExternalLoginService
+func (m *externalLoginServiceMetrics) Begin(ctx context.Context, provider, returnTo, linkUserID string) (ExternalLoginStart, error) {
+	start, err := m.next.Begin(ctx, provider, returnTo, linkUserID)
+	observeOperation(m.operations, "begin", err)
+	return start, err
+}
+
+// Complete implements ExternalLoginService
+func (m *externalLoginServiceMetrics) Complete(ctx context.Context, callback ExternalCallback) (ExternalLoginResult, error) {
+	result, err := m.next.Complete(ctx, callback)
+	observeOperation(m.operations, "complete", err)
+	return result, err
+}
+
+// ListIdentities implements ExternalLoginService
+func (m *externalLoginServiceMetrics) ListIdentities(ctx context.Context, userID string) ([]model.ExternalIdentity, error) {
+	identities, err := m.next.ListIdentities(ctx, userID)
+	observeOperation(m.operations, "list_identities", err)
+	return identities, err
+}
+
+// Unlink implements ExternalLoginService
+func (m *externalLoginServiceMetrics) Unlink(ctx context.Context, userID, provider string) error {
+	err := m.next.Unlink(ctx, userID, provider)
+	observeOperation(m.operations, "unlink", err)
+	return err
+}
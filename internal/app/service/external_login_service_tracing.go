@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// externalLoginServiceTracing decorates an ExternalLoginService with a span
+// per method. Codes, states and tokens are never recorded.
+type externalLoginServiceTracing struct {
+	next   ExternalLoginService
+	tracer trace.Tracer
+}
+
+// NewExternalLoginServiceTracing wraps next so that every call runs in a child span
+func NewExternalLoginServiceTracing(next ExternalLoginService) ExternalLoginService {
+	return &externalLoginServiceTracing{
+		next:   next,
+		tracer: otel.Tracer(tracerName),
+	}
+}
+
+// start starts the span of an operation
+func (t *externalLoginServiceTracing) start(ctx context.Context, operation string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, "ExternalLoginService."+operation, trace.WithAttributes(attributes...))
+}
+
+// Providers implements ExternalLoginService
+func (t *externalLoginServiceTracing) Providers(ctx context.Context) ([]ExternalProvider, error) {
+	ctx, span := t.start(ctx, "Providers")
+	providers, err := t.next.Providers(ctx)
+	endSpan(span, err)
+	return providers, err
+}
+
+// Begin implements ExternalLoginService
+func (t *externalLoginServiceTracing) Begin(ctx context.Context, provider, returnTo, linkUserID string) (ExternalLoginStart, error) {
+	ctx, span := t.start(ctx, "Begin",
+		attribute.String("oidc.provider", provider), attribute.Bool("oidc.link", linkUserID != ""))
+	start, err := t.next.Begin(ctx, provider, returnTo, linkUserID)
+	endSpan(span, err)
+	return start, err
+}
+
+// Complete implements ExternalLoginService
+func (t *externalLoginServiceTracing) Complete(ctx context.Context, callback ExternalCallback) (ExternalLoginResult, error) {
+	ctx, span := t.start(ctx, "Complete", attribute.String("oidc.provider", callback.Provider))
+	result, err := t.next.Complete(ctx, callback)
+	if err == nil {
+		span.SetAttributes(
+			attribute.String("user.id", result.Identity.UserID),
+			attribute.Bool("oidc.linked", result.Linked),
+			attribute.Bool("oidc.created", result.Created),
+			attribute.Bool("auth.mfa_required", result.Login.MFA != nil),
+		)
+	}
+	endSpan(span, err)
+	return result, err
+}
+
+// ListIdentities implements ExternalLoginService
+func (t *externalLoginServiceTracing) ListIdentities(ctx context.Context, userID string) ([]model.ExternalIdentity, error) {
+	ctx, span := t.start(ctx, "ListIdentities", attribute.String("user.id", userID))
+	identities, err := t.next.ListIdentities(ctx, userID)
+	endSpan(span, err)
+	return identities, err
+}
+
+// Unlink implements ExternalLoginService
+func (t *externalLoginServiceTracing) Unlink(ctx context.Context, userID, provider string) error {
+	ctx, span := t.start(ctx, "Unlink", attribute.String("user.id", userID), attribute.String("oidc.provider", provider))
+	err := t.next.Unlink(ctx, userID, provider)
+	endSpan(span, err)
+	return err
+}
@@ -0,0 +1,436 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/internal/pkg/config"
+	"github.com/ThePotatoVerse/pkg/database"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/ThePotatoVerse/pkg/totp"
+)
+
+// Two-factor authentication errors
+var (
+	ErrInvalidMFACode    = errors.New("invalid two-factor authentication code")
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrMFALocked         = errors.New("too many wrong two-factor authentication codes")
+)
+
+// totpSkew is how many time steps a code may be off, to allow for clock
+// drift between the server and authenticator apps
+const totpSkew = 1
+
+// Recovery codes look like xxxxx-xxxxx, in lowercase base32
+const (
+	recoveryCodeLength = 10
+	recoveryCodeBytes  = 7
+)
+
+// recoveryEncoding is the alphabet of recovery codes
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFAStatus describes the second factors of a user
+type MFAStatus struct {
+	TOTPEnabled            bool
+	EnabledAt              *time.Time
+	RecoveryCodesRemaining int
+}
+
+// TOTPEnrollment is a TOTP secret waiting to be confirmed, together with the
+// otpauth:// URI authenticator apps enroll it from
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// MFAService defines the interface for two-factor authentication with TOTP
+// authenticator apps.
+//
+// EnrollTOTP generates a secret, which ConfirmTOTP enables once it is given
+// a first code from it. Confirming returns single-use recovery codes, which
+// Verify accepts in place of a TOTP code and which are only stored hashed.
+// Regenerating recovery codes and disabling a user's own factor take a
+// current code, so that a stolen session alone cannot do either. Verify
+// counts wrong codes per user on every path, and returns ErrMFALocked for a
+// while once there were too many in a row, so that codes cannot be guessed
+// through any endpoint or across logins.
+type MFAService interface {
+	Status(ctx context.Context, userID string) (MFAStatus, error)
+	EnrollTOTP(ctx context.Context, userID string) (TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error)
+	RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error)
+	Disable(ctx context.Context, userID, code string) error
+	Verify(ctx context.Context, userID, code string) error
+}
+
+// mfaService implements MFAService
+type mfaService struct {
+	log       logger.Logger
+	userRepo  repository.UserRepository
+	mfa       repository.MFARepository
+	txManager database.TxManager
+	cfg       *config.MFAConfig
+}
+
+// NewMFAService creates a new two-factor authentication service
+func NewMFAService(
+	log logger.Logger,
+	userRepo repository.UserRepository,
+	mfa repository.MFARepository,
+	txManager database.TxManager,
+	cfg *config.MFAConfig,
+) MFAService {
+	return &mfaService{
+		log:       log,
+		userRepo:  userRepo,
+		mfa:       mfa,
+		txManager: txManager,
+		cfg:       cfg,
+	}
+}
+
+// Status describes the second factors of a user
+func (s *mfaService) Status(ctx context.Context, userID string) (MFAStatus, error) {
+	if _, err := s.findUser(ctx, userID); err != nil {
+		return MFAStatus{}, err
+	}
+
+	factor, err := s.mfa.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return MFAStatus{}, nil
+		}
+		return MFAStatus{}, err
+	}
+	if !factor.Confirmed() {
+		return MFAStatus{}, nil
+	}
+
+	remaining, err := s.mfa.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return MFAStatus{}, err
+	}
+
+	return MFAStatus{
+		TOTPEnabled:            true,
+		EnabledAt:              factor.ConfirmedAt,
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+// EnrollTOTP generates a new TOTP secret for a user, replacing any secret
+// that was not confirmed yet
+func (s *mfaService) EnrollTOTP(ctx context.Context, userID string) (TOTPEnrollment, error) {
+	s.log.FromContext(ctx).Info("Enrolling TOTP", "user_id", userID)
+
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+
+	factor, err := s.mfa.GetTOTP(ctx, userID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return TOTPEnrollment{}, err
+	}
+	if err == nil && factor.Confirmed() {
+		return TOTPEnrollment{}, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+
+	err = s.mfa.SaveTOTP(ctx, model.TOTPFactor{
+		UserID:    userID,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+
+	return TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.cfg.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables the enrolled TOTP secret of a user with a first code
+// from it, and returns new recovery codes
+func (s *mfaService) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	s.log.FromContext(ctx).Info("Confirming TOTP", "user_id", userID)
+
+	if _, err := s.findUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	factor, err := s.mfa.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrMFANotEnabled
+		}
+		return nil, err
+	}
+	if factor.Confirmed() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	now := time.Now()
+	step, ok := totp.Validate(factor.Secret, normalizeCode(code), now, totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, records, err := newRecoveryCodes(userID, s.cfg.RecoveryCodes, now)
+	if err != nil {
+		return nil, err
+	}
+
+	factor.ConfirmedAt = &now
+	factor.LastUsedStep = step
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.mfa.SaveTOTP(ctx, factor); err != nil {
+			return err
+		}
+		return s.mfa.ReplaceRecoveryCodes(ctx, userID, records)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.FromContext(ctx).Info("Enabled TOTP", "user_id", userID)
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user, after
+// checking a current code
+func (s *mfaService) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	s.log.FromContext(ctx).Info("Regenerating recovery codes", "user_id", userID)
+
+	if _, err := s.findUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	codes, records, err := newRecoveryCodes(userID, s.cfg.RecoveryCodes, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		return s.mfa.ReplaceRecoveryCodes(ctx, userID, records)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable removes the TOTP factor and recovery codes of a user. Users
+// disabling their own factor must give a current code; principals permitted
+// to disable it for others, such as admins helping a user who lost their
+// authenticator, need not. An enrollment that was never confirmed protects
+// nothing yet, so cancelling it takes no code.
+func (s *mfaService) Disable(ctx context.Context, userID, code string) error {
+	s.log.FromContext(ctx).Info("Disabling two-factor authentication", "user_id", userID)
+
+	if _, err := s.findUser(ctx, userID); err != nil {
+		return err
+	}
+	factor, err := s.mfa.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrMFANotEnabled
+		}
+		return err
+	}
+
+	principal, ok := PrincipalFromContext(ctx)
+	if factor.Confirmed() && (!ok || principal.UserID == userID) {
+		if err := s.Verify(ctx, userID, code); err != nil {
+			return err
+		}
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.mfa.DeleteTOTP(ctx, userID); err != nil {
+			return err
+		}
+		return s.mfa.ReplaceRecoveryCodes(ctx, userID, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.log.FromContext(ctx).Info("Disabled two-factor authentication", "user_id", userID)
+	return nil
+}
+
+// Verify checks a TOTP or recovery code of a user. Each TOTP code is only
+// accepted once, and recovery codes are used up. Wrong codes lock the factor
+// once there were too many in a row.
+func (s *mfaService) Verify(ctx context.Context, userID, code string) error {
+	factor, err := s.mfa.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrMFANotEnabled
+		}
+		return err
+	}
+	if !factor.Confirmed() {
+		return ErrMFANotEnabled
+	}
+
+	now := time.Now()
+	if factor.Locked(now) {
+		s.log.FromContext(ctx).Warn("Second factor locked", "user_id", userID, "locked_until", factor.LockedUntil)
+		return lockedError(*factor.LockedUntil)
+	}
+
+	if err := s.checkCode(ctx, factor, normalizeCode(code), now); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			return s.recordFailure(ctx, userID, now)
+		}
+		return err
+	}
+
+	if factor.FailedAttempts > 0 || factor.LockedUntil != nil {
+		if err := s.mfa.ClearTOTPFailures(ctx, userID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCode checks a normalized TOTP or recovery code against a factor
+func (s *mfaService) checkCode(ctx context.Context, factor model.TOTPFactor, code string, now time.Time) error {
+	userID := factor.UserID
+
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(factor.Secret, code, now, totpSkew)
+		if !ok {
+			s.log.FromContext(ctx).Warn("Invalid TOTP code", "user_id", userID)
+			return ErrInvalidMFACode
+		}
+
+		if err := s.mfa.UseTOTPStep(ctx, userID, step); err != nil {
+			if errors.Is(err, repository.ErrConflict) {
+				s.log.FromContext(ctx).Warn("TOTP code replayed", "user_id", userID)
+				return ErrInvalidMFACode
+			}
+			if errors.Is(err, repository.ErrNotFound) {
+				return ErrMFANotEnabled
+			}
+			return err
+		}
+		return nil
+	}
+
+	if len(code) != recoveryCodeLength {
+		return ErrInvalidMFACode
+	}
+	if err := s.mfa.UseRecoveryCode(ctx, userID, hashToken(code)); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			s.log.FromContext(ctx).Warn("Invalid recovery code", "user_id", userID)
+			return ErrInvalidMFACode
+		}
+		return err
+	}
+
+	s.log.FromContext(ctx).Info("Used recovery code", "user_id", userID)
+	return nil
+}
+
+// recordFailure counts a wrong code against the factor of a user, locks it
+// once there were too many in a row, and returns the error to report. The
+// lockout doubles with each wrong code past the threshold.
+func (s *mfaService) recordFailure(ctx context.Context, userID string, now time.Time) error {
+	attempts, err := s.mfa.RecordTOTPFailure(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrMFANotEnabled
+		}
+		return err
+	}
+
+	over := attempts - s.cfg.LockoutThreshold
+	if over < 0 {
+		return ErrInvalidMFACode
+	}
+
+	lockout := s.cfg.LockoutDuration
+	for i := 0; i < over && lockout < s.cfg.MaxLockoutDuration; i++ {
+		lockout *= 2
+	}
+	until := now.Add(min(lockout, s.cfg.MaxLockoutDuration))
+	if err := s.mfa.LockTOTP(ctx, userID, until); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrMFANotEnabled
+		}
+		return err
+	}
+
+	s.log.FromContext(ctx).Warn("Too many wrong second factors, locking", "user_id", userID, "attempts", attempts, "locked_until", until)
+	return lockedError(until)
+}
+
+// lockedError reports a factor locked until the given time
+func lockedError(until time.Time) error {
+	return fmt.Errorf("%w, try again after %s", ErrMFALocked, until.UTC().Format(time.RFC3339))
+}
+
+// findUser returns the user with userID, or ErrUserNotFound
+func (s *mfaService) findUser(ctx context.Context, userID string) (model.User, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return model.User{}, ErrUserNotFound
+		}
+		return model.User{}, err
+	}
+	return user, nil
+}
+
+// newRecoveryCodes returns n new recovery codes for a user, together with
+// the records they are stored as
+func newRecoveryCodes(userID string, n int, now time.Time) ([]string, []model.RecoveryCode, error) {
+	codes := make([]string, n)
+	records := make([]model.RecoveryCode, n)
+
+	b := make([]byte, recoveryCodeBytes)
+	for i := range codes {
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(recoveryEncoding.EncodeToString(b))[:recoveryCodeLength]
+		codes[i] = code[:recoveryCodeLength/2] + "-" + code[recoveryCodeLength/2:]
+		records[i] = model.RecoveryCode{
+			ID:        hashToken(code),
+			UserID:    userID,
+			CreatedAt: now,
+		}
+	}
+
+	return codes, records, nil
+}
+
+// normalizeCode strips the separators users may type in a code, and
+// lowercases recovery codes
+func normalizeCode(code string) string {
+	code = strings.NewReplacer(" ", "", "-", "").Replace(code)
+	return strings.ToLower(code)
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// mfaServiceMetrics decorates an MFAService with per-operation counters
+type mfaServiceMetrics struct {
+	next       MFAService
+	operations *prometheus.CounterVec
+}
+
+// NewMFAServiceMetrics wraps next so that every call is counted by
+// operation and result in mfa_service_operations_total
+func NewMFAServiceMetrics(next MFAService, registerer prometheus.Registerer) MFAService {
+	operations := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "mfa_service_operations_total",
+		Help: "Total number of two-factor authentication service operations by operation and result.",
+	}, []string{"operation", "result"})
+
+	registerer.MustRegister(operations)
+
+	return &mfaServiceMetrics{
+		next:       next,
+		operations: operations,
+	}
+}
+
+// Status implements MFAService
+func (m *mfaServiceMetrics) Status(ctx context.Context, userID string) (MFAStatus, error) {
+	status, err := m.next.Status(ctx, userID)
+	observeOperation(m.operations, "status", err)
+	return status, err
+}
+
+// EnrollTOTP implements MFAService
+func (m *mfaServiceMetrics) EnrollTOTP(ctx context.Context, userID string) (TOTPEnrollment, error) {
+	enrollment, err := m.next.EnrollTOTP(ctx, userID)
+	observeOperation(m.operations, "enroll_totp", err)
+	return enrollment, err
+}
+
+// ConfirmTOTP implements MFAService
+func (m *mfaServiceMetrics) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	codes, err := m.next.ConfirmTOTP(ctx, userID, code)
+	observeOperation(m.operations, "confirm_totp", err)
+	return codes, err
+}
+
+// RegenerateRecoveryCodes implements MFAService
+func (m *mfaServiceMetrics) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	codes, err := m.next.RegenerateRecoveryCodes(ctx, userID, code)
+	observeOperation(m.operations, "regenerate_recovery_codes", err)
+	return codes, err
+}
+
+// Disable implements MFAService
+func (m *mfaServiceMetrics) Disable(ctx context.Context, userID, code string) error {
+	err := m.next.Disable(ctx, userID, code)
+	observeOperation(m.operations, "disable", err)
+	return err
+}
+
+// Verify implements MFAService
+func (m *mfaServiceMetrics) Verify(ctx context.Context, userID, code string) error {
+	err := m.next.Verify(ctx, userID, code)
+	observeOperation(m.operations, "verify", err)
+	return err
+}
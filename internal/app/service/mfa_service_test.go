@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/internal/app/repository/memory"
+	"github.com/ThePotatoVerse/internal/pkg/config"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/ThePotatoVerse/pkg/totp"
+)
+
+// TestVerifyLockout gives a sequence of "wrong" and "right" codes, and
+// checks the outcome of the last one. The test service locks after 3 wrong
+// codes for a minute, doubling up to 5 minutes. With unlock set, each
+// lockout is expired before the next code.
+func TestVerifyLockout(t *testing.T) {
+	w, r := "wrong", "right"
+
+	tests := []struct {
+		name        string
+		codes       []string
+		unlock      bool
+		wantErr     error
+		wantLockout time.Duration
+	}{
+		{
+			name:    "right code",
+			codes:   []string{r},
+			wantErr: nil,
+		},
+		{
+			name:    "wrong codes below the threshold",
+			codes:   []string{w, w},
+			wantErr: ErrInvalidMFACode,
+		},
+		{
+			name:        "threshold locks",
+			codes:       []string{w, w, w},
+			wantErr:     ErrMFALocked,
+			wantLockout: time.Minute,
+		},
+		{
+			name:        "lockout doubles",
+			codes:       []string{w, w, w, w},
+			unlock:      true,
+			wantErr:     ErrMFALocked,
+			wantLockout: 2 * time.Minute,
+		},
+		{
+			name:        "lockout doubles again",
+			codes:       []string{w, w, w, w, w},
+			unlock:      true,
+			wantErr:     ErrMFALocked,
+			wantLockout: 4 * time.Minute,
+		},
+		{
+			name:        "lockout is capped",
+			codes:       []string{w, w, w, w, w, w, w, w},
+			unlock:      true,
+			wantErr:     ErrMFALocked,
+			wantLockout: 5 * time.Minute,
+		},
+		{
+			name:        "locked factor rejects the right code",
+			codes:       []string{w, w, w, r},
+			wantErr:     ErrMFALocked,
+			wantLockout: time.Minute,
+		},
+		{
+			name:    "right code after the lockout",
+			codes:   []string{w, w, w, r},
+			unlock:  true,
+			wantErr: nil,
+		},
+		{
+			name:    "right code resets the count",
+			codes:   []string{w, w, r, w, w},
+			wantErr: ErrInvalidMFACode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, mfa, user := newTestMFAService(t)
+			factor := confirmTestTOTP(t, mfa, user)
+
+			var (
+				err  error
+				last time.Time
+			)
+			for _, code := range tt.codes {
+				if tt.unlock {
+					expireTestLockout(t, mfa, user)
+				}
+
+				if code == r {
+					// Confirming used the current step, so use the next one
+					code, err = totp.Code(factor.Secret, totp.Step(time.Now())+1)
+					if err != nil {
+						t.Fatalf("generating code: %v", err)
+					}
+				} else {
+					code = "000000"
+				}
+
+				last = time.Now()
+				err = s.Verify(ctx, user.ID, code)
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			factor, err = mfa.GetTOTP(ctx, user.ID)
+			if err != nil {
+				t.Fatalf("reading factor: %v", err)
+			}
+
+			var lockout time.Duration
+			if factor.LockedUntil != nil {
+				lockout = factor.LockedUntil.Sub(last).Round(time.Second)
+			}
+			if lockout != tt.wantLockout {
+				t.Errorf("got lockout %s, want %s", lockout, tt.wantLockout)
+			}
+		})
+	}
+}
+
+// TestDisable checks when disabling two-factor authentication takes a code
+func TestDisable(t *testing.T) {
+	tests := []struct {
+		name      string
+		confirmed bool
+		// admin disables the factor for the user instead of the user
+		admin   bool
+		wantErr error
+	}{
+		{
+			name:      "own confirmed factor",
+			confirmed: true,
+			wantErr:   ErrInvalidMFACode,
+		},
+		{
+			name:      "confirmed factor of another user",
+			confirmed: true,
+			admin:     true,
+		},
+		{
+			name: "own unconfirmed enrollment",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mfa, user := newTestMFAService(t)
+			if tt.confirmed {
+				confirmTestTOTP(t, mfa, user)
+			} else if _, err := s.EnrollTOTP(context.Background(), user.ID); err != nil {
+				t.Fatalf("enrolling: %v", err)
+			}
+
+			principal := Principal{UserID: user.ID, Role: user.Role, Method: AuthMethodSession}
+			if tt.admin {
+				principal = Principal{UserID: "admin", Role: model.RoleAdmin, Method: AuthMethodSession}
+			}
+			ctx := ContextWithPrincipal(context.Background(), principal)
+
+			err := s.Disable(ctx, user.ID, "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			_, err = mfa.GetTOTP(ctx, user.ID)
+			if deleted := errors.Is(err, repository.ErrNotFound); deleted != (tt.wantErr == nil) {
+				t.Errorf("factor deleted: %t, want %t", deleted, tt.wantErr == nil)
+			}
+		})
+	}
+}
+
+// newTestMFAService creates an MFA service backed by memory repositories,
+// together with its MFA repository and a user to protect
+func newTestMFAService(t *testing.T) (MFAService, repository.MFARepository, model.User) {
+	t.Helper()
+
+	tx := memory.NewTxManager()
+	userRepo := memory.NewUserRepository(tx)
+	user, err := userRepo.Create(context.Background(), model.User{Name: "Ada", Email: "ada@example.com", Role: model.RoleMember})
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+
+	cfg := &config.MFAConfig{
+		Issuer:             "ThePotatoVerse",
+		RecoveryCodes:      10,
+		LockoutThreshold:   3,
+		LockoutDuration:    time.Minute,
+		MaxLockoutDuration: 5 * time.Minute,
+	}
+
+	mfa := memory.NewMFARepository(tx)
+	return NewMFAService(logger.New(), userRepo, mfa, tx, cfg), mfa, user
+}
+
+// confirmTestTOTP gives user a TOTP factor confirmed with a code of the
+// current time step
+func confirmTestTOTP(t *testing.T, mfa repository.MFARepository, user model.User) model.TOTPFactor {
+	t.Helper()
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("generating secret: %v", err)
+	}
+
+	now := time.Now()
+	factor := model.TOTPFactor{
+		UserID:       user.ID,
+		Secret:       secret,
+		CreatedAt:    now,
+		ConfirmedAt:  &now,
+		LastUsedStep: totp.Step(now),
+	}
+	if err := mfa.SaveTOTP(context.Background(), factor); err != nil {
+		t.Fatalf("saving factor: %v", err)
+	}
+	return factor
+}
+
+// expireTestLockout ends the lockout of the factor of user, if any, as if
+// it had run out. Wrong codes stay counted.
+func expireTestLockout(t *testing.T, mfa repository.MFARepository, user model.User) {
+	t.Helper()
+
+	factor, err := mfa.GetTOTP(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("reading factor: %v", err)
+	}
+	if factor.LockedUntil == nil {
+		return
+	}
+
+	past := time.Now().Add(-time.Second)
+	factor.LockedUntil = &past
+	if err := mfa.SaveTOTP(context.Background(), factor); err != nil {
+		t.Fatalf("saving factor: %v", err)
+	}
+}
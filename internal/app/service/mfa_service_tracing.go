@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// mfaServiceTracing decorates an MFAService with a span per method. Secrets
+// and codes are never recorded.
+type mfaServiceTracing struct {
+	next   MFAService
+	tracer trace.Tracer
+}
+
+// NewMFAServiceTracing wraps next so that every call runs in a child span
+func NewMFAServiceTracing(next MFAService) MFAService {
+	return &mfaServiceTracing{
+		next:   next,
+		tracer: otel.Tracer(tracerName),
+	}
+}
+
+// start starts the span of an operation on a user
+func (t *mfaServiceTracing) start(ctx context.Context, operation, userID string) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, "MFAService."+operation, trace.WithAttributes(attribute.String("user.id", userID)))
+}
+
+// Status implements MFAService
+func (t *mfaServiceTracing) Status(ctx context.Context, userID string) (MFAStatus, error) {
+	ctx, span := t.start(ctx, "Status", userID)
+	status, err := t.next.Status(ctx, userID)
+	endSpan(span, err)
+	return status, err
+}
+
+// EnrollTOTP implements MFAService
+func (t *mfaServiceTracing) EnrollTOTP(ctx context.Context, userID string) (TOTPEnrollment, error) {
+	ctx, span := t.start(ctx, "EnrollTOTP", userID)
+	enrollment, err := t.next.EnrollTOTP(ctx, userID)
+	endSpan(span, err)
+	return enrollment, err
+}
+
+// ConfirmTOTP implements MFAService
+func (t *mfaServiceTracing) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	ctx, span := t.start(ctx, "ConfirmTOTP", userID)
+	codes, err := t.next.ConfirmTOTP(ctx, userID, code)
+	endSpan(span, err)
+	return codes, err
+}
+
+// RegenerateRecoveryCodes implements MFAService
+func (t *mfaServiceTracing) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	ctx, span := t.start(ctx, "RegenerateRecoveryCodes", userID)
+	codes, err := t.next.RegenerateRecoveryCodes(ctx, userID, code)
+	endSpan(span, err)
+	return codes, err
+}
+
+// Disable implements MFAService
+func (t *mfaServiceTracing) Disable(ctx context.Context, userID, code string) error {
+	ctx, span := t.start(ctx, "Disable", userID)
+	err := t.next.Disable(ctx, userID, code)
+	endSpan(span, err)
+	return err
+}
+
+// Verify implements MFAService
+func (t *mfaServiceTracing) Verify(ctx context.Context, userID, code string) error {
+	ctx, span := t.start(ctx, "Verify", userID)
+	err := t.next.Verify(ctx, userID, code)
+	endSpan(span, err)
+	return err
+}
@@ -0,0 +1,848 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/internal/pkg/config"
+	"github.com/ThePotatoVerse/internal/pkg/validator"
+	"github.com/ThePotatoVerse/pkg/database"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/ThePotatoVerse/pkg/token"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// OAuth provider errors
+var (
+	ErrOAuthClientNotFound  = errors.New("oauth client not found")
+	ErrOAuthConsentNotFound = errors.New("oauth consent not found")
+
+	// ErrInvalidOAuthClient is returned for authorization requests with an
+	// unknown client or redirect URI, which cannot be redirected back to
+	ErrInvalidOAuthClient = errors.New("unknown oauth client or redirect uri")
+
+	// ErrInvalidConsent is returned for consent forms that are malformed,
+	// expired, or were shown to another user
+	ErrInvalidConsent = errors.New("invalid or expired consent form")
+
+	// ErrOAuthRequest is wrapped by every OAuthError
+	ErrOAuthRequest = errors.New("oauth request rejected")
+)
+
+// OAuth error codes, from RFC 6749 sections 4.1.2.1 and 5.2
+const (
+	OAuthInvalidRequest          = "invalid_request"
+	OAuthInvalidClient           = "invalid_client"
+	OAuthInvalidGrant            = "invalid_grant"
+	OAuthInvalidScope            = "invalid_scope"
+	OAuthAccessDenied            = "access_denied"
+	OAuthUnsupportedResponseType = "unsupported_response_type"
+	OAuthUnsupportedGrantType    = "unsupported_grant_type"
+)
+
+// OAuthError is an error response defined by OAuth 2.0. Errors of
+// authorization requests are sent back to the client by redirecting to
+// RedirectURL; errors of token requests are returned to the client directly.
+type OAuthError struct {
+	Code        string
+	Description string
+
+	redirectURI string
+	state       string
+	issuer      string
+}
+
+// Error implements error
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// Unwrap returns ErrOAuthRequest
+func (e *OAuthError) Unwrap() error {
+	return ErrOAuthRequest
+}
+
+// RedirectURL returns where to send the user agent to report the error to
+// the client, or "" if the error is not for an authorization request
+func (e *OAuthError) RedirectURL() string {
+	if e.redirectURI == "" {
+		return ""
+	}
+
+	params := url.Values{"error": {e.Code}, "error_description": {e.Description}}
+	if e.state != "" {
+		params.Set("state", e.state)
+	}
+	params.Set("iss", e.issuer)
+	return WithQuery(e.redirectURI, params)
+}
+
+// OAuth scopes. Access tokens always carry the openid scope; profile and
+// email release the matching OpenID Connect claims.
+const (
+	OAuthScopeOpenID  = "openid"
+	OAuthScopeProfile = "profile"
+	OAuthScopeEmail   = "email"
+)
+
+// OAuthScopes lists the scopes clients can request
+var OAuthScopes = []string{OAuthScopeOpenID, OAuthScopeProfile, OAuthScopeEmail}
+
+// PKCE code challenges are the unpadded base64url SHA-256 of a verifier of
+// 43 to 128 characters (RFC 7636)
+const (
+	pkceMethodS256        = "S256"
+	pkceChallengeLength   = 43
+	pkceVerifierMinLength = 43
+	pkceVerifierMaxLength = 128
+)
+
+// consentPurpose binds consent tokens to consent forms
+const consentPurpose = "oauth-consent"
+
+// NewOAuthClient describes an OAuth client to register. Confidential clients
+// get a secret; public clients do not.
+type NewOAuthClient struct {
+	Name         string
+	RedirectURIs []string
+	Confidential bool
+}
+
+// CreatedOAuthClient is a newly registered client together with its secret,
+// which cannot be retrieved again
+type CreatedOAuthClient struct {
+	model.OAuthClient
+	Secret string
+}
+
+// AuthorizationRequest is an OAuth 2.0 authorization request (RFC 6749
+// section 4.1.1), with a PKCE challenge and an OpenID Connect nonce
+type AuthorizationRequest struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state,omitempty"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+	Nonce               string `json:"nonce,omitempty"`
+}
+
+// Authorization is the outcome of an authorization request. Either
+// RedirectURL is set, sending the user back to the client with a code, or
+// ConsentToken is set, and the user must first approve the client for
+// Scopes by submitting it to Consent.
+type Authorization struct {
+	Client       model.OAuthClient
+	Scopes       []string
+	RedirectURL  string
+	ConsentToken string
+}
+
+// TokenRequest is an OAuth 2.0 access token request (RFC 6749 section
+// 4.1.3), with the client credentials and PKCE verifier
+type TokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	ClientID     string
+	ClientSecret string
+}
+
+// OAuthTokens is a successful access token response. IDToken is only set
+// for the openid scope.
+type OAuthTokens struct {
+	AccessToken string
+	ExpiresIn   time.Duration
+	IDToken     string
+	Scopes      []string
+}
+
+// UserClaims are the OpenID Connect standard claims about a user that the
+// granted scopes release
+type UserClaims struct {
+	Name          string `json:"name,omitempty"`
+	UpdatedAt     int64  `json:"updated_at,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+}
+
+// UserInfo is the response of the userinfo endpoint
+type UserInfo struct {
+	Subject string `json:"sub"`
+	UserClaims
+}
+
+// AuthorizedClient is a client a user has consented to, with the scopes
+// they allowed it
+type AuthorizedClient struct {
+	Client    model.OAuthClient
+	Scopes    []string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// consentClaims is the signed payload of a consent token
+type consentClaims struct {
+	UserID    string               `json:"sub"`
+	Request   AuthorizationRequest `json:"req"`
+	Scopes    []string             `json:"scopes"`
+	ExpiresAt int64                `json:"exp"`
+}
+
+// oauthAccessClaims are the claims of access tokens issued to clients
+type oauthAccessClaims struct {
+	jwt.RegisteredClaims
+	Scope    string `json:"scope"`
+	ClientID string `json:"client_id"`
+}
+
+// idTokenClaims are the claims of OpenID Connect ID tokens
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce string `json:"nonce,omitempty"`
+	UserClaims
+}
+
+// OAuthService defines the interface for the built-in OAuth 2.0 and OpenID
+// Connect provider, which lets registered clients sign users in with the
+// authorization code flow.
+//
+// Every authorization request must carry a PKCE S256 challenge, including
+// those of confidential clients. Users are asked to consent to a client
+// once per set of scopes, and consents are remembered per client until they
+// are revoked, which also stops the userinfo endpoint from answering for
+// tokens issued under them.
+type OAuthService interface {
+	CreateClient(ctx context.Context, input NewOAuthClient) (CreatedOAuthClient, error)
+	ListClients(ctx context.Context) ([]model.OAuthClient, error)
+	GetClient(ctx context.Context, id string) (model.OAuthClient, error)
+	DeleteClient(ctx context.Context, id string) error
+	Authorize(ctx context.Context, userID string, req AuthorizationRequest) (Authorization, error)
+	Consent(ctx context.Context, userID, consentToken string, approve bool) (Authorization, error)
+	Exchange(ctx context.Context, req TokenRequest) (OAuthTokens, error)
+	UserInfo(ctx context.Context, accessToken string) (UserInfo, error)
+	ListConsents(ctx context.Context, userID string) ([]AuthorizedClient, error)
+	RevokeConsent(ctx context.Context, userID, clientID string) error
+}
+
+// oauthService implements OAuthService
+type oauthService struct {
+	log       logger.Logger
+	clients   repository.OAuthClientRepository
+	consents  repository.OAuthConsentRepository
+	codes     repository.OAuthCodeRepository
+	userRepo  repository.UserRepository
+	txManager database.TxManager
+	validator *validator.Validator
+	keys      *token.KeySet
+	signer    *token.Signer
+	issuer    string
+	cfg       *config.AuthConfig
+}
+
+// NewOAuthService creates a new OAuth provider service. Tokens are signed
+// with keys and issued by issuer, the public URL of the server; consent
+// forms are signed with signer.
+func NewOAuthService(
+	log logger.Logger,
+	clients repository.OAuthClientRepository,
+	consents repository.OAuthConsentRepository,
+	codes repository.OAuthCodeRepository,
+	userRepo repository.UserRepository,
+	txManager database.TxManager,
+	validator *validator.Validator,
+	keys *token.KeySet,
+	signer *token.Signer,
+	issuer string,
+	cfg *config.AuthConfig,
+) OAuthService {
+	return &oauthService{
+		log:       log,
+		clients:   clients,
+		consents:  consents,
+		codes:     codes,
+		userRepo:  userRepo,
+		txManager: txManager,
+		validator: validator,
+		keys:      keys,
+		signer:    signer,
+		issuer:    strings.TrimSuffix(issuer, "/"),
+		cfg:       cfg,
+	}
+}
+
+// CreateClient registers a client, generating a secret for confidential ones
+func (s *oauthService) CreateClient(ctx context.Context, input NewOAuthClient) (CreatedOAuthClient, error) {
+	s.log.FromContext(ctx).Info("Creating OAuth client", "name", input.Name)
+
+	check := s.validator.Check()
+	input.Name = check.Name("name", input.Name)
+	if len(input.RedirectURIs) == 0 {
+		check.Fail("redirect_uris", "must not be empty")
+	}
+	for i, uri := range input.RedirectURIs {
+		if reason := checkRedirectURI(uri); reason != "" {
+			check.Fail(fmt.Sprintf("redirect_uris[%d]", i), reason)
+		}
+	}
+	if err := check.Err(); err != nil {
+		return CreatedOAuthClient{}, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+
+	client := model.OAuthClient{
+		ID:           uuid.New().String(),
+		Name:         input.Name,
+		RedirectURIs: input.RedirectURIs,
+		CreatedAt:    time.Now(),
+	}
+
+	var secret string
+	if input.Confidential {
+		var err error
+		if secret, err = newToken(); err != nil {
+			return CreatedOAuthClient{}, err
+		}
+		client.SecretHash = hashToken(secret)
+	}
+
+	if err := s.clients.Create(ctx, client); err != nil {
+		return CreatedOAuthClient{}, err
+	}
+
+	s.log.FromContext(ctx).Info("Created OAuth client", "client_id", client.ID)
+	return CreatedOAuthClient{OAuthClient: client, Secret: secret}, nil
+}
+
+// ListClients returns all registered clients
+func (s *oauthService) ListClients(ctx context.Context) ([]model.OAuthClient, error) {
+	return s.clients.List(ctx)
+}
+
+// GetClient returns a registered client
+func (s *oauthService) GetClient(ctx context.Context, id string) (model.OAuthClient, error) {
+	client, err := s.findClient(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return model.OAuthClient{}, ErrOAuthClientNotFound
+		}
+		return model.OAuthClient{}, err
+	}
+	return client, nil
+}
+
+// DeleteClient removes a client, together with the consents given to it and
+// its unused authorization codes. Access tokens already issued to it stay
+// valid until they expire, but no longer get answers from userinfo.
+func (s *oauthService) DeleteClient(ctx context.Context, id string) error {
+	s.log.FromContext(ctx).Info("Deleting OAuth client", "client_id", id)
+
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrOAuthClientNotFound
+	}
+	if err := s.clients.Delete(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrOAuthClientNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Authorize handles an authorization request of the user with userID. If the
+// user has already consented to the requested scopes, it issues a code right
+// away; otherwise it returns a consent token to ask them first.
+//
+// Requests with an unknown client or redirect URI fail with
+// ErrInvalidOAuthClient, since redirecting to an unverified URI would make
+// the server an open redirector. Other invalid requests fail with an
+// OAuthError to redirect back to the client.
+func (s *oauthService) Authorize(ctx context.Context, userID string, req AuthorizationRequest) (Authorization, error) {
+	client, err := s.findClient(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return Authorization{}, ErrInvalidOAuthClient
+		}
+		return Authorization{}, err
+	}
+	if !client.AllowsRedirect(req.RedirectURI) {
+		s.log.FromContext(ctx).Warn("Unregistered OAuth redirect URI", "client_id", client.ID)
+		return Authorization{}, ErrInvalidOAuthClient
+	}
+
+	fail := func(code, description string) (Authorization, error) {
+		return Authorization{}, &OAuthError{
+			Code:        code,
+			Description: description,
+			redirectURI: req.RedirectURI,
+			state:       req.State,
+			issuer:      s.issuer,
+		}
+	}
+
+	if req.ResponseType != "code" {
+		return fail(OAuthUnsupportedResponseType, "response_type must be code")
+	}
+	scopes, ok := parseScopes(req.Scope)
+	if !ok {
+		return fail(OAuthInvalidScope, "supported scopes are "+strings.Join(OAuthScopes, ", "))
+	}
+	if req.CodeChallenge == "" {
+		return fail(OAuthInvalidRequest, "code_challenge is required")
+	}
+	if req.CodeChallengeMethod != pkceMethodS256 {
+		return fail(OAuthInvalidRequest, "code_challenge_method must be S256")
+	}
+	if len(req.CodeChallenge) != pkceChallengeLength {
+		return fail(OAuthInvalidRequest, "code_challenge must be a base64url SHA-256 hash")
+	}
+
+	consent, err := s.consents.Get(ctx, userID, client.ID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return Authorization{}, err
+	}
+	if err == nil && consent.Covers(scopes) {
+		redirectURL, err := s.issueCode(ctx, client, userID, req, scopes)
+		if err != nil {
+			return Authorization{}, err
+		}
+		return Authorization{Client: client, Scopes: scopes, RedirectURL: redirectURL}, nil
+	}
+
+	consentToken, err := s.signer.Sign(consentPurpose, consentClaims{
+		UserID:    userID,
+		Request:   req,
+		Scopes:    scopes,
+		ExpiresAt: time.Now().Add(s.cfg.OAuth.ConsentTTL).Unix(),
+	})
+	if err != nil {
+		return Authorization{}, err
+	}
+
+	return Authorization{Client: client, Scopes: scopes, ConsentToken: consentToken}, nil
+}
+
+// Consent records the decision of a user on a consent form returned by
+// Authorize. If they approve, the consent is remembered and a code is
+// issued; if not, the client is told that access was denied.
+func (s *oauthService) Consent(ctx context.Context, userID, consentToken string, approve bool) (Authorization, error) {
+	var claims consentClaims
+	if err := s.signer.Verify(consentPurpose, consentToken, &claims); err != nil {
+		return Authorization{}, ErrInvalidConsent
+	}
+	if claims.UserID != userID || time.Now().Unix() >= claims.ExpiresAt {
+		return Authorization{}, ErrInvalidConsent
+	}
+
+	req := claims.Request
+	client, err := s.findClient(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return Authorization{}, ErrInvalidOAuthClient
+		}
+		return Authorization{}, err
+	}
+	if !client.AllowsRedirect(req.RedirectURI) {
+		return Authorization{}, ErrInvalidOAuthClient
+	}
+
+	if !approve {
+		s.log.FromContext(ctx).Info("OAuth consent denied", "user_id", userID, "client_id", client.ID)
+		return Authorization{}, &OAuthError{
+			Code:        OAuthAccessDenied,
+			Description: "the user denied access",
+			redirectURI: req.RedirectURI,
+			state:       req.State,
+			issuer:      s.issuer,
+		}
+	}
+
+	var redirectURL string
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		now := time.Now()
+		consent, err := s.consents.Get(ctx, userID, client.ID)
+		if err != nil {
+			if !errors.Is(err, repository.ErrNotFound) {
+				return err
+			}
+			consent = model.OAuthConsent{UserID: userID, ClientID: client.ID, CreatedAt: now}
+		}
+		consent.Scopes = mergeScopes(consent.Scopes, claims.Scopes)
+		consent.UpdatedAt = now
+
+		if err := s.consents.Save(ctx, consent); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return ErrInvalidConsent
+			}
+			return err
+		}
+
+		redirectURL, err = s.issueCode(ctx, client, userID, req, claims.Scopes)
+		return err
+	})
+	if err != nil {
+		return Authorization{}, err
+	}
+
+	s.log.FromContext(ctx).Info("OAuth consent given", "user_id", userID, "client_id", client.ID)
+	return Authorization{Client: client, Scopes: claims.Scopes, RedirectURL: redirectURL}, nil
+}
+
+// Exchange redeems an authorization code for an access token and, for the
+// openid scope, an ID token. Codes are single use, even when the exchange
+// fails.
+func (s *oauthService) Exchange(ctx context.Context, req TokenRequest) (OAuthTokens, error) {
+	fail := func(code, description string) (OAuthTokens, error) {
+		return OAuthTokens{}, &OAuthError{Code: code, Description: description}
+	}
+
+	if req.GrantType == "" {
+		return fail(OAuthInvalidRequest, "grant_type is required")
+	}
+	if req.GrantType != "authorization_code" {
+		return fail(OAuthUnsupportedGrantType, "grant_type must be authorization_code")
+	}
+
+	client, err := s.findClient(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fail(OAuthInvalidClient, "unknown client")
+		}
+		return OAuthTokens{}, err
+	}
+	if client.Confidential() != (req.ClientSecret != "") ||
+		client.Confidential() && subtle.ConstantTimeCompare([]byte(hashToken(req.ClientSecret)), []byte(client.SecretHash)) != 1 {
+		s.log.FromContext(ctx).Warn("OAuth client authentication failed", "client_id", client.ID)
+		return fail(OAuthInvalidClient, "client authentication failed")
+	}
+
+	if req.Code == "" || req.CodeVerifier == "" {
+		return fail(OAuthInvalidRequest, "code and code_verifier are required")
+	}
+
+	code, err := s.codes.Consume(ctx, hashToken(req.Code))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fail(OAuthInvalidGrant, "invalid or expired code")
+		}
+		return OAuthTokens{}, err
+	}
+	if code.Expired(time.Now()) || code.ClientID != client.ID {
+		return fail(OAuthInvalidGrant, "invalid or expired code")
+	}
+	if req.RedirectURI != code.RedirectURI {
+		return fail(OAuthInvalidGrant, "redirect_uri does not match the authorization request")
+	}
+	if !verifyPKCE(req.CodeVerifier, code.CodeChallenge) {
+		s.log.FromContext(ctx).Warn("OAuth code verifier mismatch", "client_id", client.ID)
+		return fail(OAuthInvalidGrant, "code_verifier does not match the code_challenge")
+	}
+
+	user, err := s.userRepo.FindByID(ctx, code.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fail(OAuthInvalidGrant, "the user no longer exists")
+		}
+		return OAuthTokens{}, err
+	}
+
+	now := time.Now()
+	accessToken, err := s.keys.SignClaims(&oauthAccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Issuer:    s.issuer,
+			Subject:   user.ID,
+			Audience:  jwt.ClaimStrings{client.ID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.cfg.JWT.AccessTokenTTL)),
+		},
+		Scope:    strings.Join(code.Scopes, " "),
+		ClientID: client.ID,
+	})
+	if err != nil {
+		return OAuthTokens{}, err
+	}
+
+	tokens := OAuthTokens{
+		AccessToken: accessToken,
+		ExpiresIn:   s.cfg.JWT.AccessTokenTTL,
+		Scopes:      code.Scopes,
+	}
+
+	if containsScope(code.Scopes, OAuthScopeOpenID) {
+		tokens.IDToken, err = s.keys.SignClaims(&idTokenClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    s.issuer,
+				Subject:   user.ID,
+				Audience:  jwt.ClaimStrings{client.ID},
+				IssuedAt:  jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(now.Add(s.cfg.OAuth.IDTokenTTL)),
+			},
+			Nonce:      code.Nonce,
+			UserClaims: userClaims(user, code.Scopes),
+		})
+		if err != nil {
+			return OAuthTokens{}, err
+		}
+	}
+
+	s.log.FromContext(ctx).Info("Issued OAuth tokens", "user_id", user.ID, "client_id", client.ID)
+	return tokens, nil
+}
+
+// UserInfo returns the claims about the user an access token was issued
+// for. Tokens stop working here once the user revokes their consent or the
+// client is deleted.
+func (s *oauthService) UserInfo(ctx context.Context, accessToken string) (UserInfo, error) {
+	var claims oauthAccessClaims
+	if err := s.keys.ParseClaims(accessToken, &claims, s.issuer); err != nil {
+		return UserInfo{}, ErrInvalidToken
+	}
+	if claims.ClientID == "" {
+		return UserInfo{}, ErrInvalidToken
+	}
+
+	scopes := strings.Fields(claims.Scope)
+	if !containsScope(scopes, OAuthScopeOpenID) {
+		return UserInfo{}, ErrForbidden
+	}
+
+	consent, err := s.consents.Get(ctx, claims.Subject, claims.ClientID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return UserInfo{}, ErrInvalidToken
+		}
+		return UserInfo{}, err
+	}
+	if !consent.Covers(scopes) {
+		return UserInfo{}, ErrInvalidToken
+	}
+
+	user, err := s.userRepo.FindByID(ctx, claims.Subject)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return UserInfo{}, ErrInvalidToken
+		}
+		return UserInfo{}, err
+	}
+
+	return UserInfo{Subject: user.ID, UserClaims: userClaims(user, scopes)}, nil
+}
+
+// ListConsents returns the clients a user has consented to
+func (s *oauthService) ListConsents(ctx context.Context, userID string) ([]AuthorizedClient, error) {
+	if _, err := s.userRepo.FindByID(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	consents, err := s.consents.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	authorized := make([]AuthorizedClient, 0, len(consents))
+	for _, consent := range consents {
+		client, err := s.clients.FindByID(ctx, consent.ClientID)
+		if err != nil {
+			// Consents to deleted clients are left behind by storage
+			// without cascades, and no longer grant anything
+			if errors.Is(err, repository.ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+
+		authorized = append(authorized, AuthorizedClient{
+			Client:    client,
+			Scopes:    consent.Scopes,
+			CreatedAt: consent.CreatedAt,
+			UpdatedAt: consent.UpdatedAt,
+		})
+	}
+
+	return authorized, nil
+}
+
+// RevokeConsent removes the consent of a user to a client, so that the
+// client has to ask again and its tokens no longer get answers from
+// userinfo
+func (s *oauthService) RevokeConsent(ctx context.Context, userID, clientID string) error {
+	s.log.FromContext(ctx).Info("Revoking OAuth consent", "user_id", userID, "client_id", clientID)
+
+	if _, err := uuid.Parse(clientID); err != nil {
+		return ErrOAuthConsentNotFound
+	}
+	if err := s.consents.Delete(ctx, userID, clientID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrOAuthConsentNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// findClient returns the client with id, or repository.ErrNotFound. IDs
+// that are not UUIDs, which clients can send anything as, are never found.
+func (s *oauthService) findClient(ctx context.Context, id string) (model.OAuthClient, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return model.OAuthClient{}, repository.ErrNotFound
+	}
+	return s.clients.FindByID(ctx, id)
+}
+
+// issueCode stores a new authorization code for an approved request and
+// returns the URL that hands it to the client
+func (s *oauthService) issueCode(ctx context.Context, client model.OAuthClient, userID string, req AuthorizationRequest, scopes []string) (string, error) {
+	now := time.Now()
+
+	// Expired codes can no longer be exchanged, so there is no need to keep
+	// them
+	if _, err := s.codes.DeleteExpired(ctx, now); err != nil {
+		s.log.FromContext(ctx).Warn("Failed to delete expired authorization codes", "error", err)
+	}
+
+	value, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.codes.Create(ctx, model.OAuthAuthorizationCode{
+		ID:            hashToken(value),
+		ClientID:      client.ID,
+		UserID:        userID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+		Nonce:         req.Nonce,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(s.cfg.OAuth.CodeTTL),
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", ErrInvalidOAuthClient
+		}
+		return "", err
+	}
+
+	params := url.Values{"code": {value}}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	params.Set("iss", s.issuer)
+	return WithQuery(req.RedirectURI, params), nil
+}
+
+// userClaims returns the claims about user that scopes release
+func userClaims(user model.User, scopes []string) UserClaims {
+	var claims UserClaims
+	if containsScope(scopes, OAuthScopeProfile) {
+		claims.Name = user.Name
+		claims.UpdatedAt = user.UpdatedAt.Unix()
+	}
+	if containsScope(scopes, OAuthScopeEmail) {
+		verified := user.EmailVerified()
+		claims.Email = user.Email
+		claims.EmailVerified = &verified
+	}
+	return claims
+}
+
+// parseScopes parses a space-separated scope parameter, adding the openid
+// scope every token carries. It reports false for unknown scopes.
+func parseScopes(scope string) ([]string, bool) {
+	scopes := []string{OAuthScopeOpenID}
+	for _, requested := range strings.Fields(scope) {
+		if !containsScope(OAuthScopes, requested) {
+			return nil, false
+		}
+		if !containsScope(scopes, requested) {
+			scopes = append(scopes, requested)
+		}
+	}
+	return scopes, true
+}
+
+// mergeScopes returns the scopes in either a or b, in order
+func mergeScopes(a, b []string) []string {
+	merged := append([]string(nil), a...)
+	for _, scope := range b {
+		if !containsScope(merged, scope) {
+			merged = append(merged, scope)
+		}
+	}
+	return merged
+}
+
+// containsScope reports whether scopes includes scope
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyPKCE checks a code verifier against an S256 code challenge
+func verifyPKCE(verifier, challenge string) bool {
+	if len(verifier) < pkceVerifierMinLength || len(verifier) > pkceVerifierMaxLength {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// checkRedirectURI returns why uri cannot be registered as a redirect URI,
+// or "" if it can. Redirect URIs must be absolute and without a fragment,
+// and use https, http on a loopback address for native apps, or a private
+// scheme such as com.example.app (RFC 8252).
+func checkRedirectURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() {
+		return "must be an absolute URI"
+	}
+	if u.Fragment != "" || strings.Contains(uri, "#") {
+		return "must not have a fragment"
+	}
+
+	switch u.Scheme {
+	case "https":
+		if u.Host == "" {
+			return "must have a host"
+		}
+	case "http":
+		host := u.Hostname()
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return "must use https, unless it is on a loopback address"
+		}
+	default:
+		if !strings.Contains(u.Scheme, ".") {
+			return "must use https or a private scheme such as com.example.app"
+		}
+	}
+	return ""
+}
+
+// WithQuery adds params to the query of uri, keeping any it already has
+func WithQuery(uri string, params url.Values) string {
+	if strings.Contains(uri, "?") {
+		return uri + "&" + params.Encode()
+	}
+	return uri + "?" + params.Encode()
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// oauthServiceMetrics decorates an OAuthService with per-operation counters
+type oauthServiceMetrics struct {
+	next       OAuthService
+	operations *prometheus.CounterVec
+}
+
+// NewOAuthServiceMetrics wraps next so that every call is counted by
+// operation and result in oauth_service_operations_total
+func NewOAuthServiceMetrics(next OAuthService, registerer prometheus.Registerer) OAuthService {
+	operations := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "oauth_service_operations_total",
+		Help: "Total number of OAuth provider service operations by operation and result.",
+	}, []string{"operation", "result"})
+
+	registerer.MustRegister(operations)
+
+	return &oauthServiceMetrics{
+		next:       next,
+		operations: operations,
+	}
+}
+
+// CreateClient implements OAuthService
+func (m *oauthServiceMetrics) CreateClient(ctx context.Context, input NewOAuthClient) (CreatedOAuthClient, error) {
+	client, err := m.next.CreateClient(ctx, input)
+	observeOperation(m.operations, "create_client", err)
+	return client, err
+}
+
+// ListClients implements OAuthService
+func (m *oauthServiceMetrics) ListClients(ctx context.Context) ([]model.OAuthClient, error) {
+	clients, err := m.next.ListClients(ctx)
+	observeOperation(m.operations, "list_clients", err)
+	return clients, err
+}
+
+// GetClient implements OAuthService
+func (m *oauthServiceMetrics) GetClient(ctx context.Context, id string) (model.OAuthClient, error) {
+	client, err := m.next.GetClient(ctx, id)
+	observeOperation(m.operations, "get_client", err)
+	return client, err
+}
+
+// DeleteClient implements OAuthService
+func (m *oauthServiceMetrics) DeleteClient(ctx context.Context, id string) error {
+	err := m.next.DeleteClient(ctx, id)
+	observeOperation(m.operations, "delete_client", err)
+	return err
+}
+
+// Authorize implements OAuthService
+func (m *oauthServiceMetrics) Authorize(ctx context.Context, userID string, req AuthorizationRequest) (Authorization, error) {
+	authorization, err := m.next.Authorize(ctx, userID, req)
+	observeOperation(m.operations, "authorize", err)
+	return authorization, err
+}
+
+// Consent implements OAuthService
+func (m *oauthServiceMetrics) Consent(ctx context.Context, userID, consentToken string, approve bool) (Authorization, error) {
+	authorization, err := m.next.Consent(ctx, userID, consentToken, approve)
+	observeOperation(m.operations, "consent", err)
+	return authorization, err
+}
+
+// Exchange implements OAuthService
+func (m *oauthServiceMetrics) Exchange(ctx context.Context, req TokenRequest) (OAuthTokens, error) {
+	tokens, err := m.next.Exchange(ctx, req)
+	observeOperation(m.operations, "exchange", err)
+	return tokens, err
+}
+
+// UserInfo implements OAuthService
+func (m *oauthServiceMetrics) UserInfo(ctx context.Context, accessToken string) (UserInfo, error) {
+	info, err := m.next.UserInfo(ctx, accessToken)
+	observeOperation(m.operations, "user_info", err)
+	return info, err
+}
+
+// ListConsents implements OAuthService
+func (m *oauthServiceMetrics) ListConsents(ctx context.Context, userID string) ([]AuthorizedClient, error) {
+	clients, err := m.next.ListConsents(ctx, userID)
+	observeOperation(m.operations, "list_consents", err)
+	return clients, err
+}
+
+// RevokeConsent implements OAuthService
+func (m *oauthServiceMetrics) RevokeConsent(ctx context.Context, userID, clientID string) error {
+	err := m.next.RevokeConsent(ctx, userID, clientID)
+	observeOperation(m.operations, "revoke_consent", err)
+	return err
+}
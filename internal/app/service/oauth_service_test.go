@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository/memory"
+	"github.com/ThePotatoVerse/internal/pkg/config"
+	"github.com/ThePotatoVerse/internal/pkg/validator"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/ThePotatoVerse/pkg/token"
+)
+
+// TestPKCE runs the authorization code flow with PKCE challenges and
+// verifiers, checking the OAuth error code each step fails with
+func TestPKCE(t *testing.T) {
+	verifier := strings.Repeat("v", pkceVerifierMinLength)
+	shortVerifier := strings.Repeat("v", pkceVerifierMinLength-1)
+
+	tests := []struct {
+		name             string
+		challenge        string
+		method           string
+		verifier         string
+		wantAuthorizeErr string
+		wantExchangeErr  string
+	}{
+		{
+			name:      "matching verifier",
+			challenge: pkceChallenge(verifier),
+			method:    "S256",
+			verifier:  verifier,
+		},
+		{
+			name:             "missing challenge",
+			method:           "S256",
+			wantAuthorizeErr: OAuthInvalidRequest,
+		},
+		{
+			name:             "plain method",
+			challenge:        verifier,
+			method:           "plain",
+			wantAuthorizeErr: OAuthInvalidRequest,
+		},
+		{
+			name:             "challenge that is not a hash",
+			challenge:        "abc",
+			method:           "S256",
+			wantAuthorizeErr: OAuthInvalidRequest,
+		},
+		{
+			name:            "missing verifier",
+			challenge:       pkceChallenge(verifier),
+			method:          "S256",
+			wantExchangeErr: OAuthInvalidRequest,
+		},
+		{
+			name:            "wrong verifier",
+			challenge:       pkceChallenge(verifier),
+			method:          "S256",
+			verifier:        strings.Repeat("w", pkceVerifierMinLength),
+			wantExchangeErr: OAuthInvalidGrant,
+		},
+		{
+			name:            "verifier too short",
+			challenge:       pkceChallenge(shortVerifier),
+			method:          "S256",
+			verifier:        shortVerifier,
+			wantExchangeErr: OAuthInvalidGrant,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, client, user := newTestOAuthService(t)
+			redirectURI := client.RedirectURIs[0]
+
+			authorization, err := s.Authorize(ctx, user.ID, AuthorizationRequest{
+				ResponseType:        "code",
+				ClientID:            client.ID,
+				RedirectURI:         redirectURI,
+				Scope:               "openid",
+				CodeChallenge:       tt.challenge,
+				CodeChallengeMethod: tt.method,
+			})
+			if code := oauthErrorCode(t, err); code != tt.wantAuthorizeErr {
+				t.Fatalf("authorize: got error %q, want %q", code, tt.wantAuthorizeErr)
+			}
+			if err != nil {
+				return
+			}
+
+			authorization, err = s.Consent(ctx, user.ID, authorization.ConsentToken, true)
+			if err != nil {
+				t.Fatalf("consenting: %v", err)
+			}
+			redirect, err := url.Parse(authorization.RedirectURL)
+			if err != nil {
+				t.Fatalf("parsing redirect URL: %v", err)
+			}
+
+			_, err = s.Exchange(ctx, TokenRequest{
+				GrantType:    "authorization_code",
+				Code:         redirect.Query().Get("code"),
+				RedirectURI:  redirectURI,
+				CodeVerifier: tt.verifier,
+				ClientID:     client.ID,
+			})
+			if code := oauthErrorCode(t, err); code != tt.wantExchangeErr {
+				t.Fatalf("exchange: got error %q, want %q", code, tt.wantExchangeErr)
+			}
+		})
+	}
+}
+
+// newTestOAuthService creates an OAuth service backed by memory
+// repositories, together with a public client and a user to authorize it
+func newTestOAuthService(t *testing.T) (OAuthService, model.OAuthClient, model.User) {
+	t.Helper()
+
+	log := logger.New()
+	cfg := &config.AuthConfig{
+		JWT: config.JWTConfig{AccessTokenTTL: 15 * time.Minute},
+		OAuth: config.OAuthConfig{
+			CodeTTL:    time.Minute,
+			IDTokenTTL: time.Hour,
+			ConsentTTL: 10 * time.Minute,
+		},
+	}
+
+	keys, err := token.LoadKeySet(&cfg.JWT, log)
+	if err != nil {
+		t.Fatalf("loading keys: %v", err)
+	}
+	signer, err := token.NewSigner("test secret", log)
+	if err != nil {
+		t.Fatalf("creating signer: %v", err)
+	}
+
+	tx := memory.NewTxManager()
+	userRepo := memory.NewUserRepository(tx)
+	user, err := userRepo.Create(context.Background(), model.User{Name: "Ada", Email: "ada@example.com", Role: model.RoleMember})
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+
+	validator := validator.New(&config.ValidationConfig{NameMinLength: 1, NameMaxLength: 100, PasswordMinLength: 8})
+	s := NewOAuthService(log, memory.NewOAuthClientRepository(tx), memory.NewOAuthConsentRepository(tx),
+		memory.NewOAuthCodeRepository(tx), userRepo, tx, validator, keys, signer, "https://id.example.com", cfg)
+
+	client, err := s.CreateClient(context.Background(), NewOAuthClient{
+		Name:         "Example app",
+		RedirectURIs: []string{"https://app.example.com/callback"},
+	})
+	if err != nil {
+		t.Fatalf("creating client: %v", err)
+	}
+
+	return s, client.OAuthClient, user
+}
+
+// pkceChallenge returns the S256 challenge of verifier
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// oauthErrorCode returns the OAuth error code of err, or "" for nil
+func oauthErrorCode(t *testing.T, err error) string {
+	t.Helper()
+
+	if err == nil {
+		return ""
+	}
+	var oauthErr *OAuthError
+	if !errors.As(err, &oauthErr) {
+		t.Fatalf("got error %v, want an OAuth error", err)
+	}
+	return oauthErr.Code
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// oauthServiceTracing decorates an OAuthService with a span per method.
+// Secrets, codes and tokens are never recorded.
+type oauthServiceTracing struct {
+	next   OAuthService
+	tracer trace.Tracer
+}
+
+// NewOAuthServiceTracing wraps next so that every call runs in a child span
+func NewOAuthServiceTracing(next OAuthService) OAuthService {
+	return &oauthServiceTracing{
+		next:   next,
+		tracer: otel.Tracer(tracerName),
+	}
+}
+
+// start starts the span of an operation
+func (t *oauthServiceTracing) start(ctx context.Context, operation string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, "OAuthService."+operation, trace.WithAttributes(attributes...))
+}
+
+// CreateClient implements OAuthService
+func (t *oauthServiceTracing) CreateClient(ctx context.Context, input NewOAuthClient) (CreatedOAuthClient, error) {
+	ctx, span := t.start(ctx, "CreateClient")
+	client, err := t.next.CreateClient(ctx, input)
+	endSpan(span, err)
+	return client, err
+}
+
+// ListClients implements OAuthService
+func (t *oauthServiceTracing) ListClients(ctx context.Context) ([]model.OAuthClient, error) {
+	ctx, span := t.start(ctx, "ListClients")
+	clients, err := t.next.ListClients(ctx)
+	endSpan(span, err)
+	return clients, err
+}
+
+// GetClient implements OAuthService
+func (t *oauthServiceTracing) GetClient(ctx context.Context, id string) (model.OAuthClient, error) {
+	ctx, span := t.start(ctx, "GetClient", attribute.String("oauth.client_id", id))
+	client, err := t.next.GetClient(ctx, id)
+	endSpan(span, err)
+	return client, err
+}
+
+// DeleteClient implements OAuthService
+func (t *oauthServiceTracing) DeleteClient(ctx context.Context, id string) error {
+	ctx, span := t.start(ctx, "DeleteClient", attribute.String("oauth.client_id", id))
+	err := t.next.DeleteClient(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+// Authorize implements OAuthService
+func (t *oauthServiceTracing) Authorize(ctx context.Context, userID string, req AuthorizationRequest) (Authorization, error) {
+	ctx, span := t.start(ctx, "Authorize", attribute.String("user.id", userID), attribute.String("oauth.client_id", req.ClientID))
+	authorization, err := t.next.Authorize(ctx, userID, req)
+	endSpan(span, err)
+	return authorization, err
+}
+
+// Consent implements OAuthService
+func (t *oauthServiceTracing) Consent(ctx context.Context, userID, consentToken string, approve bool) (Authorization, error) {
+	ctx, span := t.start(ctx, "Consent", attribute.String("user.id", userID), attribute.Bool("oauth.approved", approve))
+	authorization, err := t.next.Consent(ctx, userID, consentToken, approve)
+	endSpan(span, err)
+	return authorization, err
+}
+
+// Exchange implements OAuthService
+func (t *oauthServiceTracing) Exchange(ctx context.Context, req TokenRequest) (OAuthTokens, error) {
+	ctx, span := t.start(ctx, "Exchange", attribute.String("oauth.client_id", req.ClientID))
+	tokens, err := t.next.Exchange(ctx, req)
+	endSpan(span, err)
+	return tokens, err
+}
+
+// UserInfo implements OAuthService
+func (t *oauthServiceTracing) UserInfo(ctx context.Context, accessToken string) (UserInfo, error) {
+	ctx, span := t.start(ctx, "UserInfo")
+	info, err := t.next.UserInfo(ctx, accessToken)
+	endSpan(span, err)
+	return info, err
+}
+
+// ListConsents implements OAuthService
+func (t *oauthServiceTracing) ListConsents(ctx context.Context, userID string) ([]AuthorizedClient, error) {
+	ctx, span := t.start(ctx, "ListConsents", attribute.String("user.id", userID))
+	clients, err := t.next.ListConsents(ctx, userID)
+	endSpan(span, err)
+	return clients, err
+}
+
+// RevokeConsent implements OAuthService
+func (t *oauthServiceTracing) RevokeConsent(ctx context.Context, userID, clientID string) error {
+	ctx, span := t.start(ctx, "RevokeConsent", attribute.String("user.id", userID), attribute.String("oauth.client_id", clientID))
+	err := t.next.RevokeConsent(ctx, userID, clientID)
+	endSpan(span, err)
+	return err
+}
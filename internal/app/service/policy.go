@@ -0,0 +1,179 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+)
+
+// ErrForbidden is returned when the principal lacks the permission for an action
+var ErrForbidden = errors.New("permission denied")
+
+// Action is something a principal can be permitted to do
+type Action string
+
+// Actions on users
+const (
+	ActionListUsers       Action = "users:list"
+	ActionReadUser        Action = "users:read"
+	ActionReadDeletedUser Action = "users:read_deleted"
+	ActionUpdateUser      Action = "users:update"
+	ActionDeleteUser      Action = "users:delete"
+	ActionHardDeleteUser  Action = "users:hard_delete"
+	ActionRestoreUser     Action = "users:restore"
+	ActionSetUserRole     Action = "users:set_role"
+	ActionCreateAPIKey    Action = "api_keys:create"
+	ActionListAPIKeys     Action = "api_keys:list"
+	ActionRevokeAPIKey    Action = "api_keys:revoke"
+	ActionManageMFA       Action = "mfa:manage"
+	ActionDisableMFA      Action = "mfa:disable"
+
+	ActionResendVerification Action = "users:resend_verification"
+
+	ActionManageOAuthClients Action = "oauth_clients:manage"
+	ActionListOAuthConsents  Action = "oauth_consents:list"
+	ActionRevokeOAuthConsent Action = "oauth_consents:revoke"
+
+	ActionLinkIdentity   Action = "identities:link"
+	ActionListIdentities Action = "identities:list"
+	ActionUnlinkIdentity Action = "identities:unlink"
+)
+
+// Actions lists every action, which are also the scopes an API key can be
+// limited to
+var Actions = []Action{
+	ActionListUsers,
+	ActionReadUser,
+	ActionReadDeletedUser,
+	ActionUpdateUser,
+	ActionDeleteUser,
+	ActionHardDeleteUser,
+	ActionRestoreUser,
+	ActionSetUserRole,
+	ActionCreateAPIKey,
+	ActionListAPIKeys,
+	ActionRevokeAPIKey,
+	ActionManageMFA,
+	ActionDisableMFA,
+	ActionResendVerification,
+	ActionManageOAuthClients,
+	ActionListOAuthConsents,
+	ActionRevokeOAuthConsent,
+	ActionLinkIdentity,
+	ActionListIdentities,
+	ActionUnlinkIdentity,
+}
+
+// Valid reports whether a is a known action
+func (a Action) Valid() bool {
+	for _, action := range Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Scope limits the users a permission applies to
+type Scope int
+
+// Permission scopes
+const (
+	// ScopeOwn permits the action on the principal's own user only
+	ScopeOwn Scope = iota + 1
+	// ScopeAny permits the action on every user
+	ScopeAny
+)
+
+// rolePermissions is the permissions table: the actions each role may
+// perform, and on which users. Actions missing from a role are denied. API
+// keys and second factors can only be set up for oneself, never on behalf of
+// another user, but admins can disable the second factor of a user who lost
+// it. Every user can ask for their own verification email, which is how they
+// become admins when listed in auth.admin_emails. Only admins register OAuth
+// clients.
+var rolePermissions = map[model.Role]map[Action]Scope{
+	model.RoleAdmin: {
+		ActionListUsers:       ScopeAny,
+		ActionReadUser:        ScopeAny,
+		ActionReadDeletedUser: ScopeAny,
+		ActionUpdateUser:      ScopeAny,
+		ActionDeleteUser:      ScopeAny,
+		ActionHardDeleteUser:  ScopeAny,
+		ActionRestoreUser:     ScopeAny,
+		ActionSetUserRole:     ScopeAny,
+		ActionCreateAPIKey:    ScopeOwn,
+		ActionListAPIKeys:     ScopeAny,
+		ActionRevokeAPIKey:    ScopeAny,
+		ActionManageMFA:       ScopeOwn,
+		ActionDisableMFA:      ScopeAny,
+
+		ActionResendVerification: ScopeAny,
+
+		ActionManageOAuthClients: ScopeAny,
+		ActionListOAuthConsents:  ScopeAny,
+		ActionRevokeOAuthConsent: ScopeAny,
+
+		ActionLinkIdentity:   ScopeOwn,
+		ActionListIdentities: ScopeAny,
+		ActionUnlinkIdentity: ScopeAny,
+	},
+	model.RoleMember: {
+		ActionListUsers:    ScopeAny,
+		ActionReadUser:     ScopeAny,
+		ActionUpdateUser:   ScopeOwn,
+		ActionDeleteUser:   ScopeOwn,
+		ActionCreateAPIKey: ScopeOwn,
+		ActionListAPIKeys:  ScopeOwn,
+		ActionRevokeAPIKey: ScopeOwn,
+		ActionManageMFA:    ScopeOwn,
+		ActionDisableMFA:   ScopeOwn,
+
+		ActionResendVerification: ScopeOwn,
+
+		ActionListOAuthConsents:  ScopeOwn,
+		ActionRevokeOAuthConsent: ScopeOwn,
+
+		ActionLinkIdentity:   ScopeOwn,
+		ActionListIdentities: ScopeOwn,
+		ActionUnlinkIdentity: ScopeOwn,
+	},
+	model.RoleReadOnly: {
+		ActionListUsers:    ScopeAny,
+		ActionReadUser:     ScopeAny,
+		ActionCreateAPIKey: ScopeOwn,
+		ActionListAPIKeys:  ScopeOwn,
+		ActionRevokeAPIKey: ScopeOwn,
+		ActionManageMFA:    ScopeOwn,
+		ActionDisableMFA:   ScopeOwn,
+
+		ActionResendVerification: ScopeOwn,
+
+		ActionListOAuthConsents:  ScopeOwn,
+		ActionRevokeOAuthConsent: ScopeOwn,
+
+		ActionLinkIdentity:   ScopeOwn,
+		ActionListIdentities: ScopeOwn,
+		ActionUnlinkIdentity: ScopeOwn,
+	},
+}
+
+// Authorize checks that principal may perform action on the user with
+// targetID, which is empty for actions on the user collection. Principals
+// limited to scopes must also have the action among them. It returns
+// ErrForbidden if not.
+func Authorize(principal Principal, action Action, targetID string) error {
+	if !principal.HasScope(action) {
+		return ErrForbidden
+	}
+
+	switch rolePermissions[principal.Role][action] {
+	case ScopeAny:
+		return nil
+	case ScopeOwn:
+		if targetID != "" && targetID == principal.UserID {
+			return nil
+		}
+	}
+	return ErrForbidden
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+)
+
+// Authentication methods
+const (
+	AuthMethodSession = "session"
+	AuthMethodToken   = "token"
+	AuthMethodAPIKey  = "api_key"
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	UserID string
+	Role   model.Role
+	Method string
+
+	// SessionID identifies the login session, if authenticated with one
+	SessionID string
+
+	// TokenID is the jti claim of the access token, if authenticated with one
+	TokenID string
+
+	// APIKeyID identifies the API key, if authenticated with one
+	APIKeyID string
+
+	// Scopes limits the actions of the principal, within those its role
+	// permits. Nil means no limit.
+	Scopes []Action
+}
+
+// HasScope reports whether the scopes of the principal include action
+func (p Principal) HasScope(action Action) bool {
+	if p.Scopes == nil {
+		return true
+	}
+	for _, scope := range p.Scopes {
+		if scope == action {
+			return true
+		}
+	}
+	return false
+}
+
+// principalKey is the context key holding the Principal
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx carrying the principal
+func ContextWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal carried by ctx, if any
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
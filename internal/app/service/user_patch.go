@@ -0,0 +1,151 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+// Patch errors
+var (
+	ErrInvalidPatch     = errors.New("invalid patch document")
+	ErrPatchFailed      = errors.New("patch cannot be applied")
+	ErrReadOnlyField    = errors.New("patch modifies a read-only field")
+	ErrUnsupportedPatch = errors.New("unsupported patch type")
+)
+
+// PatchType identifies the format of a patch document by its media type
+type PatchType string
+
+// Supported patch types
+const (
+	MergePatch PatchType = "application/merge-patch+json"
+	JSONPatch  PatchType = "application/json-patch+json"
+)
+
+// Patch applies a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902)
+// document to a user. When version is non-zero it must match the stored
+// version. Only fields whose values change are written.
+func (s *userService) Patch(ctx context.Context, id string, version int, patchType PatchType, patch []byte) (model.User, error) {
+	s.log.FromContext(ctx).Info("Patching user", "id", id, "version", version, "type", patchType)
+
+	var updatedUser model.User
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// Check if user exists
+		existing, err := s.userRepo.FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return mapWriteError(err)
+		}
+
+		// Check version
+		if version != 0 && version != existing.Version {
+			return ErrVersionMismatch
+		}
+
+		patched, err := applyPatch(existing, patchType, patch)
+		if err != nil {
+			return err
+		}
+
+		// Validate user
+		patched, err = s.validateUser(patched)
+		if err != nil {
+			return err
+		}
+
+		// Collect changed fields
+		changes := repository.UserPatch{ID: existing.ID, Version: existing.Version}
+		if patched.Name != existing.Name {
+			changes.Name = &patched.Name
+		}
+		if patched.Email != existing.Email {
+			changes.Email = &patched.Email
+		}
+
+		if changes.Name == nil && changes.Email == nil {
+			updatedUser = existing
+			return nil
+		}
+
+		updatedUser, err = s.userRepo.Patch(ctx, changes)
+		if err != nil {
+			return mapWriteError(err)
+		}
+
+		if changes.Email != nil {
+			s.sendVerificationAfterCommit(ctx, updatedUser.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return model.User{}, err
+	}
+
+	return updatedUser, nil
+}
+
+// applyPatch applies a patch document to the JSON representation of user
+func applyPatch(user model.User, patchType PatchType, patch []byte) (model.User, error) {
+	doc, err := json.Marshal(user)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	var patchedDoc []byte
+	switch patchType {
+	case MergePatch:
+		if !json.Valid(patch) {
+			return model.User{}, ErrInvalidPatch
+		}
+		patchedDoc, err = jsonpatch.MergePatch(doc, patch)
+	case JSONPatch:
+		var ops jsonpatch.Patch
+		ops, err = jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return model.User{}, ErrInvalidPatch
+		}
+		patchedDoc, err = ops.Apply(doc)
+	default:
+		return model.User{}, ErrUnsupportedPatch
+	}
+	if err != nil {
+		return model.User{}, ErrPatchFailed
+	}
+
+	// Decode strictly so that unknown fields and wrong types are rejected
+	var patched model.User
+	decoder := json.NewDecoder(bytes.NewReader(patchedDoc))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		return model.User{}, ErrPatchFailed
+	}
+
+	// Reject changes to fields managed by the system. Roles are changed
+	// with SetRole, and users deleted and restored with Delete and Restore,
+	// which need different permissions.
+	if patched.ID != user.ID ||
+		patched.Role != user.Role ||
+		!sameTime(patched.EmailVerifiedAt, user.EmailVerifiedAt) ||
+		!sameTime(patched.DeletedAt, user.DeletedAt) ||
+		patched.Version != user.Version ||
+		!patched.CreatedAt.Equal(user.CreatedAt) ||
+		!patched.UpdatedAt.Equal(user.UpdatedAt) {
+		return model.User{}, ErrReadOnlyField
+	}
+
+	return patched, nil
+}
+
+// sameTime reports whether two optional times are both unset or equal
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -3,62 +3,129 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/ThePotatoVerse/internal/app/model"
 	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/internal/pkg/validator"
+	"github.com/ThePotatoVerse/pkg/database"
 	"github.com/ThePotatoVerse/pkg/logger"
 )
 
 // Common errors
 var (
-	ErrUserNotFound = errors.New("user not found")
-	ErrInvalidInput = errors.New("invalid input")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrInvalidInput    = errors.New("invalid input")
+	ErrInvalidCursor   = errors.New("invalid cursor")
+	ErrEmailTaken      = errors.New("email already in use")
+	ErrVersionMismatch = errors.New("user has been modified")
+	ErrInvalidRole     = errors.New("invalid role")
+)
+
+// Pagination limits
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
 )
 
 // UserService defines the interface for user business logic
 type UserService interface {
-	List(ctx context.Context) ([]model.User, error)
+	List(ctx context.Context, query repository.UserQuery) (repository.UserPage, error)
 	Create(ctx context.Context, user model.User) (model.User, error)
 	Get(ctx context.Context, id string) (model.User, error)
-	Update(ctx context.Context, user model.User) error
-	Delete(ctx context.Context, id string) error
+	GetWithDeleted(ctx context.Context, id string) (model.User, error)
+	Update(ctx context.Context, user model.User) (model.User, error)
+	Patch(ctx context.Context, id string, version int, patchType PatchType, patch []byte) (model.User, error)
+	Delete(ctx context.Context, id string, version int) error
+	Restore(ctx context.Context, id string) (model.User, error)
+	SetRole(ctx context.Context, id string, version int, role model.Role) (model.User, error)
+	HardDelete(ctx context.Context, id string, version int) error
+	PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error)
 }
 
 // userService implements UserService
 type userService struct {
-	log      logger.Logger
-	userRepo repository.UserRepository
+	log       logger.Logger
+	userRepo  repository.UserRepository
+	txManager database.TxManager
+	validator *validator.Validator
+	verifier  VerificationService
 }
 
-// NewUserService creates a new user service
-func NewUserService(log logger.Logger, userRepo repository.UserRepository) UserService {
+// NewUserService creates a new user service. New users, and users whose
+// email changes, are sent a verification email through verifier.
+func NewUserService(log logger.Logger, userRepo repository.UserRepository, txManager database.TxManager, validator *validator.Validator, verifier VerificationService) UserService {
 	return &userService{
-		log:      log,
-		userRepo: userRepo,
+		log:       log,
+		userRepo:  userRepo,
+		txManager: txManager,
+		validator: validator,
+		verifier:  verifier,
 	}
 }
 
-// List returns all users
-func (s *userService) List(ctx context.Context) ([]model.User, error) {
-	s.log.Info("Listing users")
-	return s.userRepo.FindAll(ctx)
+// List returns a page of users matching the query
+func (s *userService) List(ctx context.Context, query repository.UserQuery) (repository.UserPage, error) {
+	s.log.FromContext(ctx).Info("Listing users", "limit", query.Limit, "offset", query.Offset, "sort", query.SortBy)
+
+	// Apply defaults
+	if query.Limit == 0 {
+		query.Limit = DefaultPageSize
+	}
+	if query.SortBy == "" {
+		query.SortBy = repository.SortByCreatedAt
+		query.SortDesc = true
+	}
+
+	// Validate query
+	if query.Limit < 0 || query.Limit > MaxPageSize || query.Offset < 0 || !query.SortBy.Valid() {
+		return repository.UserPage{}, ErrInvalidInput
+	}
+
+	page, err := s.userRepo.FindAll(ctx, query)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return repository.UserPage{}, ErrInvalidCursor
+		}
+		return repository.UserPage{}, err
+	}
+
+	return page, nil
 }
 
-// Create creates a new user
+// Create creates a new user. Users without a role become members.
 func (s *userService) Create(ctx context.Context, user model.User) (model.User, error) {
-	s.log.Info("Creating user")
+	s.log.FromContext(ctx).Info("Creating user")
 
 	// Validate user
-	if user.Name == "" {
-		return model.User{}, ErrInvalidInput
+	if user.Role == "" {
+		user.Role = model.RoleMember
+	}
+	if !user.Role.Valid() {
+		return model.User{}, ErrInvalidRole
+	}
+	user, err := s.validateUser(user)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	createdUser, err := s.userRepo.Create(ctx, user)
+	if err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return model.User{}, ErrEmailTaken
+		}
+		return model.User{}, err
 	}
 
-	return s.userRepo.Create(ctx, user)
+	s.sendVerificationAfterCommit(ctx, createdUser.ID)
+
+	return createdUser, nil
 }
 
 // Get returns a user by ID
 func (s *userService) Get(ctx context.Context, id string) (model.User, error) {
-	s.log.Info("Getting user", "id", id)
+	s.log.FromContext(ctx).Info("Getting user", "id", id)
 
 	user, err := s.userRepo.FindByID(ctx, id)
 	if err != nil {
@@ -71,39 +138,179 @@ func (s *userService) Get(ctx context.Context, id string) (model.User, error) {
 	return user, nil
 }
 
-// Update updates a user
-func (s *userService) Update(ctx context.Context, user model.User) error {
-	s.log.Info("Updating user", "id", user.ID)
+// GetWithDeleted returns a user by ID, including soft-deleted users
+func (s *userService) GetWithDeleted(ctx context.Context, id string) (model.User, error) {
+	s.log.FromContext(ctx).Info("Getting user including deleted", "id", id)
+
+	user, err := s.userRepo.FindByIDWithDeleted(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return model.User{}, ErrUserNotFound
+		}
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+// Update updates a user. When user.Version is non-zero it must match the
+// stored version, otherwise ErrVersionMismatch is returned.
+func (s *userService) Update(ctx context.Context, user model.User) (model.User, error) {
+	s.log.FromContext(ctx).Info("Updating user", "id", user.ID, "version", user.Version)
 
 	// Validate user
-	if user.ID == "" || user.Name == "" {
-		return ErrInvalidInput
+	if user.ID == "" {
+		return model.User{}, ErrInvalidInput
+	}
+	user, err := s.validateUser(user)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	var updatedUser model.User
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// Check if user exists
+		existing, err := s.userRepo.FindByIDForUpdate(ctx, user.ID)
+		if err != nil {
+			return mapWriteError(err)
+		}
+
+		// Check version
+		if user.Version == 0 {
+			user.Version = existing.Version
+		} else if user.Version != existing.Version {
+			return ErrVersionMismatch
+		}
+
+		updatedUser, err = s.userRepo.Update(ctx, user)
+		if err != nil {
+			return mapWriteError(err)
+		}
+
+		if updatedUser.Email != existing.Email {
+			s.sendVerificationAfterCommit(ctx, updatedUser.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return model.User{}, err
 	}
 
-	// Check if user exists
-	_, err := s.userRepo.FindByID(ctx, user.ID)
+	return updatedUser, nil
+}
+
+// Delete soft-deletes a user. When version is non-zero it must match the
+// stored version, otherwise ErrVersionMismatch is returned.
+func (s *userService) Delete(ctx context.Context, id string, version int) error {
+	s.log.FromContext(ctx).Info("Deleting user", "id", id, "version", version)
+
+	return mapWriteError(s.userRepo.Delete(ctx, id, version))
+}
+
+// Restore restores a soft-deleted user
+func (s *userService) Restore(ctx context.Context, id string) (model.User, error) {
+	s.log.FromContext(ctx).Info("Restoring user", "id", id)
+
+	user, err := s.userRepo.Restore(ctx, id)
 	if err != nil {
-		if errors.Is(err, repository.ErrNotFound) {
-			return ErrUserNotFound
+		return model.User{}, mapWriteError(err)
+	}
+
+	return user, nil
+}
+
+// SetRole changes the role of a user. When version is non-zero it must match
+// the stored version, otherwise ErrVersionMismatch is returned.
+func (s *userService) SetRole(ctx context.Context, id string, version int, role model.Role) (model.User, error) {
+	s.log.FromContext(ctx).Info("Setting user role", "id", id, "version", version, "role", role)
+
+	if !role.Valid() {
+		return model.User{}, ErrInvalidRole
+	}
+
+	var updatedUser model.User
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// Check if user exists
+		existing, err := s.userRepo.FindByIDForUpdate(ctx, id)
+		if err != nil {
+			return mapWriteError(err)
+		}
+
+		// Check version
+		if version != 0 && version != existing.Version {
+			return ErrVersionMismatch
 		}
-		return err
+
+		if existing.Role == role {
+			updatedUser = existing
+			return nil
+		}
+
+		updatedUser, err = s.userRepo.Patch(ctx, repository.UserPatch{ID: id, Version: existing.Version, Role: &role})
+		return mapWriteError(err)
+	})
+	if err != nil {
+		return model.User{}, err
 	}
 
-	return s.userRepo.Update(ctx, user)
+	return updatedUser, nil
 }
 
-// Delete deletes a user
-func (s *userService) Delete(ctx context.Context, id string) error {
-	s.log.Info("Deleting user", "id", id)
+// HardDelete permanently deletes a user, whether or not it was soft-deleted.
+// When version is non-zero it must match the stored version.
+func (s *userService) HardDelete(ctx context.Context, id string, version int) error {
+	s.log.FromContext(ctx).Info("Permanently deleting user", "id", id, "version", version)
 
-	// Check if user exists
-	_, err := s.userRepo.FindByID(ctx, id)
+	return mapWriteError(s.userRepo.HardDelete(ctx, id, version))
+}
+
+// PurgeDeleted permanently deletes users soft-deleted longer than retention ago
+func (s *userService) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
+	purged, err := s.userRepo.PurgeDeleted(ctx, time.Now().Add(-retention))
 	if err != nil {
-		if errors.Is(err, repository.ErrNotFound) {
-			return ErrUserNotFound
+		return 0, err
+	}
+
+	s.log.FromContext(ctx).Info("Purged deleted users", "count", purged, "retention", retention)
+	return purged, nil
+}
+
+// sendVerificationAfterCommit sends a verification email to a user once the
+// transaction carried by ctx, if any, has committed. Failing to send does not
+// fail the write, as the user can ask for another email.
+func (s *userService) sendVerificationAfterCommit(ctx context.Context, userID string) {
+	s.txManager.AfterCommit(ctx, func(ctx context.Context) {
+		if err := s.verifier.SendVerification(ctx, userID); err != nil {
+			s.log.FromContext(ctx).Error("Failed to send verification email", "user_id", userID, "error", err)
 		}
-		return err
+	})
+}
+
+// validateUser checks the fields a user must have and returns the user with
+// them normalized. The error wraps both ErrInvalidInput and the
+// validator.Errors listing each invalid field.
+func (s *userService) validateUser(user model.User) (model.User, error) {
+	check := s.validator.Check()
+	user.Name = check.Name("name", user.Name)
+	user.Email = check.Email("email", user.Email)
+
+	if err := check.Err(); err != nil {
+		return model.User{}, fmt.Errorf("%w: %w", ErrInvalidInput, err)
 	}
+	return user, nil
+}
 
-	return s.userRepo.Delete(ctx, id)
+// mapWriteError translates repository write errors into service errors
+func mapWriteError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrUserNotFound
+	case errors.Is(err, repository.ErrConflict):
+		return ErrEmailTaken
+	case errors.Is(err, repository.ErrVersionMismatch):
+		return ErrVersionMismatch
+	}
+	return err
 }
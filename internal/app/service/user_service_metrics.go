@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Operation results
+const (
+	resultSuccess  = "success"
+	resultRejected = "rejected"
+	resultError    = "error"
+)
+
+// rejectedErrors are service errors caused by the caller rather than a failure
+var rejectedErrors = []error{
+	ErrUserNotFound,
+	ErrInvalidInput,
+	ErrInvalidCursor,
+	ErrEmailTaken,
+	ErrVersionMismatch,
+	ErrInvalidRole,
+	ErrInvalidPatch,
+	ErrPatchFailed,
+	ErrReadOnlyField,
+	ErrUnsupportedPatch,
+	ErrInvalidCredentials,
+	ErrUnauthenticated,
+	ErrInvalidToken,
+	ErrForbidden,
+	ErrAPIKeyNotFound,
+	ErrInvalidVerification,
+	ErrInvalidPasswordReset,
+	ErrInvalidMFACode,
+	ErrMFAAlreadyEnabled,
+	ErrMFANotEnabled,
+	ErrMFALocked,
+	ErrOAuthClientNotFound,
+	ErrOAuthConsentNotFound,
+	ErrInvalidOAuthClient,
+	ErrInvalidConsent,
+	ErrOAuthRequest,
+	ErrExternalProviderNotFound,
+	ErrIdentityNotFound,
+	ErrInvalidExternalLogin,
+	ErrExternalLoginFailed,
+	ErrExternalAccountNotLinked,
+	ErrIdentityAlreadyLinked,
+	ErrLastLoginMethod,
+}
+
+// observeOperation counts the result of an operation in operations
+func observeOperation(operations *prometheus.CounterVec, operation string, err error) {
+	result := resultSuccess
+	if err != nil {
+		result = resultError
+		for _, rejected := range rejectedErrors {
+			if errors.Is(err, rejected) {
+				result = resultRejected
+				break
+			}
+		}
+	}
+
+	operations.WithLabelValues(operation, result).Inc()
+}
+
+// userServiceMetrics decorates a UserService with per-operation counters
+type userServiceMetrics struct {
+	next       UserService
+	operations *prometheus.CounterVec
+}
+
+// NewUserServiceMetrics wraps next so that every call is counted by
+// operation and result in user_service_operations_total
+func NewUserServiceMetrics(next UserService, registerer prometheus.Registerer) UserService {
+	operations := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "user_service_operations_total",
+		Help: "Total number of user service operations by operation and result.",
+	}, []string{"operation", "result"})
+
+	registerer.MustRegister(operations)
+
+	return &userServiceMetrics{
+		next:       next,
+		operations: operations,
+	}
+}
+
+// observe records the result of an operation
+func (m *userServiceMetrics) observe(operation string, err error) {
+	observeOperation(m.operations, operation, err)
+}
+
+// List implements UserService
+func (m *userServiceMetrics) List(ctx context.Context, query repository.UserQuery) (repository.UserPage, error) {
+	page, err := m.next.List(ctx, query)
+	m.observe("list", err)
+	return page, err
+}
+
+// Create implements UserService
+func (m *userServiceMetrics) Create(ctx context.Context, user model.User) (model.User, error) {
+	user, err := m.next.Create(ctx, user)
+	m.observe("create", err)
+	return user, err
+}
+
+// Get implements UserService
+func (m *userServiceMetrics) Get(ctx context.Context, id string) (model.User, error) {
+	user, err := m.next.Get(ctx, id)
+	m.observe("get", err)
+	return user, err
+}
+
+// GetWithDeleted implements UserService
+func (m *userServiceMetrics) GetWithDeleted(ctx context.Context, id string) (model.User, error) {
+	user, err := m.next.GetWithDeleted(ctx, id)
+	m.observe("get_with_deleted", err)
+	return user, err
+}
+
+// Update implements UserService
+func (m *userServiceMetrics) Update(ctx context.Context, user model.User) (model.User, error) {
+	user, err := m.next.Update(ctx, user)
+	m.observe("update", err)
+	return user, err
+}
+
+// Patch implements UserService
+func (m *userServiceMetrics) Patch(ctx context.Context, id string, version int, patchType PatchType, patch []byte) (model.User, error) {
+	user, err := m.next.Patch(ctx, id, version, patchType, patch)
+	m.observe("patch", err)
+	return user, err
+}
+
+// Delete implements UserService
+func (m *userServiceMetrics) Delete(ctx context.Context, id string, version int) error {
+	err := m.next.Delete(ctx, id, version)
+	m.observe("delete", err)
+	return err
+}
+
+// Restore implements UserService
+func (m *userServiceMetrics) Restore(ctx context.Context, id string) (model.User, error) {
+	user, err := m.next.Restore(ctx, id)
+	m.observe("restore", err)
+	return user, err
+}
+
+// SetRole implements UserService
+func (m *userServiceMetrics) SetRole(ctx context.Context, id string, version int, role model.Role) (model.User, error) {
+	user, err := m.next.SetRole(ctx, id, version, role)
+	m.observe("set_role", err)
+	return user, err
+}
+
+// HardDelete implements UserService
+func (m *userServiceMetrics) HardDelete(ctx context.Context, id string, version int) error {
+	err := m.next.HardDelete(ctx, id, version)
+	m.observe("hard_delete", err)
+	return err
+}
+
+// PurgeDeleted implements UserService
+func (m *userServiceMetrics) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
+	purged, err := m.next.PurgeDeleted(ctx, retention)
+	m.observe("purge_deleted", err)
+	return purged, err
+}
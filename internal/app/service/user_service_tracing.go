@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies spans created by this package
+const tracerName = "github.com/ThePotatoVerse/internal/app/service"
+
+// userServiceTracing decorates a UserService with a span per method
+type userServiceTracing struct {
+	next   UserService
+	tracer trace.Tracer
+}
+
+// NewUserServiceTracing wraps next so that every call runs in a child span
+func NewUserServiceTracing(next UserService) UserService {
+	return &userServiceTracing{
+		next:   next,
+		tracer: otel.Tracer(tracerName),
+	}
+}
+
+// start starts the span of an operation
+func (t *userServiceTracing) start(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, "UserService."+operation, trace.WithAttributes(attrs...))
+}
+
+// endSpan records the result of an operation and ends its span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// List implements UserService
+func (t *userServiceTracing) List(ctx context.Context, query repository.UserQuery) (repository.UserPage, error) {
+	ctx, span := t.start(ctx, "List",
+		attribute.Int("query.limit", query.Limit),
+		attribute.Int("query.offset", query.Offset),
+		attribute.String("query.sort", string(query.SortBy)),
+	)
+	page, err := t.next.List(ctx, query)
+	endSpan(span, err)
+	return page, err
+}
+
+// Create implements UserService
+func (t *userServiceTracing) Create(ctx context.Context, user model.User) (model.User, error) {
+	ctx, span := t.start(ctx, "Create")
+	user, err := t.next.Create(ctx, user)
+	if err == nil {
+		span.SetAttributes(attribute.String("user.id", user.ID))
+	}
+	endSpan(span, err)
+	return user, err
+}
+
+// Get implements UserService
+func (t *userServiceTracing) Get(ctx context.Context, id string) (model.User, error) {
+	ctx, span := t.start(ctx, "Get", attribute.String("user.id", id))
+	user, err := t.next.Get(ctx, id)
+	endSpan(span, err)
+	return user, err
+}
+
+// GetWithDeleted implements UserService
+func (t *userServiceTracing) GetWithDeleted(ctx context.Context, id string) (model.User, error) {
+	ctx, span := t.start(ctx, "GetWithDeleted", attribute.String("user.id", id))
+	user, err := t.next.GetWithDeleted(ctx, id)
+	endSpan(span, err)
+	return user, err
+}
+
+// Update implements UserService
+func (t *userServiceTracing) Update(ctx context.Context, user model.User) (model.User, error) {
+	ctx, span := t.start(ctx, "Update", attribute.String("user.id", user.ID))
+	user, err := t.next.Update(ctx, user)
+	endSpan(span, err)
+	return user, err
+}
+
+// Patch implements UserService
+func (t *userServiceTracing) Patch(ctx context.Context, id string, version int, patchType PatchType, patch []byte) (model.User, error) {
+	ctx, span := t.start(ctx, "Patch",
+		attribute.String("user.id", id),
+		attribute.String("patch.type", string(patchType)),
+	)
+	user, err := t.next.Patch(ctx, id, version, patchType, patch)
+	endSpan(span, err)
+	return user, err
+}
+
+// Delete implements UserService
+func (t *userServiceTracing) Delete(ctx context.Context, id string, version int) error {
+	ctx, span := t.start(ctx, "Delete", attribute.String("user.id", id))
+	err := t.next.Delete(ctx, id, version)
+	endSpan(span, err)
+	return err
+}
+
+// Restore implements UserService
+func (t *userServiceTracing) Restore(ctx context.Context, id string) (model.User, error) {
+	ctx, span := t.start(ctx, "Restore", attribute.String("user.id", id))
+	user, err := t.next.Restore(ctx, id)
+	endSpan(span, err)
+	return user, err
+}
+
+// SetRole implements UserService
+func (t *userServiceTracing) SetRole(ctx context.Context, id string, version int, role model.Role) (model.User, error) {
+	ctx, span := t.start(ctx, "SetRole",
+		attribute.String("user.id", id),
+		attribute.String("user.role", string(role)),
+	)
+	user, err := t.next.SetRole(ctx, id, version, role)
+	endSpan(span, err)
+	return user, err
+}
+
+// HardDelete implements UserService
+func (t *userServiceTracing) HardDelete(ctx context.Context, id string, version int) error {
+	ctx, span := t.start(ctx, "HardDelete", attribute.String("user.id", id))
+	err := t.next.HardDelete(ctx, id, version)
+	endSpan(span, err)
+	return err
+}
+
+// PurgeDeleted implements UserService
+func (t *userServiceTracing) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
+	ctx, span := t.start(ctx, "PurgeDeleted", attribute.String("retention", retention.String()))
+	purged, err := t.next.PurgeDeleted(ctx, retention)
+	span.SetAttributes(attribute.Int64("purged", purged))
+	endSpan(span, err)
+	return purged, err
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/ThePotatoVerse/internal/app/repository"
+	"github.com/ThePotatoVerse/internal/pkg/config"
+	"github.com/ThePotatoVerse/pkg/database"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/ThePotatoVerse/pkg/mailer"
+	"github.com/ThePotatoVerse/pkg/token"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidVerification is returned for verification tokens that are
+// malformed, expired, already used, or for an email the user no longer has
+var ErrInvalidVerification = errors.New("invalid or expired verification token")
+
+// verificationPurpose binds verification tokens to email verification
+const verificationPurpose = "email-verification"
+
+// verificationClaims is the signed payload of a verification token
+type verificationClaims struct {
+	ID        string `json:"jti"`
+	UserID    string `json:"sub"`
+	Email     string `json:"email"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// VerificationService defines the interface for email verification.
+// Verification emails carry a link with a signed, single-use token that
+// confirms the address it was sent to.
+type VerificationService interface {
+	SendVerification(ctx context.Context, userID string) error
+	Verify(ctx context.Context, token string) (model.User, error)
+}
+
+// verificationService implements VerificationService
+type verificationService struct {
+	log           logger.Logger
+	userRepo      repository.UserRepository
+	verifications repository.EmailVerificationRepository
+	txManager     database.TxManager
+	mailer        mailer.Mailer
+	signer        *token.Signer
+	publicURL     string
+	cfg           *config.AuthConfig
+}
+
+// NewVerificationService creates a new email verification service. Links in
+// verification emails point at publicURL. Users verifying an email listed in
+// cfg.AdminEmails become admins.
+func NewVerificationService(
+	log logger.Logger,
+	userRepo repository.UserRepository,
+	verifications repository.EmailVerificationRepository,
+	txManager database.TxManager,
+	mailer mailer.Mailer,
+	signer *token.Signer,
+	publicURL string,
+	cfg *config.AuthConfig,
+) VerificationService {
+	return &verificationService{
+		log:           log,
+		userRepo:      userRepo,
+		verifications: verifications,
+		txManager:     txManager,
+		mailer:        mailer,
+		signer:        signer,
+		publicURL:     strings.TrimSuffix(publicURL, "/"),
+		cfg:           cfg,
+	}
+}
+
+// SendVerification emails a verification link to the current email of a
+// user. Users whose email is already verified are not sent anything.
+func (s *verificationService) SendVerification(ctx context.Context, userID string) error {
+	s.log.FromContext(ctx).Info("Sending verification email", "user_id", userID)
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if user.EmailVerified() {
+		s.log.FromContext(ctx).Info("Email already verified", "user_id", userID)
+		return nil
+	}
+
+	now := time.Now()
+
+	// Expired verifications can no longer be used, so there is no need to
+	// remember whether they were
+	if _, err := s.verifications.DeleteExpired(ctx, now); err != nil {
+		s.log.FromContext(ctx).Warn("Failed to delete expired verifications", "error", err)
+	}
+
+	verification := model.EmailVerification{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		Email:     user.Email,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.cfg.Verification.TokenTTL),
+	}
+	if err := s.verifications.Create(ctx, verification); err != nil {
+		return err
+	}
+
+	value, err := s.signer.Sign(verificationPurpose, verificationClaims{
+		ID:        verification.ID,
+		UserID:    verification.UserID,
+		Email:     verification.Email,
+		ExpiresAt: verification.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := s.publicURL + "/verify?" + url.Values{"token": {value}}.Encode()
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Please confirm that %s is your email address by opening this link:\n\n"+
+			"%s\n\n"+
+			"The link expires in %s. If you did not expect this email, you can ignore it.\n",
+			user.Name, user.Email, link, s.cfg.Verification.TokenTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+
+	return nil
+}
+
+// Verify checks a verification token and marks the email it was sent to as
+// verified, provided the user still has that email
+func (s *verificationService) Verify(ctx context.Context, value string) (model.User, error) {
+	s.log.FromContext(ctx).Info("Verifying email")
+
+	var claims verificationClaims
+	if err := s.signer.Verify(verificationPurpose, value, &claims); err != nil {
+		return model.User{}, ErrInvalidVerification
+	}
+
+	now := time.Now()
+	if !now.Before(time.Unix(claims.ExpiresAt, 0)) {
+		return model.User{}, ErrInvalidVerification
+	}
+
+	var user model.User
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.verifications.Consume(ctx, claims.ID, now); err != nil {
+			return err
+		}
+
+		verified, err := s.userRepo.MarkEmailVerified(ctx, claims.UserID, claims.Email, now)
+		if err != nil {
+			return err
+		}
+
+		user, err = grantConfiguredAdmin(ctx, s.userRepo, s.cfg.AdminEmails, verified)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) || errors.Is(err, repository.ErrConflict) {
+			return model.User{}, ErrInvalidVerification
+		}
+		return model.User{}, err
+	}
+
+	s.log.FromContext(ctx).Info("Verified email", "user_id", user.ID)
+	return user, nil
+}
+
+// grantConfiguredAdmin makes a user an admin if their email is verified and
+// listed in adminEmails, and returns the user as stored. Only verified emails
+// count, so that nobody becomes an admin by typing an address they do not
+// control. It must run in the transaction that verified the email.
+func grantConfiguredAdmin(ctx context.Context, userRepo repository.UserRepository, adminEmails []string, user model.User) (model.User, error) {
+	if user.EmailVerifiedAt == nil || user.Role == model.RoleAdmin {
+		return user, nil
+	}
+
+	for _, email := range adminEmails {
+		if strings.EqualFold(strings.TrimSpace(user.Email), strings.TrimSpace(email)) {
+			role := model.RoleAdmin
+			return userRepo.Patch(ctx, repository.UserPatch{ID: user.ID, Version: user.Version, Role: &role})
+		}
+	}
+
+	return user, nil
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// verificationServiceMetrics decorates a VerificationService with per-operation counters
+type verificationServiceMetrics struct {
+	next       VerificationService
+	operations *prometheus.CounterVec
+}
+
+// NewVerificationServiceMetrics wraps next so that every call is counted by
+// operation and result in verification_service_operations_total
+func NewVerificationServiceMetrics(next VerificationService, registerer prometheus.Registerer) VerificationService {
+	operations := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "verification_service_operations_total",
+		Help: "Total number of email verification service operations by operation and result.",
+	}, []string{"operation", "result"})
+
+	registerer.MustRegister(operations)
+
+	return &verificationServiceMetrics{
+		next:       next,
+		operations: operations,
+	}
+}
+
+// SendVerification implements VerificationService
+func (m *verificationServiceMetrics) SendVerification(ctx context.Context, userID string) error {
+	err := m.next.SendVerification(ctx, userID)
+	observeOperation(m.operations, "send_verification", err)
+	return err
+}
+
+// Verify implements VerificationService
+func (m *verificationServiceMetrics) Verify(ctx context.Context, token string) (model.User, error) {
+	user, err := m.next.Verify(ctx, token)
+	observeOperation(m.operations, "verify", err)
+	return user, err
+}
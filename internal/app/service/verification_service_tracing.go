@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ThePotatoVerse/internal/app/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// verificationServiceTracing decorates a VerificationService with a span per
+// method. Tokens are never recorded.
+type verificationServiceTracing struct {
+	next   VerificationService
+	tracer trace.Tracer
+}
+
+// NewVerificationServiceTracing wraps next so that every call runs in a child span
+func NewVerificationServiceTracing(next VerificationService) VerificationService {
+	return &verificationServiceTracing{
+		next:   next,
+		tracer: otel.Tracer(tracerName),
+	}
+}
+
+// start starts the span of an operation
+func (t *verificationServiceTracing) start(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, "VerificationService."+operation, trace.WithAttributes(attrs...))
+}
+
+// SendVerification implements VerificationService
+func (t *verificationServiceTracing) SendVerification(ctx context.Context, userID string) error {
+	ctx, span := t.start(ctx, "SendVerification", attribute.String("user.id", userID))
+	err := t.next.SendVerification(ctx, userID)
+	endSpan(span, err)
+	return err
+}
+
+// Verify implements VerificationService
+func (t *verificationServiceTracing) Verify(ctx context.Context, token string) (model.User, error) {
+	ctx, span := t.start(ctx, "Verify")
+	user, err := t.next.Verify(ctx, token)
+	if err == nil {
+		span.SetAttributes(attribute.String("user.id", user.ID))
+	}
+	endSpan(span, err)
+	return user, err
+}
@@ -1,6 +1,11 @@
 package config
 
 import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -8,8 +13,15 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server ServerConfig `mapstructure:"server"`
-	DB     DBConfig     `mapstructure:"db"`
+	Server     ServerConfig     `mapstructure:"server"`
+	DB         DBConfig         `mapstructure:"db"`
+	Storage    StorageConfig    `mapstructure:"storage"`
+	Purge      PurgeConfig      `mapstructure:"purge"`
+	Tracing    TracingConfig    `mapstructure:"tracing"`
+	Validation ValidationConfig `mapstructure:"validation"`
+	Auth       AuthConfig       `mapstructure:"auth"`
+	Mail       MailConfig       `mapstructure:"mail"`
+	RateLimit  RateLimitConfig  `mapstructure:"rate_limit"`
 }
 
 // ServerConfig holds HTTP server configuration
@@ -18,6 +30,19 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
 	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
+
+	// ShutdownDelay is how long the server keeps serving with failing
+	// readiness before it stops accepting connections
+	ShutdownDelay time.Duration `mapstructure:"shutdown_delay"`
+
+	// PublicURL is the address clients reach the server at, used to build
+	// links sent outside of HTTP responses
+	PublicURL string `mapstructure:"public_url"`
+
+	// TrustedProxies lists the addresses or CIDR ranges of reverse proxies
+	// whose X-Forwarded-For headers are believed. Requests from anywhere
+	// else are attributed to the address they come from.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 // DBConfig holds database configuration
@@ -28,14 +53,313 @@ type DBConfig struct {
 	Password string `mapstructure:"password"`
 	Name     string `mapstructure:"name"`
 	SSLMode  string `mapstructure:"ssl_mode"`
+
+	// AutoMigrate applies pending schema migrations at startup
+	AutoMigrate bool `mapstructure:"auto_migrate"`
+}
+
+// Storage drivers
+const (
+	StorageDriverMemory   = "memory"
+	StorageDriverPostgres = "postgres"
+)
+
+// StorageConfig holds storage backend configuration
+type StorageConfig struct {
+	Driver string `mapstructure:"driver"`
+}
+
+// PurgeConfig holds configuration for purging soft-deleted users
+type PurgeConfig struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	Interval  time.Duration `mapstructure:"interval"`
+	Retention time.Duration `mapstructure:"retention"`
+}
+
+// Tracing exporters
+const (
+	TracingExporterOTLP   = "otlp"
+	TracingExporterStdout = "stdout"
+	TracingExporterFile   = "file"
+)
+
+// TracingConfig holds OpenTelemetry tracing configuration
+type TracingConfig struct {
+	Enabled     bool    `mapstructure:"enabled"`
+	ServiceName string  `mapstructure:"service_name"`
+	Exporter    string  `mapstructure:"exporter"`
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+
+	// Endpoint is the OTLP/HTTP collector address, e.g. localhost:4318
+	Endpoint string `mapstructure:"endpoint"`
+	Insecure bool   `mapstructure:"insecure"`
+
+	// FilePath is where the file exporter writes spans
+	FilePath string `mapstructure:"file_path"`
+}
+
+// ValidationConfig holds the rules user input is validated against
+type ValidationConfig struct {
+	NameMinLength int `mapstructure:"name_min_length"`
+	NameMaxLength int `mapstructure:"name_max_length"`
+
+	PasswordMinLength int `mapstructure:"password_min_length"`
+
+	// DisallowedEmailDomains rejects addresses at these domains and their
+	// subdomains, e.g. disposable mail providers
+	DisallowedEmailDomains []string `mapstructure:"disallowed_email_domains"`
+}
+
+// AuthConfig holds authentication configuration
+type AuthConfig struct {
+	// SessionTTL is how long a login session lasts
+	SessionTTL time.Duration `mapstructure:"session_ttl"`
+
+	// SessionCleanupInterval is how often expired sessions are deleted
+	SessionCleanupInterval time.Duration `mapstructure:"session_cleanup_interval"`
+
+	// CookieName names the cookie holding the session token
+	CookieName string `mapstructure:"cookie_name"`
+
+	// CookieSecure restricts the session cookie to HTTPS
+	CookieSecure bool `mapstructure:"cookie_secure"`
+
+	// AdminEmails lists emails whose users are given the admin role once
+	// they verify the email. Every other user is a member.
+	AdminEmails []string `mapstructure:"admin_emails"`
+
+	JWT           JWTConfig           `mapstructure:"jwt"`
+	Verification  VerificationConfig  `mapstructure:"verification"`
+	PasswordReset PasswordResetConfig `mapstructure:"password_reset"`
+	MFA           MFAConfig           `mapstructure:"mfa"`
+	OAuth         OAuthConfig         `mapstructure:"oauth"`
+	OIDC          OIDCConfig          `mapstructure:"oidc"`
+}
+
+// JWTConfig holds configuration for JWT access tokens and refresh tokens
+type JWTConfig struct {
+	Issuer          string        `mapstructure:"issuer"`
+	Audience        string        `mapstructure:"audience"`
+	AccessTokenTTL  time.Duration `mapstructure:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `mapstructure:"refresh_token_ttl"`
+
+	// ActiveKeyID selects the key new tokens are signed with. Every listed
+	// key verifies tokens, so retired keys stay listed until the tokens they
+	// signed have expired. Without keys an ephemeral key is generated.
+	ActiveKeyID string         `mapstructure:"active_key_id"`
+	Keys        []JWTKeyConfig `mapstructure:"keys"`
+}
+
+// JWTKeyConfig locates a PEM encoded RSA or ECDSA P-256 key. Keys with only
+// a public key verify tokens but cannot sign them.
+type JWTKeyConfig struct {
+	ID             string `mapstructure:"id"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
+}
+
+// VerificationConfig holds configuration for email verification tokens
+type VerificationConfig struct {
+	TokenTTL time.Duration `mapstructure:"token_ttl"`
+
+	// Secret signs verification tokens. Without a secret a random one is
+	// generated, and links sent before a restart stop working.
+	Secret string `mapstructure:"secret"`
+}
+
+// PasswordResetConfig holds configuration for password reset tokens
+type PasswordResetConfig struct {
+	TokenTTL time.Duration `mapstructure:"token_ttl"`
+
+	// MaxRequests limits how many reset emails are sent to one address
+	// within RequestWindow. Further requests are accepted but ignored.
+	MaxRequests   int           `mapstructure:"max_requests"`
+	RequestWindow time.Duration `mapstructure:"request_window"`
 }
 
+// MFAConfig holds configuration for two-factor authentication
+type MFAConfig struct {
+	// Issuer names the account in authenticator apps
+	Issuer string `mapstructure:"issuer"`
+
+	// ChallengeTTL is how long a login waits for its second factor, and
+	// MaxAttempts how many wrong codes it accepts before it must restart
+	ChallengeTTL time.Duration `mapstructure:"challenge_ttl"`
+	MaxAttempts  int           `mapstructure:"max_attempts"`
+
+	// RecoveryCodes is how many recovery codes are issued at a time
+	RecoveryCodes int `mapstructure:"recovery_codes"`
+
+	// LockoutThreshold is how many wrong codes in a row, across logins and
+	// every other check of a user's second factor, lock it for
+	// LockoutDuration. Each further wrong code doubles the lockout, up to
+	// MaxLockoutDuration.
+	LockoutThreshold   int           `mapstructure:"lockout_threshold"`
+	LockoutDuration    time.Duration `mapstructure:"lockout_duration"`
+	MaxLockoutDuration time.Duration `mapstructure:"max_lockout_duration"`
+}
+
+// OAuthConfig holds configuration for the built-in OAuth 2.0 and OpenID
+// Connect provider. Its issuer is server.public_url, and access tokens last
+// as long as jwt.access_token_ttl.
+type OAuthConfig struct {
+	// CodeTTL is how long an authorization code can be exchanged for tokens
+	CodeTTL time.Duration `mapstructure:"code_ttl"`
+
+	// IDTokenTTL is how long ID tokens are valid
+	IDTokenTTL time.Duration `mapstructure:"id_token_ttl"`
+
+	// ConsentTTL is how long a consent form can be submitted
+	ConsentTTL time.Duration `mapstructure:"consent_ttl"`
+
+	// Secret signs consent forms. Without a secret a random one is
+	// generated, and forms shown before a restart stop working.
+	Secret string `mapstructure:"secret"`
+
+	// LoginURL is where browsers without a session are sent to log in,
+	// with the authorization request to return to in the return_to
+	// parameter. Without it they get a 401 response.
+	LoginURL string `mapstructure:"login_url"`
+}
+
+// OIDCConfig holds configuration for logging in through external OpenID
+// Connect identity providers
+type OIDCConfig struct {
+	// StateTTL is how long a login started at a provider can be completed
+	StateTTL time.Duration `mapstructure:"state_ttl"`
+
+	// Secret signs the cookie that carries a login between the redirect to
+	// the provider and its callback. Without a secret a random one is
+	// generated, and logins started before a restart fail.
+	Secret string `mapstructure:"secret"`
+
+	// ReturnOrigins lists the origins, like https://app.example.com, that
+	// return_to may point at after a login. Paths on this server are always
+	// allowed.
+	ReturnOrigins []string `mapstructure:"return_origins"`
+
+	Providers []OIDCProviderConfig `mapstructure:"providers"`
+}
+
+// OIDCProviderConfig configures one external OpenID Connect provider
+type OIDCProviderConfig struct {
+	// Name identifies the provider in URLs, like google in
+	// /api/v1/auth/oidc/google/login
+	Name string `mapstructure:"name"`
+
+	// DisplayName is shown on login buttons, and defaults to Name
+	DisplayName string `mapstructure:"display_name"`
+
+	// Issuer is the provider's issuer URL, from which its configuration is
+	// discovered at /.well-known/openid-configuration
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	Scopes       []string `mapstructure:"scopes"`
+
+	// DisableSignup stops users without an account from being created when
+	// they first log in, so only linked accounts can use the provider
+	DisableSignup bool `mapstructure:"disable_signup"`
+
+	// AllowedDomains limits which email domains can sign up. Empty allows
+	// every domain.
+	AllowedDomains []string `mapstructure:"allowed_domains"`
+}
+
+// Rate limit stores
+const (
+	RateLimitStoreMemory   = "memory"
+	RateLimitStorePostgres = "postgres"
+)
+
+// Identities requests are rate limited by
+const (
+	// RateLimitByIP limits each client address
+	RateLimitByIP = "ip"
+	// RateLimitByUser limits each authenticated user, however they
+	// authenticate, and anonymous requests by address
+	RateLimitByUser = "user"
+	// RateLimitByAPIKey limits each API key on its own, and other requests
+	// like RateLimitByUser
+	RateLimitByAPIKey = "api_key"
+)
+
+// RateLimitConfig holds configuration for limiting how often clients may
+// make requests. Each group of routes has its own rule.
+type RateLimitConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// Store keeps the token buckets. The memory store limits each replica
+	// on its own; the postgres store shares the limits between replicas,
+	// and needs the postgres storage driver.
+	Store string `mapstructure:"store"`
+
+	// CleanupInterval is how often buckets that have refilled are deleted
+	CleanupInterval time.Duration `mapstructure:"cleanup_interval"`
+
+	// Auth covers logins, token requests, password resets and registration
+	Auth RateLimitRule `mapstructure:"auth"`
+
+	// API covers the authenticated /api/v1 routes
+	API RateLimitRule `mapstructure:"api"`
+
+	// PreAuth covers the same routes by client address before their
+	// credentials are checked, so that requests with wrong credentials are
+	// limited too. It should allow at least as much as API.
+	PreAuth RateLimitRule `mapstructure:"pre_auth"`
+
+	// Public covers the OAuth provider endpoints and email verification
+	Public RateLimitRule `mapstructure:"public"`
+}
+
+// RateLimitRule is a token bucket per identity: each identity may make
+// Burst requests at once, and RequestsPerSecond on average. A rule without
+// RequestsPerSecond does not limit.
+type RateLimitRule struct {
+	RequestsPerSecond float64 `mapstructure:"requests_per_second"`
+	Burst             int     `mapstructure:"burst"`
+	Identity          string  `mapstructure:"identity"`
+}
+
+// Mail drivers
+const (
+	MailDriverLog  = "log"
+	MailDriverFile = "file"
+	MailDriverSMTP = "smtp"
+)
+
+// MailConfig holds configuration for sending email
+type MailConfig struct {
+	Driver string `mapstructure:"driver"`
+	From   string `mapstructure:"from"`
+
+	// Dir is where the file driver drops messages
+	Dir string `mapstructure:"dir"`
+
+	SMTP SMTPConfig `mapstructure:"smtp"`
+}
+
+// SMTPConfig holds the SMTP server mail is sent through. STARTTLS is used
+// when the server offers it, and credentials are only sent if a username is
+// set.
+type SMTPConfig struct {
+	Host     string        `mapstructure:"host"`
+	Port     int           `mapstructure:"port"`
+	Username string        `mapstructure:"username"`
+	Password string        `mapstructure:"password"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+}
+
+// providerName matches OIDC provider names, which appear in URLs
+var providerName = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
 // Load loads configuration from file and environment variables
 func Load() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath(".")
 	viper.AddConfigPath("./config")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 
 	// Set defaults
@@ -53,9 +377,181 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
+	// Providers are a list, so viper cannot default their fields
+	for i := range cfg.Auth.OIDC.Providers {
+		p := &cfg.Auth.OIDC.Providers[i]
+		if p.DisplayName == "" {
+			p.DisplayName = p.Name
+		}
+		if len(p.Scopes) == 0 {
+			p.Scopes = []string{"openid", "email", "profile"}
+		}
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
 	return &cfg, nil
 }
 
+// validate checks configuration values that cannot be expressed as defaults
+func (c *Config) validate() error {
+	switch c.Storage.Driver {
+	case StorageDriverMemory, StorageDriverPostgres:
+	default:
+		return fmt.Errorf("unsupported storage driver %q", c.Storage.Driver)
+	}
+
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("invalid trusted proxy %q", proxy)
+		}
+	}
+
+	if c.Tracing.Enabled {
+		switch c.Tracing.Exporter {
+		case TracingExporterOTLP, TracingExporterStdout, TracingExporterFile:
+		default:
+			return fmt.Errorf("unsupported tracing exporter %q", c.Tracing.Exporter)
+		}
+	}
+
+	if c.Purge.Enabled && (c.Purge.Interval <= 0 || c.Purge.Retention < 0) {
+		return fmt.Errorf("invalid purge interval %s or retention %s", c.Purge.Interval, c.Purge.Retention)
+	}
+
+	if c.Validation.NameMinLength < 1 || c.Validation.NameMaxLength < c.Validation.NameMinLength {
+		return fmt.Errorf("invalid name length limits %d-%d", c.Validation.NameMinLength, c.Validation.NameMaxLength)
+	}
+
+	if c.Validation.PasswordMinLength < 1 {
+		return fmt.Errorf("invalid password minimum length %d", c.Validation.PasswordMinLength)
+	}
+
+	if c.Auth.SessionTTL <= 0 || c.Auth.SessionCleanupInterval <= 0 || c.Auth.CookieName == "" {
+		return fmt.Errorf("invalid session ttl %s, cleanup interval %s or cookie name %q",
+			c.Auth.SessionTTL, c.Auth.SessionCleanupInterval, c.Auth.CookieName)
+	}
+
+	if c.Auth.JWT.AccessTokenTTL <= 0 || c.Auth.JWT.RefreshTokenTTL <= 0 {
+		return fmt.Errorf("invalid access token ttl %s or refresh token ttl %s", c.Auth.JWT.AccessTokenTTL, c.Auth.JWT.RefreshTokenTTL)
+	}
+
+	if len(c.Auth.JWT.Keys) > 0 {
+		active := false
+		for _, key := range c.Auth.JWT.Keys {
+			if key.ID == "" || (key.PrivateKeyFile == "") == (key.PublicKeyFile == "") {
+				return fmt.Errorf("jwt key %q needs an id and exactly one of private_key_file or public_key_file", key.ID)
+			}
+			if key.ID == c.Auth.JWT.ActiveKeyID {
+				active = key.PrivateKeyFile != ""
+			}
+		}
+		if !active {
+			return fmt.Errorf("active jwt key %q is not a listed private key", c.Auth.JWT.ActiveKeyID)
+		}
+	}
+
+	if c.Auth.Verification.TokenTTL <= 0 {
+		return fmt.Errorf("invalid verification token ttl %s", c.Auth.Verification.TokenTTL)
+	}
+
+	if c.Auth.PasswordReset.TokenTTL <= 0 || c.Auth.PasswordReset.MaxRequests < 1 || c.Auth.PasswordReset.RequestWindow <= 0 {
+		return fmt.Errorf("invalid password reset token ttl %s, max requests %d or request window %s",
+			c.Auth.PasswordReset.TokenTTL, c.Auth.PasswordReset.MaxRequests, c.Auth.PasswordReset.RequestWindow)
+	}
+
+	if c.Auth.MFA.Issuer == "" || c.Auth.MFA.ChallengeTTL <= 0 || c.Auth.MFA.MaxAttempts < 1 || c.Auth.MFA.RecoveryCodes < 1 {
+		return fmt.Errorf("invalid mfa issuer %q, challenge ttl %s, max attempts %d or recovery codes %d",
+			c.Auth.MFA.Issuer, c.Auth.MFA.ChallengeTTL, c.Auth.MFA.MaxAttempts, c.Auth.MFA.RecoveryCodes)
+	}
+	if c.Auth.MFA.LockoutThreshold < 1 || c.Auth.MFA.LockoutDuration <= 0 || c.Auth.MFA.MaxLockoutDuration < c.Auth.MFA.LockoutDuration {
+		return fmt.Errorf("invalid mfa lockout threshold %d, duration %s or max duration %s",
+			c.Auth.MFA.LockoutThreshold, c.Auth.MFA.LockoutDuration, c.Auth.MFA.MaxLockoutDuration)
+	}
+
+	if c.Auth.OAuth.CodeTTL <= 0 || c.Auth.OAuth.IDTokenTTL <= 0 || c.Auth.OAuth.ConsentTTL <= 0 {
+		return fmt.Errorf("invalid oauth code ttl %s, id token ttl %s or consent ttl %s",
+			c.Auth.OAuth.CodeTTL, c.Auth.OAuth.IDTokenTTL, c.Auth.OAuth.ConsentTTL)
+	}
+	if c.Auth.OAuth.LoginURL != "" {
+		if u, err := url.Parse(c.Auth.OAuth.LoginURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid oauth login url %q", c.Auth.OAuth.LoginURL)
+		}
+	}
+
+	if c.Auth.OIDC.StateTTL <= 0 {
+		return fmt.Errorf("invalid oidc state ttl %s", c.Auth.OIDC.StateTTL)
+	}
+	for _, origin := range c.Auth.OIDC.ReturnOrigins {
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" || strings.TrimSuffix(u.Path, "/") != "" {
+			return fmt.Errorf("invalid oidc return origin %q", origin)
+		}
+	}
+	providers := make(map[string]bool, len(c.Auth.OIDC.Providers))
+	for i, p := range c.Auth.OIDC.Providers {
+		if !providerName.MatchString(p.Name) || providers[p.Name] {
+			return fmt.Errorf("oidc provider %d needs a unique name of lowercase letters, digits and dashes, got %q", i, p.Name)
+		}
+		providers[p.Name] = true
+		if u, err := url.Parse(p.Issuer); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid issuer %q for oidc provider %q", p.Issuer, p.Name)
+		}
+		if p.ClientID == "" {
+			return fmt.Errorf("oidc provider %q needs a client_id", p.Name)
+		}
+	}
+
+	if c.RateLimit.Enabled {
+		switch c.RateLimit.Store {
+		case RateLimitStoreMemory:
+		case RateLimitStorePostgres:
+			if c.Storage.Driver != StorageDriverPostgres {
+				return fmt.Errorf("rate limit store %q needs the postgres storage driver", c.RateLimit.Store)
+			}
+		default:
+			return fmt.Errorf("unsupported rate limit store %q", c.RateLimit.Store)
+		}
+		if c.RateLimit.CleanupInterval <= 0 {
+			return fmt.Errorf("invalid rate limit cleanup interval %s", c.RateLimit.CleanupInterval)
+		}
+
+		rules := map[string]RateLimitRule{
+			"auth":     c.RateLimit.Auth,
+			"api":      c.RateLimit.API,
+			"pre_auth": c.RateLimit.PreAuth,
+			"public":   c.RateLimit.Public,
+		}
+		for group, rule := range rules {
+			if rule.RequestsPerSecond < 0 || (rule.RequestsPerSecond > 0 && rule.Burst < 1) {
+				return fmt.Errorf("invalid rate limit for %s: %g requests per second with burst %d", group, rule.RequestsPerSecond, rule.Burst)
+			}
+			switch rule.Identity {
+			case RateLimitByIP, RateLimitByUser, RateLimitByAPIKey:
+			default:
+				return fmt.Errorf("unsupported rate limit identity %q for %s", rule.Identity, group)
+			}
+		}
+	}
+
+	switch c.Mail.Driver {
+	case MailDriverLog:
+	case MailDriverFile:
+		if c.Mail.Dir == "" {
+			return fmt.Errorf("mail driver %q needs a dir", c.Mail.Driver)
+		}
+	case MailDriverSMTP:
+		if c.Mail.SMTP.Host == "" || c.Mail.SMTP.Port <= 0 {
+			return fmt.Errorf("invalid smtp address %s:%d", c.Mail.SMTP.Host, c.Mail.SMTP.Port)
+		}
+	default:
+		return fmt.Errorf("unsupported mail driver %q", c.Mail.Driver)
+	}
+
+	return nil
+}
+
 // setDefaults sets default values for configuration
 func setDefaults() {
 	// Server defaults
@@ -63,6 +559,9 @@ func setDefaults() {
 	viper.SetDefault("server.read_timeout", 10*time.Second)
 	viper.SetDefault("server.write_timeout", 10*time.Second)
 	viper.SetDefault("server.idle_timeout", 120*time.Second)
+	viper.SetDefault("server.shutdown_delay", 0)
+	viper.SetDefault("server.public_url", "http://localhost:8080")
+	viper.SetDefault("server.trusted_proxies", []string{})
 
 	// DB defaults
 	viper.SetDefault("db.host", "localhost")
@@ -71,4 +570,86 @@ func setDefaults() {
 	viper.SetDefault("db.password", "postgres")
 	viper.SetDefault("db.name", "app")
 	viper.SetDefault("db.ssl_mode", "disable")
+	viper.SetDefault("db.auto_migrate", false)
+
+	// Storage defaults
+	viper.SetDefault("storage.driver", StorageDriverMemory)
+
+	// Purge defaults
+	viper.SetDefault("purge.enabled", true)
+	viper.SetDefault("purge.interval", 1*time.Hour)
+	viper.SetDefault("purge.retention", 30*24*time.Hour)
+
+	// Tracing defaults
+	viper.SetDefault("tracing.enabled", false)
+	viper.SetDefault("tracing.service_name", "thepotatoverse")
+	viper.SetDefault("tracing.exporter", TracingExporterStdout)
+	viper.SetDefault("tracing.sample_ratio", 1.0)
+	viper.SetDefault("tracing.endpoint", "localhost:4318")
+	viper.SetDefault("tracing.insecure", true)
+	viper.SetDefault("tracing.file_path", "traces.json")
+
+	// Validation defaults
+	viper.SetDefault("validation.name_min_length", 1)
+	viper.SetDefault("validation.name_max_length", 100)
+	viper.SetDefault("validation.disallowed_email_domains", []string{})
+	viper.SetDefault("validation.password_min_length", 8)
+
+	// Auth defaults
+	viper.SetDefault("auth.session_ttl", 24*time.Hour)
+	viper.SetDefault("auth.session_cleanup_interval", 1*time.Hour)
+	viper.SetDefault("auth.cookie_name", "session")
+	viper.SetDefault("auth.cookie_secure", false)
+	viper.SetDefault("auth.admin_emails", []string{})
+	viper.SetDefault("auth.jwt.issuer", "thepotatoverse")
+	viper.SetDefault("auth.jwt.audience", "thepotatoverse")
+	viper.SetDefault("auth.jwt.access_token_ttl", 15*time.Minute)
+	viper.SetDefault("auth.jwt.refresh_token_ttl", 30*24*time.Hour)
+	viper.SetDefault("auth.verification.token_ttl", 48*time.Hour)
+	viper.SetDefault("auth.verification.secret", "")
+	viper.SetDefault("auth.password_reset.token_ttl", 1*time.Hour)
+	viper.SetDefault("auth.password_reset.max_requests", 3)
+	viper.SetDefault("auth.password_reset.request_window", 1*time.Hour)
+	viper.SetDefault("auth.mfa.issuer", "ThePotatoVerse")
+	viper.SetDefault("auth.mfa.challenge_ttl", 5*time.Minute)
+	viper.SetDefault("auth.mfa.max_attempts", 5)
+	viper.SetDefault("auth.mfa.recovery_codes", 10)
+	viper.SetDefault("auth.mfa.lockout_threshold", 10)
+	viper.SetDefault("auth.mfa.lockout_duration", 5*time.Minute)
+	viper.SetDefault("auth.mfa.max_lockout_duration", 24*time.Hour)
+	viper.SetDefault("auth.oauth.code_ttl", 1*time.Minute)
+	viper.SetDefault("auth.oauth.id_token_ttl", 1*time.Hour)
+	viper.SetDefault("auth.oauth.consent_ttl", 10*time.Minute)
+	viper.SetDefault("auth.oauth.secret", "")
+	viper.SetDefault("auth.oauth.login_url", "")
+	viper.SetDefault("auth.oidc.state_ttl", 10*time.Minute)
+	viper.SetDefault("auth.oidc.secret", "")
+	viper.SetDefault("auth.oidc.return_origins", []string{})
+
+	// Mail defaults
+	viper.SetDefault("mail.driver", MailDriverLog)
+	viper.SetDefault("mail.from", "ThePotatoVerse <no-reply@localhost>")
+	viper.SetDefault("mail.dir", "mail")
+	viper.SetDefault("mail.smtp.host", "localhost")
+	viper.SetDefault("mail.smtp.port", 1025)
+	viper.SetDefault("mail.smtp.username", "")
+	viper.SetDefault("mail.smtp.password", "")
+	viper.SetDefault("mail.smtp.timeout", 10*time.Second)
+
+	// Rate limit defaults
+	viper.SetDefault("rate_limit.enabled", true)
+	viper.SetDefault("rate_limit.store", RateLimitStoreMemory)
+	viper.SetDefault("rate_limit.cleanup_interval", 1*time.Minute)
+	viper.SetDefault("rate_limit.auth.requests_per_second", 0.2)
+	viper.SetDefault("rate_limit.auth.burst", 10)
+	viper.SetDefault("rate_limit.auth.identity", RateLimitByIP)
+	viper.SetDefault("rate_limit.api.requests_per_second", 20)
+	viper.SetDefault("rate_limit.api.burst", 100)
+	viper.SetDefault("rate_limit.api.identity", RateLimitByAPIKey)
+	viper.SetDefault("rate_limit.pre_auth.requests_per_second", 50)
+	viper.SetDefault("rate_limit.pre_auth.burst", 200)
+	viper.SetDefault("rate_limit.pre_auth.identity", RateLimitByIP)
+	viper.SetDefault("rate_limit.public.requests_per_second", 5)
+	viper.SetDefault("rate_limit.public.burst", 30)
+	viper.SetDefault("rate_limit.public.identity", RateLimitByIP)
 }
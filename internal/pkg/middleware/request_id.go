@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader is the header used to receive and echo request IDs
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client supplied request IDs
+const maxRequestIDLength = 128
+
+// requestIDKey is the context key holding the request ID
+type requestIDKey struct{}
+
+// RequestID creates a gin middleware that assigns every request an ID.
+// A valid X-Request-ID header from the client is reused, otherwise a new ID
+// is generated. The ID is echoed in the response and stored in the request
+// context together with the method and route as log fields.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+
+		c.Header(RequestIDHeader, id)
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		ctx := context.WithValue(c.Request.Context(), requestIDKey{}, id)
+		ctx = logger.ContextWithFields(ctx,
+			"request_id", id,
+			"method", c.Request.Method,
+			"route", route,
+		)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, if any
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID reports whether a client supplied request ID is safe to
+// reuse: non-empty, bounded in length and made of printable ASCII
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,172 @@
+package validator
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ThePotatoVerse/internal/pkg/config"
+)
+
+// maxEmailLength is the longest address that fits in an SMTP path (RFC 5321)
+const maxEmailLength = 254
+
+// maxPasswordLength bounds the input to password hashing
+const maxPasswordLength = 1024
+
+// FieldError describes why a single field is invalid
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// Errors lists every invalid field of a value
+type Errors []FieldError
+
+// Error implements error
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldError := range e {
+		messages[i] = fieldError.Field + " " + fieldError.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validator checks and normalizes user input against the configured rules
+type Validator struct {
+	nameMinLength     int
+	nameMaxLength     int
+	passwordMinLength int
+	disallowedDomains map[string]bool
+}
+
+// New creates a validator enforcing cfg
+func New(cfg *config.ValidationConfig) *Validator {
+	disallowedDomains := make(map[string]bool, len(cfg.DisallowedEmailDomains))
+	for _, domain := range cfg.DisallowedEmailDomains {
+		disallowedDomains[strings.ToLower(strings.TrimSpace(domain))] = true
+	}
+
+	return &Validator{
+		nameMinLength:     cfg.NameMinLength,
+		nameMaxLength:     cfg.NameMaxLength,
+		passwordMinLength: cfg.PasswordMinLength,
+		disallowedDomains: disallowedDomains,
+	}
+}
+
+// Check collects the field errors of a single value
+type Check struct {
+	validator *Validator
+	errs      Errors
+}
+
+// Check starts validating a value
+func (v *Validator) Check() *Check {
+	return &Check{validator: v}
+}
+
+// Err returns the collected field errors, or nil if every field is valid
+func (c *Check) Err() error {
+	if len(c.errs) == 0 {
+		return nil
+	}
+	return c.errs
+}
+
+// Fail records a field error for rules the validator does not know about
+func (c *Check) Fail(field, format string, args ...interface{}) {
+	c.fail(field, format, args...)
+}
+
+// fail records a field error
+func (c *Check) fail(field, format string, args ...interface{}) {
+	c.errs = append(c.errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Name validates a display name and returns it with surrounding whitespace
+// trimmed. Length is counted in characters, not bytes.
+func (c *Check) Name(field, value string) string {
+	value = strings.TrimSpace(value)
+
+	switch length := utf8.RuneCountInString(value); {
+	case length == 0:
+		c.fail(field, "is required")
+		return value
+	case length < c.validator.nameMinLength:
+		c.fail(field, "must be at least %d characters", c.validator.nameMinLength)
+	case length > c.validator.nameMaxLength:
+		c.fail(field, "must be at most %d characters", c.validator.nameMaxLength)
+	}
+
+	if !utf8.ValidString(value) || strings.IndexFunc(value, unicode.IsControl) >= 0 {
+		c.fail(field, "must not contain control characters")
+	}
+
+	return value
+}
+
+// Email validates a bare email address and returns it normalized: trimmed,
+// with the domain lower-cased. The local part is kept as given, since it may
+// be case-sensitive.
+func (c *Check) Email(field, value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		c.fail(field, "is required")
+		return value
+	}
+
+	// Only accept a bare address, not "Name <address>"
+	address, err := mail.ParseAddress(value)
+	if err != nil || address.Address != value {
+		c.fail(field, "must be a valid email address")
+		return value
+	}
+
+	at := strings.LastIndex(value, "@")
+	local, domain := value[:at], strings.ToLower(value[at+1:])
+	value = local + "@" + domain
+
+	if len(value) > maxEmailLength {
+		c.fail(field, "must be at most %d characters", maxEmailLength)
+	}
+	if !strings.Contains(domain, ".") {
+		c.fail(field, "must have a fully qualified domain")
+	}
+	if c.validator.domainDisallowed(domain) {
+		c.fail(field, "must not use the domain %s", domain)
+	}
+
+	return value
+}
+
+// Password validates a new password. Passwords are used exactly as given,
+// so surrounding whitespace is kept.
+func (c *Check) Password(field, value string) string {
+	switch length := utf8.RuneCountInString(value); {
+	case length == 0:
+		c.fail(field, "is required")
+	case length < c.validator.passwordMinLength:
+		c.fail(field, "must be at least %d characters", c.validator.passwordMinLength)
+	case len(value) > maxPasswordLength:
+		c.fail(field, "must be at most %d bytes", maxPasswordLength)
+	}
+
+	return value
+}
+
+// domainDisallowed reports whether domain or one of its parents is disallowed
+func (v *Validator) domainDisallowed(domain string) bool {
+	for {
+		if v.disallowedDomains[domain] {
+			return true
+		}
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			return false
+		}
+		domain = domain[dot+1:]
+	}
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector exports connection pool statistics as Prometheus metrics
+type poolCollector struct {
+	db *Postgres
+
+	acquiredConns     *prometheus.Desc
+	idleConns         *prometheus.Desc
+	totalConns        *prometheus.Desc
+	maxConns          *prometheus.Desc
+	acquireCount      *prometheus.Desc
+	emptyAcquireCount *prometheus.Desc
+	acquireDuration   *prometheus.Desc
+}
+
+// RegisterMetrics registers the connection pool metrics
+func (p *Postgres) RegisterMetrics(registerer prometheus.Registerer) {
+	registerer.MustRegister(&poolCollector{
+		db: p,
+		acquiredConns: prometheus.NewDesc("db_pool_acquired_connections",
+			"Number of connections currently checked out of the pool.", nil, nil),
+		idleConns: prometheus.NewDesc("db_pool_idle_connections",
+			"Number of idle connections in the pool.", nil, nil),
+		totalConns: prometheus.NewDesc("db_pool_total_connections",
+			"Total number of connections in the pool.", nil, nil),
+		maxConns: prometheus.NewDesc("db_pool_max_connections",
+			"Maximum size of the pool.", nil, nil),
+		acquireCount: prometheus.NewDesc("db_pool_acquires_total",
+			"Total number of successful connection acquires.", nil, nil),
+		emptyAcquireCount: prometheus.NewDesc("db_pool_waited_acquires_total",
+			"Total number of acquires that had to wait for a connection because the pool was empty.", nil, nil),
+		acquireDuration: prometheus.NewDesc("db_pool_acquire_duration_seconds_total",
+			"Total time spent acquiring connections.", nil, nil),
+	})
+}
+
+// Describe implements prometheus.Collector
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquireCount
+	ch <- c.emptyAcquireCount
+	ch <- c.acquireDuration
+}
+
+// Collect implements prometheus.Collector
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.db.Pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireCount, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
@@ -0,0 +1,356 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// migrationLockID is the advisory lock key held while migrations run so that
+// replicas starting at the same time apply them one at a time
+const migrationLockID int64 = 4735201937
+
+// Migration errors
+var (
+	ErrDirtyDatabase        = errors.New("database schema is dirty")
+	ErrUnknownVersion       = errors.New("unknown migration version")
+	ErrMissingDownMigration = errors.New("missing down migration")
+)
+
+// migrationFilePattern matches files such as 000001_create_users_table.up.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned schema change
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Version uint
+	Name    string
+	Applied bool
+}
+
+// Migrator applies schema migrations to a PostgreSQL database.
+// Applied versions are tracked in the schema_migrations table using the same
+// layout as the migrate CLI, so databases migrated by either tool stay compatible.
+type Migrator struct {
+	db         *Postgres
+	log        logger.Logger
+	migrations []Migration
+}
+
+// NewMigrator creates a migrator that reads migrations from source
+func NewMigrator(db *Postgres, source fs.FS, log logger.Logger) (*Migrator, error) {
+	migrations, err := loadMigrations(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		log:        log,
+		migrations: migrations,
+	}, nil
+}
+
+// Up applies all pending migrations. A database already migrated past the
+// newest known migration, by a newer release, is left alone with a warning,
+// so that older replicas keep starting during rolling deploys and rollbacks.
+func (m *Migrator) Up(ctx context.Context) error {
+	if len(m.migrations) == 0 {
+		return nil
+	}
+	latest := m.migrations[len(m.migrations)-1].Version
+
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		current, err := m.version(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		if current > latest {
+			m.log.Warn("Database schema is newer than this release, skipping migrations",
+				"version", current, "latest_known", latest)
+			return nil
+		}
+		return m.migrate(ctx, conn, current, latest)
+	})
+}
+
+// Down rolls back the given number of applied migrations
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("invalid number of steps: %d", steps)
+	}
+
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		current, err := m.version(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		idx := m.index(current)
+		if current != 0 && idx < 0 {
+			return fmt.Errorf("%w: %d", ErrUnknownVersion, current)
+		}
+
+		target := uint(0)
+		if idx-steps >= 0 {
+			target = m.migrations[idx-steps].Version
+		}
+
+		return m.migrate(ctx, conn, current, target)
+	})
+}
+
+// Goto migrates up or down to the given version. Version 0 rolls back every migration.
+func (m *Migrator) Goto(ctx context.Context, version uint) error {
+	if version != 0 && m.index(version) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		current, err := m.version(ctx, conn)
+		if err != nil {
+			return err
+		}
+		return m.migrate(ctx, conn, current, version)
+	})
+}
+
+// Status returns the current version and the applied state of every known migration
+func (m *Migrator) Status(ctx context.Context) (uint, []MigrationStatus, error) {
+	var (
+		current uint
+		status  []MigrationStatus
+	)
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		var err error
+		current, err = m.version(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		status = make([]MigrationStatus, 0, len(m.migrations))
+		for _, migration := range m.migrations {
+			status = append(status, MigrationStatus{
+				Version: migration.Version,
+				Name:    migration.Name,
+				Applied: migration.Version <= current,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return current, status, nil
+}
+
+// withLock runs fn on a dedicated connection while holding the migration advisory lock
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.db.Pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// Use a fresh context so the lock is released even if ctx was cancelled
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID); err != nil {
+			m.log.Error("Failed to release migration lock", "error", err)
+		}
+	}()
+
+	query := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT NOT NULL PRIMARY KEY,
+			dirty BOOLEAN NOT NULL
+		)
+	`
+	if _, err := conn.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// version returns the currently applied version, or 0 if none has been applied
+func (m *Migrator) version(ctx context.Context, conn *pgxpool.Conn) (uint, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+
+	err := conn.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	if dirty {
+		return 0, fmt.Errorf("%w at version %d", ErrDirtyDatabase, version)
+	}
+
+	return uint(version), nil
+}
+
+// migrate applies migrations one at a time until the schema is at target
+func (m *Migrator) migrate(ctx context.Context, conn *pgxpool.Conn, current, target uint) error {
+	if current != 0 && m.index(current) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, current)
+	}
+
+	if current == target {
+		m.log.Info("Database schema is up to date", "version", current)
+		return nil
+	}
+
+	if current < target {
+		for _, migration := range m.migrations {
+			if migration.Version <= current || migration.Version > target {
+				continue
+			}
+			m.log.Info("Applying migration", "version", migration.Version, "name", migration.Name)
+			if err := m.apply(ctx, conn, migration.Up, migration.Version); err != nil {
+				return fmt.Errorf("migration %d up failed: %w", migration.Version, err)
+			}
+		}
+		return nil
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if migration.Version > current || migration.Version <= target {
+			continue
+		}
+		if migration.Down == "" {
+			return fmt.Errorf("%w: %d", ErrMissingDownMigration, migration.Version)
+		}
+
+		previous := uint(0)
+		if i > 0 {
+			previous = m.migrations[i-1].Version
+		}
+
+		m.log.Info("Reverting migration", "version", migration.Version, "name", migration.Name)
+		if err := m.apply(ctx, conn, migration.Down, previous); err != nil {
+			return fmt.Errorf("migration %d down failed: %w", migration.Version, err)
+		}
+	}
+
+	return nil
+}
+
+// apply runs a migration script and records the resulting version in one transaction
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, script string, version uint) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, script); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return err
+	}
+
+	if version != 0 {
+		if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)", int64(version)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// index returns the position of version in the migration list, or -1
+func (m *Migrator) index(version uint) int {
+	for i, migration := range m.migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// loadMigrations reads and pairs the up and down files in source, sorted by version
+func loadMigrations(source fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[uint]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+
+		contents, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[uint(version)]
+		if !ok {
+			migration = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[uint(version)] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("conflicting names for migration %d", version)
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("missing up migration for version %d", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/ThePotatoVerse/internal/pkg/config"
+	"github.com/ThePotatoVerse/pkg/health"
 	"github.com/ThePotatoVerse/pkg/logger"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
@@ -13,6 +14,7 @@ import (
 // Postgres represents a PostgreSQL database connection
 type Postgres struct {
 	Pool *pgxpool.Pool
+	name string
 	log  logger.Logger
 }
 
@@ -54,10 +56,21 @@ func NewPostgres(ctx context.Context, cfg *config.DBConfig, log logger.Logger) (
 
 	return &Postgres{
 		Pool: pool,
+		name: cfg.Name,
 		log:  log,
 	}, nil
 }
 
+// Ping checks that the database is reachable
+func (p *Postgres) Ping(ctx context.Context) error {
+	return p.Pool.Ping(ctx)
+}
+
+// RegisterHealthChecks registers the database readiness checks
+func (p *Postgres) RegisterHealthChecks(registry *health.Registry) {
+	registry.Register("postgres", p.Ping)
+}
+
 // Close closes the database connection
 func (p *Postgres) Close() {
 	p.log.Info("Closing PostgreSQL connection")
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies spans created by this package
+const tracerName = "github.com/ThePotatoVerse/pkg/database"
+
+// tracedQuerier wraps a Querier with a client span per query.
+// Query arguments are never recorded.
+type tracedQuerier struct {
+	next   Querier
+	tracer trace.Tracer
+	dbName string
+}
+
+// newTracedQuerier wraps next
+func newTracedQuerier(next Querier, dbName string) Querier {
+	return &tracedQuerier{
+		next:   next,
+		tracer: otel.Tracer(tracerName),
+		dbName: dbName,
+	}
+}
+
+// start starts the span of a query
+func (q *tracedQuerier) start(ctx context.Context, sql string) (context.Context, trace.Span) {
+	operation := queryOperation(sql)
+	return q.tracer.Start(ctx, "db "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBNamespace(q.dbName),
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(strings.TrimSpace(sql)),
+		),
+	)
+}
+
+// Exec implements Querier
+func (q *tracedQuerier) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, span := q.start(ctx, sql)
+	tag, err := q.next.Exec(ctx, sql, args...)
+	if err == nil {
+		span.SetAttributes(attribute.Int64("db.rows_affected", tag.RowsAffected()))
+	}
+	endQuerySpan(span, err)
+	return tag, err
+}
+
+// Query implements Querier. The span ends when the rows are closed.
+func (q *tracedQuerier) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	ctx, span := q.start(ctx, sql)
+	rows, err := q.next.Query(ctx, sql, args...)
+	if err != nil {
+		endQuerySpan(span, err)
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+// QueryRow implements Querier. The span ends when the row is scanned.
+func (q *tracedQuerier) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	ctx, span := q.start(ctx, sql)
+	return &tracedRow{row: q.next.QueryRow(ctx, sql, args...), span: span}
+}
+
+// tracedRows ends its span when closed
+type tracedRows struct {
+	pgx.Rows
+	span trace.Span
+	once sync.Once
+}
+
+// Close implements pgx.Rows
+func (r *tracedRows) Close() {
+	r.Rows.Close()
+	r.once.Do(func() {
+		endQuerySpan(r.span, r.Rows.Err())
+	})
+}
+
+// tracedRow ends its span when scanned
+type tracedRow struct {
+	row  pgx.Row
+	span trace.Span
+}
+
+// Scan implements pgx.Row
+func (r *tracedRow) Scan(dest ...interface{}) error {
+	err := r.row.Scan(dest...)
+	if err == pgx.ErrNoRows {
+		// Not finding a row is an expected outcome, not a failed query
+		endQuerySpan(r.span, nil)
+	} else {
+		endQuerySpan(r.span, err)
+	}
+	return err
+}
+
+// endQuerySpan records the query error, if any, and ends the span
+func endQuerySpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// queryOperation returns the leading SQL keyword of a query, e.g. SELECT
+func queryOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "QUERY"
+	}
+	return strings.ToUpper(fields[0])
+}
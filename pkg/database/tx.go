@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel"
+)
+
+// TxManager runs units of work atomically. Repositories taking part in the
+// work read the transaction from the context passed to fn.
+type TxManager interface {
+	// WithinTx runs fn in a transaction that is committed when fn returns nil
+	// and rolled back otherwise. Calls nested inside fn join the outer transaction.
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+
+	// AfterCommit registers fn to run once the transaction carried by ctx
+	// has committed, or runs it at once when ctx is not part of a
+	// transaction. fn never runs if the transaction rolls back. It is meant
+	// for side effects such as sending mail, which cannot be undone.
+	AfterCommit(ctx context.Context, fn func(ctx context.Context))
+}
+
+// Querier is implemented by both the connection pool and transactions
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// txKey is the context key holding the current pgx.Tx
+type txKey struct{}
+
+// afterCommitKey is the context key holding the functions registered with
+// AfterCommit in the current transaction
+type afterCommitKey struct{}
+
+// Conn returns the transaction carried by ctx, or the connection pool when
+// ctx is not part of a transaction. Queries run through it are traced.
+func (p *Postgres) Conn(ctx context.Context) Querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return newTracedQuerier(tx, p.name)
+	}
+	return newTracedQuerier(p.Pool, p.name)
+}
+
+// postgresTxManager implements TxManager with PostgreSQL transactions
+type postgresTxManager struct {
+	db *Postgres
+}
+
+// NewTxManager creates a transaction manager for the database
+func NewTxManager(db *Postgres) TxManager {
+	return &postgresTxManager{db: db}
+}
+
+// WithinTx runs fn in a PostgreSQL transaction
+func (m *postgresTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	// Join the transaction already in progress
+	if _, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "db transaction")
+	defer func() {
+		endQuerySpan(span, err)
+	}()
+
+	tx, err := m.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback(ctx)
+			panic(p)
+		}
+	}()
+
+	var hooks []func(ctx context.Context)
+	txCtx := context.WithValue(context.WithValue(ctx, txKey{}, tx), afterCommitKey{}, &hooks)
+
+	if err := fn(txCtx); err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			m.db.log.FromContext(ctx).Error("Failed to roll back transaction", "error", rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for _, hook := range hooks {
+		hook(ctx)
+	}
+
+	return nil
+}
+
+// AfterCommit runs fn after the transaction carried by ctx commits
+func (m *postgresTxManager) AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if hooks, ok := ctx.Value(afterCommitKey{}).(*[]func(ctx context.Context)); ok {
+		*hooks = append(*hooks, fn)
+		return
+	}
+	fn(ctx)
+}
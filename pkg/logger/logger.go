@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"os"
 
 	"go.uber.org/zap"
@@ -14,6 +15,31 @@ type Logger interface {
 	Warn(msg string, args ...interface{})
 	Error(msg string, args ...interface{})
 	Fatal(msg string, args ...interface{})
+
+	// With returns a logger that adds the given key-value pairs to every entry
+	With(args ...interface{}) Logger
+	// FromContext returns a logger that adds the fields stored in ctx by
+	// ContextWithFields, or the logger itself if there are none
+	FromContext(ctx context.Context) Logger
+}
+
+// fieldsKey is the context key holding request-scoped log fields
+type fieldsKey struct{}
+
+// ContextWithFields returns a copy of ctx carrying the given key-value pairs
+// in addition to any fields already stored in it
+func ContextWithFields(ctx context.Context, args ...interface{}) context.Context {
+	existing := Fields(ctx)
+	fields := make([]interface{}, 0, len(existing)+len(args))
+	fields = append(fields, existing...)
+	fields = append(fields, args...)
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// Fields returns the key-value pairs stored in ctx
+func Fields(ctx context.Context) []interface{} {
+	fields, _ := ctx.Value(fieldsKey{}).([]interface{})
+	return fields
 }
 
 // zapLogger implements Logger interface using zap
@@ -68,3 +94,19 @@ func (l *zapLogger) Error(msg string, args ...interface{}) {
 func (l *zapLogger) Fatal(msg string, args ...interface{}) {
 	l.logger.Fatalw(msg, args...)
 }
+
+// With returns a logger that adds the given key-value pairs to every entry
+func (l *zapLogger) With(args ...interface{}) Logger {
+	return &zapLogger{
+		logger: l.logger.With(args...),
+	}
+}
+
+// FromContext returns a logger that adds the fields stored in ctx
+func (l *zapLogger) FromContext(ctx context.Context) Logger {
+	fields := Fields(ctx)
+	if len(fields) == 0 {
+		return l
+	}
+	return l.With(fields...)
+}
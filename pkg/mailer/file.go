@@ -0,0 +1,55 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fileMailer drops each message into a directory as an .eml file, which
+// mail clients can open. It is meant for development and tests.
+type fileMailer struct {
+	dir  string
+	from *mail.Address
+}
+
+// newFileMailer creates a file mailer, creating its directory if needed
+func newFileMailer(dir string, from *mail.Address) (*fileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &fileMailer{dir: dir, from: from}, nil
+}
+
+// Send writes msg to a new file
+func (m *fileMailer) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+
+	data, err := compose(m.from, msg, now)
+	if err != nil {
+		return err
+	}
+
+	// Files are written under a temporary name and renamed, so that readers
+	// never see a partial message
+	f, err := os.CreateTemp(m.dir, ".mail-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000Z"), strings.TrimPrefix(filepath.Base(f.Name()), ".mail-"))
+	return os.Rename(f.Name(), filepath.Join(m.dir, name))
+}
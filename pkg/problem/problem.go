@@ -0,0 +1,47 @@
+package problem
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ContentType is the media type of problem details documents
+const ContentType = "application/problem+json"
+
+// BlankType is the problem type of errors that need no more explanation than
+// their HTTP status
+const BlankType = "about:blank"
+
+// Problem is an RFC 7807 problem details document. It implements error so
+// that it can be returned and reported like any other error.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes why a single request field is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// New creates a problem of the blank type, titled after its status
+func New(status int) *Problem {
+	return &Problem{
+		Type:   BlankType,
+		Title:  http.StatusText(status),
+		Status: status,
+	}
+}
+
+// Error implements error
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return fmt.Sprintf("%s: %s", p.Title, p.Detail)
+	}
+	return p.Title
+}
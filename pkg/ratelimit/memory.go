@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryStore implements Store in process memory. Each replica counts its
+// own requests, so clients get the limit once per replica.
+type memoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]bucket
+}
+
+// NewMemoryStore creates a new in-memory bucket store
+func NewMemoryStore() Store {
+	return &memoryStore{buckets: make(map[string]bucket)}
+}
+
+// Take implements Store
+func (s *memoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var previous *bucket
+	if b, ok := s.buckets[key]; ok {
+		previous = &b
+	}
+
+	b, result := limit.take(previous, now)
+	s.buckets[key] = b
+
+	return result, nil
+}
+
+// DeleteFull implements Store
+func (s *memoryStore) DeleteFull(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for key, b := range s.buckets {
+		if !b.FullAt.After(now) {
+			delete(s.buckets, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ThePotatoVerse/pkg/database"
+	"github.com/jackc/pgx/v4"
+)
+
+// postgresStore implements Store in PostgreSQL, so that replicas share
+// their buckets
+type postgresStore struct {
+	db        *database.Postgres
+	txManager database.TxManager
+}
+
+// NewPostgresStore creates a new PostgreSQL bucket store
+func NewPostgresStore(db *database.Postgres) Store {
+	return &postgresStore{
+		db:        db,
+		txManager: database.NewTxManager(db),
+	}
+}
+
+// Take implements Store. The bucket row is locked while it is updated.
+func (s *postgresStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	var result Result
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		query := `
+			SELECT tokens, updated_at, full_at
+			FROM rate_limit_buckets
+			WHERE key = $1
+			FOR UPDATE
+		`
+
+		var previous *bucket
+		var b bucket
+		err := s.db.Conn(ctx).QueryRow(ctx, query, key).Scan(&b.Tokens, &b.UpdatedAt, &b.FullAt)
+		switch {
+		case err == nil:
+			previous = &b
+		case !errors.Is(err, pgx.ErrNoRows):
+			return err
+		}
+
+		var next bucket
+		next, result = limit.take(previous, now)
+
+		if previous != nil {
+			query = `
+				UPDATE rate_limit_buckets
+				SET tokens = $2, updated_at = $3, full_at = $4
+				WHERE key = $1
+			`
+		} else {
+			// Concurrent first requests for a key all start from a full
+			// bucket, so keeping the fewest tokens counts all but one
+			query = `
+				INSERT INTO rate_limit_buckets (key, tokens, updated_at, full_at)
+				VALUES ($1, $2, $3, $4)
+				ON CONFLICT (key) DO UPDATE
+				SET tokens = LEAST(rate_limit_buckets.tokens, EXCLUDED.tokens),
+					updated_at = GREATEST(rate_limit_buckets.updated_at, EXCLUDED.updated_at),
+					full_at = GREATEST(rate_limit_buckets.full_at, EXCLUDED.full_at)
+			`
+		}
+		_, err = s.db.Conn(ctx).Exec(ctx, query, key, next.Tokens, next.UpdatedAt, next.FullAt)
+		return err
+	})
+
+	return result, err
+}
+
+// DeleteFull implements Store
+func (s *postgresStore) DeleteFull(ctx context.Context, now time.Time) (int64, error) {
+	result, err := s.db.Conn(ctx).Exec(ctx, "DELETE FROM rate_limit_buckets WHERE full_at <= $1", now)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,81 @@
+// Package ratelimit limits how often clients may make requests, with token
+// buckets kept in a pluggable store
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit is a token bucket: it holds up to Burst tokens and refills at Rate
+// tokens per second. Each request takes a token.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed bool
+
+	// Remaining is how many whole tokens are left
+	Remaining int
+
+	// RetryAfter is how long until the next token, if none was left
+	RetryAfter time.Duration
+
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+}
+
+// Store keeps token buckets by key. Take must be atomic per key, so that
+// concurrent requests cannot spend the same token.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+
+	// DeleteFull deletes the buckets that have refilled by now, which
+	// behave exactly like buckets that were never used
+	DeleteFull(ctx context.Context, now time.Time) (int64, error)
+}
+
+// bucket is the state of a token bucket at UpdatedAt. FullAt is when it
+// will have refilled, if left alone.
+type bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+	FullAt    time.Time
+}
+
+// take refills b up to now and takes a token from it if one is left. A nil
+// bucket has never been used and is full.
+func (l Limit) take(b *bucket, now time.Time) (bucket, Result) {
+	tokens := float64(l.Burst)
+	if b != nil {
+		elapsed := now.Sub(b.UpdatedAt).Seconds()
+		if elapsed < 0 {
+			// Another replica with a clock ahead of ours wrote the bucket
+			elapsed = 0
+		}
+		tokens = math.Min(tokens, b.Tokens+elapsed*l.Rate)
+	}
+
+	result := Result{Allowed: tokens >= 1}
+	if result.Allowed {
+		tokens--
+	} else {
+		result.RetryAfter = l.duration(1 - tokens)
+	}
+	result.Remaining = int(tokens)
+	result.Reset = l.duration(float64(l.Burst) - tokens)
+
+	return bucket{Tokens: tokens, UpdatedAt: now, FullAt: now.Add(result.Reset)}, result
+}
+
+// duration returns how long it takes to refill n tokens
+func (l Limit) duration(n float64) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(n / l.Rate * float64(time.Second)))
+}
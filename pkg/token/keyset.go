@@ -0,0 +1,347 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/ThePotatoVerse/internal/pkg/config"
+	"github.com/ThePotatoVerse/pkg/logger"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidToken is returned when a token is malformed, expired, not yet
+// valid, or not signed by a known key
+var ErrInvalidToken = errors.New("invalid token")
+
+// ephemeralKeyID identifies the key generated when none is configured
+const ephemeralKeyID = "ephemeral"
+
+// key is a verification key, and a signing key if private is set
+type key struct {
+	id      string
+	method  jwt.SigningMethod
+	public  crypto.PublicKey
+	private crypto.PrivateKey
+}
+
+// KeySet signs and verifies JWTs. Tokens are signed with the active key and
+// verified with the key named by their kid header, so keys can be rotated by
+// adding a new active key and keeping the old one until its tokens expire.
+type KeySet struct {
+	issuer   string
+	audience string
+	active   *key
+	keys     map[string]*key
+	order    []string
+}
+
+// LoadKeySet loads the keys listed in cfg. Without keys it generates an
+// ephemeral ECDSA key, whose tokens stop verifying when the process exits.
+func LoadKeySet(cfg *config.JWTConfig, log logger.Logger) (*KeySet, error) {
+	set := &KeySet{
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		keys:     make(map[string]*key),
+	}
+
+	if len(cfg.Keys) == 0 {
+		log.Warn("No JWT keys configured, generating an ephemeral signing key")
+
+		private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		set.add(&key{id: ephemeralKeyID, method: jwt.SigningMethodES256, public: &private.PublicKey, private: private})
+		set.active = set.keys[ephemeralKeyID]
+		return set, nil
+	}
+
+	for _, keyCfg := range cfg.Keys {
+		k, err := loadKey(keyCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load jwt key %q: %w", keyCfg.ID, err)
+		}
+		set.add(k)
+	}
+
+	set.active = set.keys[cfg.ActiveKeyID]
+	if set.active == nil || set.active.private == nil {
+		return nil, fmt.Errorf("active jwt key %q cannot sign", cfg.ActiveKeyID)
+	}
+
+	log.Info("Loaded JWT keys", "count", len(set.keys), "active_key_id", set.active.id)
+	return set, nil
+}
+
+// add adds a key to the set
+func (s *KeySet) add(k *key) {
+	s.keys[k.id] = k
+	s.order = append(s.order, k.id)
+}
+
+// Sign signs claims with the active key. The issuer and audience of the set
+// are filled in.
+func (s *KeySet) Sign(claims *jwt.RegisteredClaims) (string, error) {
+	claims.Issuer = s.issuer
+	claims.Audience = jwt.ClaimStrings{s.audience}
+
+	return s.SignClaims(claims)
+}
+
+// SignClaims signs claims with the active key as they are, for tokens with
+// their own issuer and audience, such as OpenID Connect ID tokens
+func (s *KeySet) SignClaims(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.active.method, claims)
+	token.Header["kid"] = s.active.id
+
+	return token.SignedString(s.active.private)
+}
+
+// Parse verifies a token and decodes its claims. The signature, expiry,
+// issuer and audience are all checked.
+func (s *KeySet) Parse(tokenString string) (*jwt.RegisteredClaims, error) {
+	claims := &jwt.RegisteredClaims{}
+
+	if err := s.parse(tokenString, claims, jwt.WithIssuer(s.issuer), jwt.WithAudience(s.audience)); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// ParseClaims verifies a token signed by SignClaims and decodes it into
+// claims. The signature, expiry and issuer are checked; the audience is left
+// to the caller.
+func (s *KeySet) ParseClaims(tokenString string, claims jwt.Claims, issuer string) error {
+	return s.parse(tokenString, claims, jwt.WithIssuer(issuer))
+}
+
+// parse verifies a token signed by a key of the set and decodes it into claims
+func (s *KeySet) parse(tokenString string, claims jwt.Claims, options ...jwt.ParserOption) error {
+	options = append(options,
+		jwt.WithExpirationRequired(),
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}),
+	)
+
+	if _, err := jwt.ParseWithClaims(tokenString, claims, s.keyFor, options...); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	return nil
+}
+
+// Algorithms returns the signing algorithms of the keys in the set, in
+// configuration order and without duplicates
+func (s *KeySet) Algorithms() []string {
+	var algorithms []string
+	seen := make(map[string]bool)
+
+	for _, id := range s.order {
+		alg := s.keys[id].method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			algorithms = append(algorithms, alg)
+		}
+	}
+
+	return algorithms
+}
+
+// keyFor looks up the verification key named by the kid header of a token
+func (s *KeySet) keyFor(token *jwt.Token) (interface{}, error) {
+	id, _ := token.Header["kid"].(string)
+
+	k, ok := s.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", id)
+	}
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, fmt.Errorf("key %q does not use %s", id, token.Method.Alg())
+	}
+
+	return k.public, nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set, in configuration order
+func (s *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: make([]JWK, 0, len(s.order))}
+
+	for _, id := range s.order {
+		k := s.keys[id]
+		jwk := JWK{KeyID: k.id, Use: "sig", Algorithm: k.method.Alg()}
+
+		switch public := k.public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = encodeInt(public.N, 0)
+			jwk.E = encodeInt(big.NewInt(int64(public.E)), 0)
+		case *ecdsa.PublicKey:
+			size := (public.Curve.Params().BitSize + 7) / 8
+			jwk.KeyType = "EC"
+			jwk.Curve = public.Curve.Params().Name
+			jwk.X = encodeInt(public.X, size)
+			jwk.Y = encodeInt(public.Y, size)
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
+
+// PublicKey decodes the RSA or EC public key of a JWK, such as one published
+// by another issuer
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid rsa exponent in key %q", k.KeyID)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q in key %q", k.Curve, k.KeyID)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point of key %q is not on its curve", k.KeyID)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q in key %q", k.KeyType, k.KeyID)
+	}
+}
+
+// decodeInt decodes a base64url encoded unsigned integer
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid integer in jwk")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// encodeInt base64url encodes an unsigned integer, left-padded to size bytes
+func encodeInt(n *big.Int, size int) string {
+	b := n.Bytes()
+	if len(b) < size {
+		b = append(make([]byte, size-len(b)), b...)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// loadKey reads the PEM file of a configured key
+func loadKey(cfg config.JWTKeyConfig) (*key, error) {
+	file := cfg.PublicKeyFile
+	if cfg.PrivateKeyFile != "" {
+		file = cfg.PrivateKeyFile
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", file)
+	}
+
+	k := &key{id: cfg.ID}
+	if cfg.PrivateKeyFile != "" {
+		k.private, err = parsePrivateKey(block)
+		if err != nil {
+			return nil, err
+		}
+		k.public = k.private.(crypto.Signer).Public()
+	} else {
+		k.public, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must have at least 2048 bits")
+		}
+		k.method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		if public.Curve != elliptic.P256() {
+			return nil, errors.New("EC keys must use the P-256 curve")
+		}
+		k.method = jwt.SigningMethodES256
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", public)
+	}
+
+	return k, nil
+}
+
+// parsePrivateKey parses a PKCS #8, PKCS #1 or SEC 1 private key
+func parsePrivateKey(block *pem.Block) (crypto.PrivateKey, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+}
@@ -0,0 +1,3 @@
+ALTER TABLE totp_factors
+    DROP COLUMN IF EXISTS locked_until,
+    DROP COLUMN IF EXISTS failed_attempts;
@@ -0,0 +1,4 @@
+-- Wrong codes in a row, on every path that checks a second factor, lock it
+ALTER TABLE totp_factors
+    ADD COLUMN IF NOT EXISTS failed_attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP WITH TIME ZONE;
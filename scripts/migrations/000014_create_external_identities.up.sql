@@ -0,0 +1,11 @@
+-- Links users to their accounts at external OpenID Connect providers
+CREATE TABLE IF NOT EXISTS external_identities (
+    provider VARCHAR(64) NOT NULL,
+    -- The provider's ID for the account
+    subject VARCHAR(255) NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (provider, subject),
+    UNIQUE (user_id, provider)
+);
@@ -0,0 +1,9 @@
+// Package migrations embeds the SQL schema migrations into the binary.
+package migrations
+
+import "embed"
+
+// FS holds the up and down SQL migration files
+//
+//go:embed *.sql
+var FS embed.FS